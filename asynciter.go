@@ -0,0 +1,91 @@
+package jsrunner
+
+import (
+	"strings"
+
+	"github.com/dop251/goja"
+	"github.com/evanw/esbuild/pkg/api"
+)
+
+// WithAsyncIteration enables async generators (async function*) and
+// for await...of loops, which goja's parser does not support yet. Source passed
+// to the Eval and LoadScript methods, RunAsync, and the AwaitPromise family is
+// rewritten with esbuild into equivalent generator- and promise-based code
+// before it is compiled, and Symbol.asyncIterator is installed so custom async
+// iterables work with the rewritten loops.
+//
+// Only sources mentioning async or await are rewritten, and only the two
+// missing features are lowered; everything else is left as written. Because
+// lowered code is restructured, line and column numbers in errors from such
+// scripts may not match the original source. Programs precompiled with Compile
+// and run through RunProgram or RunProgramAsync are not rewritten.
+//
+// Example:
+//
+//	runner := jsrunner.NewEventLoopRunner(jsrunner.WithAsyncIteration())
+//	runner.Start()
+//	defer runner.Stop()
+//	result, err := runner.AwaitPromise(`(async () => {
+//	    async function* pages() { yield 1; yield 2; }
+//	    const seen = [];
+//	    for await (const page of pages()) seen.push(page);
+//	    return seen;
+//	})()`)
+func WithAsyncIteration() Option {
+	return func(r *Runner) {
+		r.asyncIteration = true
+	}
+}
+
+// asyncIterationUnsupported lists the esbuild features goja lacks.
+var asyncIterationUnsupported = map[string]bool{
+	"async-generator": false,
+	"for-await":       false,
+}
+
+// lowerAsyncIteration rewrites async generators and for await...of loops in
+// code into constructs goja supports. Code that does not mention async or
+// await, or that esbuild cannot parse, is returned unchanged so goja reports
+// syntax errors as usual.
+func lowerAsyncIteration(code string) string {
+	if !strings.Contains(code, "async") && !strings.Contains(code, "await") {
+		return code
+	}
+
+	result := api.Transform(code, api.TransformOptions{
+		Loader:    api.LoaderJS,
+		Supported: asyncIterationUnsupported,
+	})
+	if len(result.Errors) > 0 {
+		return code
+	}
+	return string(result.Code)
+}
+
+// installAsyncIteratorSymbol defines Symbol.asyncIterator when the runtime
+// lacks it, using the registered symbol esbuild's helpers fall back to so
+// iterables defined by scripts and the lowered loops agree on the key.
+func installAsyncIteratorSymbol(vm *goja.Runtime) {
+	symbol := vm.Get("Symbol").ToObject(vm)
+	if symbol.Get("asyncIterator") != nil {
+		return
+	}
+	registered, ok := goja.AssertFunction(symbol.Get("for"))
+	if !ok {
+		return
+	}
+	key, err := registered(symbol, vm.ToValue("Symbol.asyncIterator"))
+	if err != nil {
+		return
+	}
+	_ = symbol.DefineDataProperty("asyncIterator", key, goja.FLAG_FALSE, goja.FLAG_FALSE, goja.FLAG_FALSE)
+}
+
+// prepareSource applies the source rewrites enabled by options before code is
+// compiled.
+func (r *Runner) prepareSource(code string) string {
+	if r.asyncIteration {
+		return lowerAsyncIteration(code)
+	}
+	return code
+}
@@ -0,0 +1,321 @@
+package jsrunner
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/dop251/goja"
+	"github.com/dop251/goja/ast"
+	"github.com/dop251/goja/parser"
+)
+
+// ScriptSource is a named piece of JavaScript code loaded by LoadScriptsAtomic.
+// Name is used as the source file name in syntax errors and stack traces.
+type ScriptSource struct {
+	Name string
+	Code string
+}
+
+// LoadScriptsAtomic loads a sequence of interdependent scripts with
+// all-or-nothing semantics. Every source is parsed and compiled before any of
+// them runs, so a syntax error leaves the runtime untouched. If a script
+// throws while executing, the batch is rolled back in place: globals it added
+// are removed, globals it reassigned or redefined get their previous values
+// back, and properties it added to or changed on the objects held by globals
+// (a config object, or a built-in prototype such as Array.prototype) are
+// restored too. The runtime itself is kept, so earlier scripts are not re-run
+// and configuration made through GetVM survives.
+//
+// Top-level let, const, and class declarations create bindings that cannot
+// be removed again, so a batch containing them is rejected before it runs;
+// use var or globalThis assignments instead. Global var declarations are
+// removed by a rollback.
+//
+// A rollback does not undo:
+//   - changes below the first level of a global, such as config.db.host, or
+//     to the internal state of Map, Set, Date, and typed array objects;
+//   - top-level function declarations, which cannot be deleted once they run
+//     and are reset to undefined instead;
+//   - side effects outside the runtime, such as calls to Go functions.
+//
+// Example:
+//
+//	err := runner.LoadScriptsAtomic([]jsrunner.ScriptSource{
+//	    {Name: "lib/util.js", Code: utilSource},
+//	    {Name: "lib/models.js", Code: modelsSource},
+//	    {Name: "app.js", Code: appSource},
+//	})
+//	if err != nil {
+//	    log.Fatal(err) // none of the three scripts took effect
+//	}
+func (r *Runner) LoadScriptsAtomic(sources []ScriptSource) (err error) {
+	defer r.redactError(&err)
+
+	r.syncLock()
+	defer r.syncUnlock()
+
+	programs := make([]*goja.Program, len(sources))
+	var vars []string
+	for i, src := range sources {
+		declared, err := globalVarNames(src.Name, r.prepareSource(src.Code))
+		if err != nil {
+			return fmt.Errorf("failed to load script %s: %w", src.Name, err)
+		}
+		vars = append(vars, declared...)
+		program, err := r.compile(src.Name, src.Code)
+		if err != nil {
+			return fmt.Errorf("failed to load script %s: %w", src.Name, err)
+		}
+		programs[i] = program
+	}
+
+	rollback, err := r.snapshotGlobalObject()
+	if err != nil {
+		return fmt.Errorf("failed to load scripts: %w", err)
+	}
+
+	// A var declaration makes a new global non-configurable, so a rollback
+	// could not delete it. Declaring it first as an ordinary property keeps
+	// it configurable; the declaration then reuses that property.
+	global := r.vm.GlobalObject()
+	defined := make(map[string]struct{})
+	for _, name := range global.GetOwnPropertyNames() {
+		defined[name] = struct{}{}
+	}
+	for _, name := range vars {
+		if _, ok := defined[name]; ok {
+			continue
+		}
+		defined[name] = struct{}{}
+		_ = global.DefineDataProperty(name, goja.Undefined(), goja.FLAG_TRUE, goja.FLAG_TRUE, goja.FLAG_TRUE)
+	}
+
+	for i, program := range programs {
+		if _, err := r.runProgram(program); err != nil {
+			if rollbackErr := rollback(); rollbackErr != nil {
+				return fmt.Errorf("failed to load script %s: %w (rollback failed: %v)", sources[i].Name, err, rollbackErr)
+			}
+			r.logger.Warn("globals rolled back after failed atomic load", "script", sources[i].Name)
+			return fmt.Errorf("failed to load script %s: %w", sources[i].Name, err)
+		}
+	}
+
+	for _, src := range sources {
+		r.logger.Debug("script loaded", "name", src.Name)
+	}
+	return nil
+}
+
+// globalVarNames parses code and returns the names its top-level var
+// declarations bind. It returns an error if code declares let, const, or
+// class bindings at the top level, which LoadScriptsAtomic cannot roll back.
+func globalVarNames(name, code string) ([]string, error) {
+	program, err := goja.Parse(name, code, parser.WithDisableSourceMaps)
+	if err != nil {
+		return nil, err
+	}
+	for _, stmt := range program.Body {
+		switch decl := stmt.(type) {
+		case *ast.LexicalDeclaration:
+			return nil, fmt.Errorf("top-level %s declarations cannot be rolled back; use var or globalThis properties", decl.Token)
+		case *ast.ClassDeclaration:
+			return nil, fmt.Errorf("top-level class declarations cannot be rolled back; use var or globalThis properties")
+		}
+	}
+	var names []string
+	for _, decl := range program.DeclarationList {
+		for _, binding := range decl.List {
+			names = appendBoundNames(names, binding.Target)
+		}
+	}
+	return names, nil
+}
+
+// appendBoundNames appends the identifiers bound by target, which is an
+// identifier or a destructuring pattern.
+func appendBoundNames(names []string, target ast.Node) []string {
+	switch t := target.(type) {
+	case *ast.Identifier:
+		names = append(names, t.Name.String())
+	case *ast.AssignExpression:
+		names = appendBoundNames(names, t.Left)
+	case *ast.ArrayPattern:
+		for _, elem := range t.Elements {
+			if elem != nil {
+				names = appendBoundNames(names, elem)
+			}
+		}
+		if t.Rest != nil {
+			names = appendBoundNames(names, t.Rest)
+		}
+	case *ast.ObjectPattern:
+		for _, prop := range t.Properties {
+			switch p := prop.(type) {
+			case *ast.PropertyShort:
+				names = append(names, p.Name.Name.String())
+			case *ast.PropertyKeyed:
+				names = appendBoundNames(names, p.Value)
+			}
+		}
+		if t.Rest != nil {
+			names = appendBoundNames(names, t.Rest)
+		}
+	}
+	return names
+}
+
+// globalRollbackSource records the property descriptors and prototype of the
+// global object and of every object or function a global holds, including
+// constructors' prototype objects, and returns a function that restores them.
+// Descriptors are read without running getters. The built-ins it relies on
+// are captured up front so a failed batch that replaces them cannot break the
+// rollback. It runs in sloppy mode so deleting a non-configurable property (a
+// function declaration) fails quietly instead of throwing.
+//
+// Once a script declares a global var, goja stops listing the built-in
+// globals among the global object's own keys, although they are still
+// defined. builtins names them so they are recorded anyway.
+const globalRollbackSource = `(function(builtins) {
+	var g = globalThis;
+	var ownKeys = Reflect.ownKeys;
+	var describe = Reflect.getOwnPropertyDescriptor;
+	var define = Reflect.defineProperty;
+	var remove = Reflect.deleteProperty;
+	var getProto = Reflect.getPrototypeOf;
+	var setProto = Reflect.setPrototypeOf;
+	var apply = Reflect.apply;
+	var hasOwn = Object.prototype.hasOwnProperty;
+	var forEach = Array.prototype.forEach;
+	var push = Array.prototype.push;
+	var SavedMap = Map;
+	var mapGet = Map.prototype.get;
+	var mapSet = Map.prototype.set;
+	var mapHas = Map.prototype.has;
+	var mapForEach = Map.prototype.forEach;
+
+	function isObject(v) {
+		return (typeof v === "object" && v !== null) || typeof v === "function";
+	}
+
+	function keysOf(obj) {
+		var keys = ownKeys(obj);
+		if (obj === g) {
+			apply(forEach, builtins, [function(name) {
+				if (describe(g, name)) {
+					apply(push, keys, [name]);
+				}
+			}]);
+		}
+		return keys;
+	}
+
+	var records = [];
+	var seen = new SavedMap();
+	function record(obj) {
+		if (!isObject(obj) || apply(mapHas, seen, [obj])) {
+			return;
+		}
+		apply(mapSet, seen, [obj, true]);
+		try {
+			var saved = new SavedMap();
+			apply(forEach, keysOf(obj), [function(key) {
+				apply(mapSet, saved, [key, describe(obj, key)]);
+			}]);
+			apply(push, records, [{ obj: obj, saved: saved, proto: getProto(obj) }]);
+		} catch (e) {}
+	}
+
+	record(g);
+	apply(mapForEach, records[0].saved, [function(desc, key) {
+		if (!desc || !isObject(desc.value)) {
+			return;
+		}
+		record(desc.value);
+		var proto = describe(desc.value, "prototype");
+		if (proto && isObject(proto.value)) {
+			record(proto.value);
+		}
+	}]);
+
+	function same(a, b) {
+		return a.value === b.value && a.get === b.get && a.set === b.set &&
+			a.writable === b.writable && a.enumerable === b.enumerable &&
+			a.configurable === b.configurable;
+	}
+
+	function restore(rec) {
+		var obj = rec.obj, saved = rec.saved;
+		try {
+			if (getProto(obj) !== rec.proto) {
+				setProto(obj, rec.proto);
+			}
+			apply(forEach, keysOf(obj), [function(key) {
+				if (apply(mapHas, saved, [key])) {
+					return;
+				}
+				if (!remove(obj, key)) {
+					try { obj[key] = undefined; } catch (e) {}
+				}
+			}]);
+			apply(mapForEach, saved, [function(before, key) {
+				var now = describe(obj, key);
+				if (now && same(now, before)) {
+					return;
+				}
+				if (!define(obj, key, before) && apply(hasOwn, before, ["value"])) {
+					try { obj[key] = before.value; } catch (e) {}
+				}
+			}]);
+		} catch (e) {}
+	}
+
+	return function() {
+		apply(forEach, records, [restore]);
+	};
+})`
+
+var globalRollbackProgram = goja.MustCompile("rollback.js", globalRollbackSource, false)
+
+// snapshotGlobalObject records the global object's own properties, and those of
+// the objects its globals hold, and returns a function that restores them in
+// place, with the limits described on LoadScriptsAtomic.
+func (r *Runner) snapshotGlobalObject() (rollback func() error, err error) {
+	value, err := r.vm.RunProgram(globalRollbackProgram)
+	if err != nil {
+		return nil, err
+	}
+	snapshot, ok := goja.AssertFunction(value)
+	if !ok {
+		return nil, fmt.Errorf("rollback snapshot is not a function")
+	}
+	value, err = snapshot(goja.Undefined(), r.vm.ToValue(builtinGlobalNames()))
+	if err != nil {
+		return nil, err
+	}
+	restore, ok := goja.AssertFunction(value)
+	if !ok {
+		return nil, fmt.Errorf("rollback snapshot is not a function")
+	}
+	return func() error {
+		// The failure being undone may have left an interrupt pending; clear
+		// it so the rollback itself can run.
+		r.clearBoundError()
+		r.vm.ClearInterrupt()
+		_, err := restore(goja.Undefined())
+		return err
+	}, nil
+}
+
+var (
+	builtinGlobalsOnce sync.Once
+	builtinGlobals     []string
+)
+
+// builtinGlobalNames returns the names of the globals a new goja runtime
+// defines.
+func builtinGlobalNames() []string {
+	builtinGlobalsOnce.Do(func() {
+		builtinGlobals = goja.New().GlobalObject().GetOwnPropertyNames()
+	})
+	return builtinGlobals
+}
@@ -0,0 +1,81 @@
+package jsrunner
+
+import (
+	"encoding/base64"
+	"strings"
+
+	"github.com/dop251/goja"
+)
+
+// WithBase64Globals installs the browser atob and btoa functions. btoa encodes
+// a binary string, whose characters must all be in the Latin-1 range, as
+// standard base64; atob decodes base64 (ASCII whitespace and missing padding
+// are tolerated) back into a binary string with one character per byte. Like
+// in browsers, both throw an InvalidCharacterError when the input cannot be
+// converted, so Unicode text must be encoded to bytes before calling btoa.
+//
+// Example:
+//
+//	runner := jsrunner.New(jsrunner.WithBase64Globals())
+//	runner.Eval(`btoa("hello")`)    // "aGVsbG8="
+//	runner.Eval(`atob("aGVsbG8=")`) // "hello"
+func WithBase64Globals() Option {
+	return func(r *Runner) {
+		r.base64Globals = true
+	}
+}
+
+// base64Globals returns the atob and btoa functions.
+func base64Globals() map[string]interface{} {
+	return map[string]interface{}{
+		"atob": atob,
+		"btoa": btoa,
+	}
+}
+
+func btoa(call goja.FunctionCall, vm *goja.Runtime) goja.Value {
+	input := call.Argument(0).String()
+	data := make([]byte, 0, len(input))
+	for _, c := range input {
+		if c > 0xFF {
+			panic(invalidCharacterError(vm, "btoa: the string contains characters outside of the Latin1 range"))
+		}
+		data = append(data, byte(c))
+	}
+	return vm.ToValue(base64.StdEncoding.EncodeToString(data))
+}
+
+func atob(call goja.FunctionCall, vm *goja.Runtime) goja.Value {
+	input := strings.Map(func(c rune) rune {
+		switch c {
+		case ' ', '\t', '\n', '\f', '\r':
+			return -1
+		}
+		return c
+	}, call.Argument(0).String())
+	if len(input)%4 == 0 {
+		input = strings.TrimSuffix(strings.TrimSuffix(input, "="), "=")
+	}
+
+	data, err := base64.RawStdEncoding.DecodeString(input)
+	if err != nil {
+		panic(invalidCharacterError(vm, "atob: the string to be decoded is not correctly encoded"))
+	}
+	chars := make([]rune, len(data))
+	for i, b := range data {
+		chars[i] = rune(b)
+	}
+	return vm.ToValue(string(chars))
+}
+
+// invalidCharacterError builds the exception browsers raise for malformed
+// atob and btoa input.
+func invalidCharacterError(vm *goja.Runtime, message string) *goja.Object {
+	errCtor, _ := goja.AssertConstructor(vm.Get("Error"))
+	obj, err := errCtor(nil, vm.ToValue(message))
+	if err != nil {
+		panic(err)
+	}
+	_ = obj.Set("name", "InvalidCharacterError")
+	return obj
+}
@@ -0,0 +1,117 @@
+package jsrunner
+
+import (
+	"reflect"
+
+	"github.com/dop251/goja"
+)
+
+// BoundErrorMode selects how errors returned by Go functions bound into the
+// runtime reach the caller.
+type BoundErrorMode int
+
+const (
+	// BoundErrorThrow turns a non-nil error returned by a bound function into
+	// a JavaScript exception. Scripts can catch it with try/catch; when
+	// uncaught, the Eval or Call that started the script fails with a
+	// *goja.Exception that unwraps to the original error.
+	BoundErrorThrow BoundErrorMode = iota
+
+	// BoundErrorReturn aborts the running script as soon as a bound function
+	// returns a non-nil error. The error cannot be caught by JavaScript
+	// (catch and finally blocks are skipped), and the Eval or Call that
+	// started the script returns it with a nil value; errors.Is and errors.As
+	// match the original error. The same holds when Go invokes a bound
+	// function directly with Call, CallOn, or an ExportFunc closure.
+	BoundErrorReturn
+)
+
+// WithBoundErrorMode controls how Go functions of the form func(...) (T, error)
+// or func(...) error, installed with SetGlobal, WithGlobals, SetGlobalObject,
+// or passed as Call arguments, surface non-nil errors. The default,
+// BoundErrorThrow, raises them as JavaScript exceptions. BoundErrorReturn
+// makes the whole Eval, Call, or LoadScript call fail with the Go error
+// instead, which suits host functions whose failures scripts must not be able
+// to swallow.
+//
+// With BoundErrorReturn, the built-in fetch helpers follow the same rule. The
+// mode applies to Runner only; NewEventLoopRunner ignores the option and logs
+// a warning.
+//
+// Example:
+//
+//	runner := jsrunner.New(jsrunner.WithBoundErrorMode(jsrunner.BoundErrorReturn))
+//	runner.SetGlobal("charge", func(cents int) (string, error) {
+//	    return "", ErrCardDeclined
+//	})
+//	_, err := runner.Eval(`try { charge(500) } catch (e) { "ignored" }`)
+//	errors.Is(err, ErrCardDeclined) // true
+func WithBoundErrorMode(mode BoundErrorMode) Option {
+	return func(r *Runner) {
+		r.boundErrorMode = mode
+	}
+}
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// returnBoundErrors wraps Go functions whose last result is an error so that,
+// in BoundErrorReturn mode, a non-nil error interrupts the runtime instead of
+// being thrown. Other values are returned unchanged.
+func (r *Runner) returnBoundErrors(v interface{}) interface{} {
+	if r.boundErrorMode != BoundErrorReturn {
+		return v
+	}
+	fn := reflect.ValueOf(v)
+	if fn.Kind() != reflect.Func || fn.IsNil() {
+		return v
+	}
+	fnType := fn.Type()
+	if fnType.NumOut() == 0 || fnType.Out(fnType.NumOut()-1) != errorType {
+		return v
+	}
+
+	return reflect.MakeFunc(fnType, func(args []reflect.Value) []reflect.Value {
+		var out []reflect.Value
+		if fnType.IsVariadic() {
+			out = fn.CallSlice(args)
+		} else {
+			out = fn.Call(args)
+		}
+		last := len(out) - 1
+		if err, _ := out[last].Interface().(error); err != nil {
+			if r.pendingBoundError == nil {
+				r.pendingBoundError = err
+			}
+			r.vm.Interrupt(err)
+			out[last] = reflect.Zero(errorType)
+		}
+		return out
+	}).Interface()
+}
+
+// boundError returns err, or the error raised by a bound function in
+// BoundErrorReturn mode when goja finished without noticing the interrupt.
+// That happens when Go calls the bound function directly (Call, CallOn, or
+// ExportFunc on it) or when the call is the script's last instruction.
+func (r *Runner) boundError(err error) error {
+	if err == nil && r.pendingBoundError != nil {
+		return r.pendingBoundError
+	}
+	return err
+}
+
+// runProgram runs p on the runner's runtime, reporting an error left by a
+// bound function.
+func (r *Runner) runProgram(p *goja.Program) (goja.Value, error) {
+	result, err := r.vm.RunProgram(p)
+	return result, r.boundError(err)
+}
+
+// clearBoundError resets the interrupt raised by a bound function once the
+// operation it aborted has returned, so the next call runs normally.
+func (r *Runner) clearBoundError() {
+	if r.pendingBoundError != nil {
+		r.pendingBoundError = nil
+		r.vm.ClearInterrupt()
+	}
+}
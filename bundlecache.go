@@ -0,0 +1,77 @@
+package jsrunner
+
+import (
+	"sync"
+
+	"github.com/boomhut/goja-runner/internal/bundler"
+)
+
+// sharedBundles is the process-wide bundle cache consulted by NewReactApp
+// when ReactAppOptions.ShareBundles is set.
+var sharedBundles = &bundleCache{entries: make(map[string]*bundleCacheEntry)}
+
+// bundleCacheEntry is a build that is running or has finished. done is
+// closed once bundles and err are set.
+type bundleCacheEntry struct {
+	done    chan struct{}
+	bundles *bundler.ReactBundles
+	err     error
+}
+
+// bundleCache deduplicates React builds by bundler.ReactOptions.CacheKey.
+// Concurrent requests for the same key wait for a single build; failed builds
+// are not cached, and neither are builds whose local input files have changed
+// since.
+type bundleCache struct {
+	mu      sync.Mutex
+	entries map[string]*bundleCacheEntry
+}
+
+// get returns the bundles cached for opts, running build when there are none
+// or when a file the cached bundles were built from has changed. hit reports
+// whether the bundles came from an earlier or concurrent build.
+func (c *bundleCache) get(opts bundler.ReactOptions, build func() (*bundler.ReactBundles, error)) (bundles *bundler.ReactBundles, hit bool, err error) {
+	key := opts.CacheKey()
+
+	c.mu.Lock()
+	for {
+		entry, ok := c.entries[key]
+		if !ok {
+			break
+		}
+		c.mu.Unlock()
+		<-entry.done
+		if entry.err != nil || !entry.bundles.InputsChanged() {
+			return entry.bundles, true, entry.err
+		}
+		c.mu.Lock()
+		if c.entries[key] == entry {
+			delete(c.entries, key)
+		}
+	}
+	entry := &bundleCacheEntry{done: make(chan struct{})}
+	c.entries[key] = entry
+	c.mu.Unlock()
+
+	entry.bundles, entry.err = build()
+	if entry.err != nil {
+		c.mu.Lock()
+		delete(c.entries, key)
+		c.mu.Unlock()
+	}
+	close(entry.done)
+	return entry.bundles, false, entry.err
+}
+
+func (c *bundleCache) clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = make(map[string]*bundleCacheEntry)
+}
+
+// ClearSharedBundleCache drops every bundle kept by the process-wide cache
+// used with ReactAppOptions.ShareBundles, so the next NewReactApp rebuilds
+// from source. Apps created earlier keep their bundles.
+func ClearSharedBundleCache() {
+	sharedBundles.clear()
+}
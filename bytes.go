@@ -0,0 +1,51 @@
+package jsrunner
+
+import (
+	"unsafe"
+
+	"github.com/dop251/goja"
+)
+
+// EvalBytes evaluates JavaScript source held in a byte slice, like Eval. The
+// bytes are handed to the compiler without copying them into a new string,
+// which halves peak memory when evaluating multi-megabyte bundles read from
+// disk or the network.
+//
+// The runtime may keep referencing code after the call returns (for example
+// in Function.prototype.toString or stack traces), so the caller must not
+// modify code afterwards. With WithProgramCache, code is copied before it is
+// cached, so the cached program does not depend on the caller's buffer and
+// the saving applies only to programs that are not cached.
+//
+// Example:
+//
+//	code, _ := os.ReadFile("bundle.js")
+//	result, err := runner.EvalBytes(code)
+func (r *Runner) EvalBytes(code []byte) (goja.Value, error) {
+	return r.Eval(bytesToString(code))
+}
+
+// LoadScriptBytes loads and executes JavaScript source held in a byte slice,
+// like LoadScriptString, without copying it into a new string. As with
+// EvalBytes, code must not be modified after the call.
+//
+// Example:
+//
+//	resp, _ := http.Get("https://cdn.example.com/bundle.js")
+//	defer resp.Body.Close()
+//	code, _ := io.ReadAll(resp.Body)
+//	if err := runner.LoadScriptBytes(code); err != nil {
+//	    log.Fatal(err)
+//	}
+func (r *Runner) LoadScriptBytes(code []byte) error {
+	return r.LoadScriptString(bytesToString(code))
+}
+
+// bytesToString returns a string sharing b's memory. The caller must own b
+// and never modify it again.
+func bytesToString(b []byte) string {
+	if len(b) == 0 {
+		return ""
+	}
+	return unsafe.String(unsafe.SliceData(b), len(b))
+}
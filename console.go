@@ -0,0 +1,90 @@
+package jsrunner
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/dop251/goja"
+)
+
+// LogEntry is a single console call captured by EvalCapture.
+type LogEntry struct {
+	// Level is the console method that was called: "log", "info", "warn",
+	// "error", or "debug".
+	Level string
+
+	// Message is the space-separated rendering of the call's arguments.
+	// Strings are used verbatim and objects are rendered as JSON.
+	Message string
+}
+
+var consoleLevels = []string{"log", "info", "warn", "error", "debug"}
+
+// EvalCapture evaluates code like Eval while recording everything it writes to
+// the console. A capturing console is installed for the duration of the call
+// and the previous console (if any) is restored afterwards, so captured output
+// never leaks into other calls. Entries are returned in call order, even when
+// evaluation fails.
+//
+// Example:
+//
+//	value, logs, err := runner.EvalCapture(`
+//	    console.log("starting");
+//	    console.warn("low disk");
+//	    42;
+//	`)
+//	for _, entry := range logs {
+//	    fmt.Printf("[%s] %s\n", entry.Level, entry.Message)
+//	}
+func (r *Runner) EvalCapture(code string) (value goja.Value, logs []LogEntry, err error) {
+	defer r.reportError("EvalCapture", code, &err)
+
+	r.syncLock()
+	defer r.syncUnlock()
+
+	global := r.vm.GlobalObject()
+	previous := global.Get("console")
+
+	console := r.vm.NewObject()
+	for _, level := range consoleLevels {
+		console.Set(level, func(call goja.FunctionCall) goja.Value {
+			logs = append(logs, LogEntry{Level: level, Message: formatConsoleArgs(call.Arguments)})
+			return goja.Undefined()
+		})
+	}
+	global.Set("console", console)
+
+	defer func() {
+		if previous == nil {
+			global.Delete("console")
+			return
+		}
+		global.Set("console", previous)
+	}()
+
+	value, err = r.eval(code)
+	return value, logs, err
+}
+
+func formatConsoleArgs(args []goja.Value) string {
+	parts := make([]string, len(args))
+	for i, arg := range args {
+		parts[i] = formatConsoleArg(arg)
+	}
+	return strings.Join(parts, " ")
+}
+
+func formatConsoleArg(arg goja.Value) string {
+	if arg == nil || goja.IsUndefined(arg) || goja.IsNull(arg) {
+		return fmt.Sprint(arg)
+	}
+	if _, isObject := arg.(*goja.Object); isObject {
+		if _, isFunc := goja.AssertFunction(arg); !isFunc {
+			if encoded, err := json.Marshal(arg.Export()); err == nil {
+				return string(encoded)
+			}
+		}
+	}
+	return arg.String()
+}
@@ -0,0 +1,173 @@
+package jsrunner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/dop251/goja"
+)
+
+// ErrTimeout is returned, wrapped with the limit that was exceeded, when
+// EvalTimeout or CallTimeout interrupt a script that ran too long.
+var ErrTimeout = errors.New("execution timed out")
+
+// CallContext is like Call but makes ctx available to Go functions invoked by
+// the script through RunnerContext. When ctx is canceled or its deadline
+// passes, the running script is interrupted and the call returns an error.
+// The built-in fetch helpers also derive their request context from ctx.
+//
+// Example:
+//
+//	runner.SetGlobal("lookupUser", func(id string) (User, error) {
+//	    return db.FindUser(jsrunner.RunnerContext(runner), id)
+//	})
+//	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+//	defer cancel()
+//	result, err := runner.CallContext(ctx, "handleRequest", payload)
+func (r *Runner) CallContext(ctx context.Context, functionName string, args ...interface{}) (value goja.Value, err error) {
+	defer r.reportError("CallContext", functionName, &err)
+
+	r.syncLock()
+	defer r.syncUnlock()
+
+	release := r.bindContext(ctx)
+	defer release()
+
+	return r.call(functionName, args...)
+}
+
+// EvalContext is like Eval but makes ctx available to Go functions invoked by
+// the expression through RunnerContext, and interrupts evaluation when ctx is
+// done.
+//
+// Example:
+//
+//	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+//	defer cancel()
+//	result, err := runner.EvalContext(ctx, "fetchJSON(apiUrl).items.length")
+func (r *Runner) EvalContext(ctx context.Context, expression string) (value goja.Value, err error) {
+	defer r.reportError("EvalContext", expression, &err)
+
+	r.syncLock()
+	defer r.syncUnlock()
+
+	release := r.bindContext(ctx)
+	defer release()
+
+	return r.eval(expression)
+}
+
+// EvalTimeout is like Eval but interrupts the evaluation once it has run for d,
+// returning an error that wraps ErrTimeout. It is a shorthand for EvalContext
+// with context.WithTimeout when no caller context needs to be threaded
+// through.
+//
+// Example:
+//
+//	result, err := runner.EvalTimeout(userExpression, 100*time.Millisecond)
+//	if errors.Is(err, jsrunner.ErrTimeout) {
+//	    return fmt.Errorf("expression too slow: %w", err)
+//	}
+func (r *Runner) EvalTimeout(expression string, d time.Duration) (value goja.Value, err error) {
+	defer r.reportError("EvalTimeout", expression, &err)
+
+	r.syncLock()
+	defer r.syncUnlock()
+
+	release := r.bindTimeout(d)
+	value, err = r.eval(expression)
+	if release() && err != nil {
+		return nil, fmt.Errorf("%w after %v", ErrTimeout, d)
+	}
+	return value, err
+}
+
+// CallTimeout is like Call but interrupts the function once it has run for d,
+// returning an error that wraps ErrTimeout.
+//
+// Example:
+//
+//	result, err := runner.CallTimeout("score", 50*time.Millisecond, candidate)
+func (r *Runner) CallTimeout(functionName string, d time.Duration, args ...interface{}) (value goja.Value, err error) {
+	defer r.reportError("CallTimeout", functionName, &err)
+
+	r.syncLock()
+	defer r.syncUnlock()
+
+	release := r.bindTimeout(d)
+	value, err = r.call(functionName, args...)
+	if release() && err != nil {
+		return nil, fmt.Errorf("%w after %v", ErrTimeout, d)
+	}
+	return value, err
+}
+
+// bindTimeout interrupts the VM once d has elapsed. The returned release
+// function cancels the timer, clears an interrupt that fired, and reports
+// whether it did.
+func (r *Runner) bindTimeout(d time.Duration) (release func() (timedOut bool)) {
+	fired := make(chan struct{})
+	timer := time.AfterFunc(d, func() {
+		r.vm.Interrupt(ErrTimeout)
+		close(fired)
+	})
+
+	return func() bool {
+		if timer.Stop() {
+			return false
+		}
+		<-fired
+		r.vm.ClearInterrupt()
+		return true
+	}
+}
+
+// RunnerContext returns the context passed to the CallContext or EvalContext
+// call currently executing on r. Outside of such a call, or when r is nil, it
+// returns context.Background().
+//
+// Bound Go functions use it to honor the caller's deadline, cancellation, and
+// request-scoped values such as trace IDs or auth information.
+func RunnerContext(r *Runner) context.Context {
+	if r == nil || r.ctx == nil {
+		return context.Background()
+	}
+	return r.ctx
+}
+
+// bindContext installs ctx as the runner's active context and interrupts the VM
+// if ctx is done before the returned release function is called. Release
+// restores the previous context, so nested calls unwind correctly.
+func (r *Runner) bindContext(ctx context.Context) (release func()) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	previous := r.ctx
+	r.ctx = ctx
+
+	if ctx.Done() == nil {
+		return func() { r.ctx = previous }
+	}
+
+	stop := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		select {
+		case <-ctx.Done():
+			r.vm.Interrupt(ctx.Err())
+		case <-stop:
+		}
+	}()
+
+	return func() {
+		close(stop)
+		<-stopped
+		if ctx.Err() != nil {
+			r.vm.ClearInterrupt()
+		}
+		r.ctx = previous
+	}
+}
@@ -0,0 +1,423 @@
+package jsrunner
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/dop251/goja"
+)
+
+// ValueConverter maps a Go value to a JavaScript value before goja's default
+// conversion runs. Returning false falls back to the default behavior.
+type ValueConverter func(v interface{}) (goja.Value, bool)
+
+// WithValueConverter installs a hook that is consulted for every value passed
+// through SetGlobal and every argument passed to Call. It lets callers map
+// domain types (decimals, money, IDs) to JavaScript-friendly representations.
+// It applies to Runner only; NewEventLoopRunner ignores it and logs a warning.
+//
+// The converter also sees values nested inside slices and string-keyed maps.
+// To make that possible, containers are copied into native JavaScript arrays
+// and objects while a converter is configured, rather than wrapped live:
+// changes a script makes to them are not visible to Go, and Go changes made
+// after SetGlobal or Call are not visible to the script. A container that
+// appears several times, including one that contains itself, is copied once
+// and every occurrence refers to the same JavaScript value.
+//
+// The converter usually needs the runtime to build objects, so capture the
+// runner in the closure:
+//
+//	var runner *jsrunner.Runner
+//	runner = jsrunner.New(jsrunner.WithValueConverter(func(v interface{}) (goja.Value, bool) {
+//	    m, ok := v.(Money)
+//	    if !ok {
+//	        return nil, false
+//	    }
+//	    return runner.GetVM().ToValue(map[string]interface{}{
+//	        "amount":   m.Amount,
+//	        "currency": m.Currency,
+//	    }), true
+//	}))
+func WithValueConverter(fn ValueConverter) Option {
+	return func(r *Runner) {
+		r.valueConverter = fn
+	}
+}
+
+// ExportConverter maps a JavaScript value to a Go value before the default
+// Export conversion runs. Returning false falls back to the default behavior.
+type ExportConverter func(val goja.Value) (interface{}, bool)
+
+// WithExportConverter installs a hook consulted by ExportWith before the
+// default Export conversion. It is the counterpart to WithValueConverter and
+// allows specialized JavaScript values to round-trip losslessly into Go types.
+// Like WithValueConverter, it is ignored by NewEventLoopRunner, which logs a
+// warning.
+//
+// Example:
+//
+//	runner := jsrunner.New(jsrunner.WithExportConverter(func(val goja.Value) (interface{}, bool) {
+//	    if !goja.IsBigInt(val) {
+//	        return nil, false
+//	    }
+//	    n, ok := new(big.Int).SetString(val.String(), 10)
+//	    return n, ok
+//	}))
+func WithExportConverter(fn ExportConverter) Option {
+	return func(r *Runner) {
+		r.exportConverter = fn
+	}
+}
+
+// ExportWith converts a goja.Value to a Go value, consulting the runner's
+// ExportConverter (if any) before falling back to Export.
+//
+// Example:
+//
+//	result, _ := runner.Eval("2n ** 64n")
+//	n := jsrunner.ExportWith(runner, result).(*big.Int)
+func ExportWith(r *Runner, val goja.Value) interface{} {
+	exported, err := exportWith(r, val)
+	if err != nil {
+		r.logger.Warn("integer conversion skipped", "error", err)
+		return Export(val)
+	}
+	return exported
+}
+
+// exportWith implements ExportWith, reporting the values WithIntegerNumbers
+// cannot convert instead of falling back to Export.
+func exportWith(r *Runner, val goja.Value) (interface{}, error) {
+	if val == nil {
+		return nil, nil
+	}
+	if obj, ok := val.(*goja.Object); ok && r != nil && len(r.classExporters) > 0 {
+		if fn, ok := r.classExporters[constructorName(r.vm, obj)]; ok {
+			return fn(val), nil
+		}
+	}
+	if r != nil && r.exportConverter != nil {
+		if exported, ok := r.exportConverter(val); ok {
+			return exported, nil
+		}
+	}
+	if r != nil && r.integerNumbers {
+		return integerNumbers(Export(val))
+	}
+	return Export(val), nil
+}
+
+// RegisterClassExporter makes ExportWith convert instances of the JavaScript
+// class named className with fn instead of the default conversion. A value is
+// an instance when its constructor's name property equals className, so
+// subclasses need their own registration. If reading the constructor throws
+// (a getter or a Proxy trap), the value's internal class name, such as
+// "Object", is matched instead. Class exporters are consulted
+// before the ExportConverter; values of other classes fall through to it and
+// then to Export. Only the value passed to ExportWith is matched, not
+// instances nested inside it.
+//
+// Register exporters during setup, before the runner is shared between
+// goroutines. Registering the same name again replaces the exporter.
+//
+// Example:
+//
+//	type Money struct {
+//	    Cents    int64
+//	    Currency string
+//	}
+//
+//	runner.RegisterClassExporter("Money", func(val goja.Value) interface{} {
+//	    obj := val.ToObject(runner.GetVM())
+//	    return Money{Cents: obj.Get("cents").ToInteger(), Currency: obj.Get("currency").String()}
+//	})
+//	result, _ := runner.Eval(`new Money(1250, "EUR")`)
+//	price := jsrunner.ExportWith(runner, result).(Money)
+func (r *Runner) RegisterClassExporter(className string, fn func(goja.Value) interface{}) {
+	r.syncLock()
+	defer r.syncUnlock()
+
+	if r.classExporters == nil {
+		r.classExporters = make(map[string]func(goja.Value) interface{})
+	}
+	r.classExporters[className] = fn
+}
+
+// WithIntegerNumbers makes ExportWith return int64 for JavaScript numbers that
+// are integral and within int64 range, including numbers nested in exported
+// objects and arrays. Other numbers remain float64. Without it, integral
+// values produced by arithmetic or JSON.parse export as float64, so IDs must
+// be re-cast by the caller.
+//
+// A value that contains itself cannot be converted. ExportWith then logs a
+// warning and returns the default Export result, and EvalExports returns an
+// error. NewEventLoopRunner ignores the option and logs a warning.
+//
+// Example:
+//
+//	runner := jsrunner.New(jsrunner.WithIntegerNumbers())
+//	result, _ := runner.Eval(`JSON.parse('{"id": 9007199254740991, "ratio": 0.5}')`)
+//	obj := jsrunner.ExportWith(runner, result).(map[string]interface{})
+//	// obj["id"] is int64(9007199254740991), obj["ratio"] is float64(0.5)
+func WithIntegerNumbers() Option {
+	return func(r *Runner) {
+		r.integerNumbers = true
+	}
+}
+
+// ExportNumber is a helper function that exports a JavaScript number as int64
+// when it is integral and fits in int64, and as float64 otherwise. Non-number
+// values are exported with Export.
+//
+// Example:
+//
+//	result, _ := runner.Eval("2 ** 53")
+//	id := jsrunner.ExportNumber(result) // int64(9007199254740992)
+func ExportNumber(val goja.Value) interface{} {
+	if val == nil {
+		return nil
+	}
+	return integerNumber(val.Export())
+}
+
+// integerNumbers converts integral float64 values to int64 throughout an
+// exported value, descending into maps and slices. It builds new maps and
+// slices rather than updating them in place, because Export returns the
+// original Go value for maps and slices passed in with SetGlobal. It returns
+// an error when a map or slice contains itself, as Export produces for a
+// cyclic JavaScript object.
+func integerNumbers(v interface{}) (interface{}, error) {
+	return integerNumbersVisiting(v, make(map[visitKey]struct{}))
+}
+
+// integerNumbersVisiting implements integerNumbers. visiting holds the
+// containers on the path from the root, so shared containers are converted
+// once per occurrence but a cycle is reported.
+func integerNumbersVisiting(v interface{}, visiting map[visitKey]struct{}) (interface{}, error) {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		key := visitKey{ptr: reflect.ValueOf(t).Pointer(), typ: reflect.TypeOf(t)}
+		if _, ok := visiting[key]; ok {
+			return nil, fmt.Errorf("cycle detected through %T", t)
+		}
+		visiting[key] = struct{}{}
+		defer delete(visiting, key)
+		out := make(map[string]interface{}, len(t))
+		for k, item := range t {
+			converted, err := integerNumbersVisiting(item, visiting)
+			if err != nil {
+				return nil, err
+			}
+			out[k] = converted
+		}
+		return out, nil
+	case []interface{}:
+		if len(t) > 0 {
+			key := visitKey{ptr: reflect.ValueOf(t).Pointer(), typ: reflect.TypeOf(t), len: len(t)}
+			if _, ok := visiting[key]; ok {
+				return nil, fmt.Errorf("cycle detected through %T", t)
+			}
+			visiting[key] = struct{}{}
+			defer delete(visiting, key)
+		}
+		out := make([]interface{}, len(t))
+		for i, item := range t {
+			converted, err := integerNumbersVisiting(item, visiting)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = converted
+		}
+		return out, nil
+	default:
+		return integerNumber(v), nil
+	}
+}
+
+func integerNumber(v interface{}) interface{} {
+	f, ok := v.(float64)
+	if !ok {
+		return v
+	}
+	if f != math.Trunc(f) || f < math.MinInt64 || f >= math.MaxInt64 {
+		return f
+	}
+	return int64(f)
+}
+
+// ExportBytes is a helper function that converts a JavaScript Uint8Array or
+// ArrayBuffer into a Go byte slice. The returned slice is a copy, so it remains
+// valid after the JavaScript value is mutated or garbage collected.
+//
+// The second return value reports whether val was a Uint8Array or ArrayBuffer.
+//
+// Example:
+//
+//	result, _ := runner.Eval("new Uint8Array([1, 2, 3])")
+//	data, ok := jsrunner.ExportBytes(result) // []byte{1, 2, 3}, true
+func ExportBytes(val goja.Value) ([]byte, bool) {
+	if val == nil {
+		return nil, false
+	}
+	switch v := val.Export().(type) {
+	case []byte:
+		return append([]byte(nil), v...), true
+	case goja.ArrayBuffer:
+		return append([]byte(nil), v.Bytes()...), true
+	default:
+		return nil, false
+	}
+}
+
+// NewUint8Array creates a JavaScript Uint8Array holding a copy of data, suitable
+// for passing binary input to scripts via SetGlobal or Call. It uses the
+// global Uint8Array constructor and returns an error if a script has deleted
+// or replaced it with something that cannot be constructed.
+//
+// Example:
+//
+//	input, err := runner.NewUint8Array([]byte("hello"))
+//	runner.SetGlobal("input", input)
+//	result, _ := runner.Eval("input.length") // 5
+func (r *Runner) NewUint8Array(data []byte) (goja.Value, error) {
+	return newUint8Array(r.vm, append([]byte(nil), data...))
+}
+
+// newUint8Array wraps data, without copying it, in a Uint8Array of vm.
+func newUint8Array(vm *goja.Runtime, data []byte) (goja.Value, error) {
+	arr, err := vm.New(vm.Get("Uint8Array"), vm.ToValue(vm.NewArrayBuffer(data)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Uint8Array: %w", err)
+	}
+	return arr, nil
+}
+
+// WithTimeConversion converts time values passed through SetGlobal and Call
+// into natural JavaScript representations:
+//   - time.Duration becomes a number of milliseconds (fractions preserved)
+//   - time.Time becomes a JavaScript Date
+//
+// Without this option goja exposes a Duration as raw int64 nanoseconds and a
+// Time as an opaque wrapped struct. A configured ValueConverter still takes
+// precedence. Time values nested in slices and maps are converted too, so
+// while this option is set, slices and maps are copied into native JavaScript
+// arrays and objects instead of being wrapped: changes a script makes to them
+// are not visible to Go, and Go changes made after the call are not visible
+// to the script. It applies to Runner only; NewEventLoopRunner ignores it and
+// logs a warning.
+//
+// Example:
+//
+//	runner := jsrunner.New(jsrunner.WithTimeConversion())
+//	runner.SetGlobal("timeout", 1500*time.Millisecond)
+//	runner.SetGlobal("now", time.Now())
+//	runner.Eval("timeout / 1000 + 's since ' + now.toISOString()")
+func WithTimeConversion() Option {
+	return func(r *Runner) {
+		r.timeConversion = true
+	}
+}
+
+// toValue converts a Go value into a goja.Value, consulting the configured
+// ValueConverter before falling back to goja's default conversion.
+func (r *Runner) toValue(v interface{}) goja.Value {
+	return r.convertValue(v, nil)
+}
+
+// convertValue implements toValue. copied holds the containers already copied
+// by the current conversion, so shared and self-referencing containers map to
+// a single JavaScript value instead of being copied again without end.
+func (r *Runner) convertValue(v interface{}, copied map[visitKey]goja.Value) goja.Value {
+	if val, ok := v.(goja.Value); ok {
+		return val
+	}
+	v = r.returnBoundErrors(v)
+	if r.valueConverter != nil {
+		if val, ok := r.valueConverter(v); ok {
+			return val
+		}
+	}
+	if r.timeConversion {
+		if val, ok := r.timeToValue(v); ok {
+			return val
+		}
+	}
+	if r.valueConverter != nil || r.timeConversion {
+		if val, ok := r.containerToValue(v, copied); ok {
+			return val
+		}
+	}
+	return r.vm.ToValue(v)
+}
+
+// containerToValue copies Go slices, arrays, and string-keyed maps into native
+// JavaScript arrays and objects, converting every element with toValue so the
+// configured converters apply at any nesting depth. goja's default conversion
+// wraps containers instead and hands nested elements to ToValue directly,
+// bypassing the converters. Byte slices keep their default conversion.
+//
+// Each slice and map is recorded in copied before its elements are converted,
+// so a container reached again returns the same JavaScript value.
+func (r *Runner) containerToValue(v interface{}, copied map[visitKey]goja.Value) (goja.Value, bool) {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		if rv.Kind() == reflect.Slice && rv.IsNil() || rv.Type().Elem().Kind() == reflect.Uint8 {
+			return nil, false
+		}
+		arr := r.vm.NewArray()
+		if rv.Kind() == reflect.Slice {
+			key := visitKey{ptr: rv.Pointer(), typ: rv.Type(), len: rv.Len()}
+			if val, ok := copied[key]; ok {
+				return val, true
+			}
+			if copied == nil {
+				copied = make(map[visitKey]goja.Value)
+			}
+			copied[key] = arr
+		}
+		for i := 0; i < rv.Len(); i++ {
+			_ = arr.Set(strconv.Itoa(i), r.convertValue(rv.Index(i).Interface(), copied))
+		}
+		return arr, true
+	case reflect.Map:
+		if rv.IsNil() || rv.Type().Key().Kind() != reflect.String {
+			return nil, false
+		}
+		key := visitKey{ptr: rv.Pointer(), typ: rv.Type()}
+		if val, ok := copied[key]; ok {
+			return val, true
+		}
+		if copied == nil {
+			copied = make(map[visitKey]goja.Value)
+		}
+		obj := r.vm.NewObject()
+		copied[key] = obj
+		keys := rv.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+		for _, key := range keys {
+			_ = obj.Set(key.String(), r.convertValue(rv.MapIndex(key).Interface(), copied))
+		}
+		return obj, true
+	}
+	return nil, false
+}
+
+func (r *Runner) timeToValue(v interface{}) (goja.Value, bool) {
+	switch t := v.(type) {
+	case time.Duration:
+		return r.vm.ToValue(float64(t) / float64(time.Millisecond)), true
+	case time.Time:
+		date, err := r.vm.New(r.vm.Get("Date"), r.vm.ToValue(t.UnixMilli()))
+		if err != nil {
+			return nil, false
+		}
+		return date, true
+	default:
+		return nil, false
+	}
+}
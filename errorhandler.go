@@ -0,0 +1,43 @@
+package jsrunner
+
+// WithErrorHandler registers fn to be called whenever an Eval, Call, or
+// LoadScript method fails, so errors can be logged or counted in one place
+// instead of at every call site. The caller still receives the error.
+//
+// op is the name of the failing method ("Eval", "EvalWith", "EvalContext",
+// "EvalTimeout", "EvalCapture", "EvalIsolated", "EvalExports", "Call",
+// "CallContext", "CallTimeout", "CallOn", "LoadScript", "LoadScriptString",
+// "LoadScriptFS", "LoadScriptReader", or "LoadScriptWithSourceMap"; EvalBytes
+// and LoadScriptBytes report as Eval and LoadScriptString). source identifies
+// what was running: the comma-separated global names for EvalExports, the
+// expression or code for the other Eval* methods, LoadScriptString, and
+// LoadScriptWithSourceMap, the function name for Call, CallContext, and
+// CallTimeout, and the file name for LoadScript and LoadScriptFS. It is empty
+// for CallOn and LoadScriptReader.
+//
+// fn runs on the calling goroutine after the runner's lock (if any) has been
+// released, so it may use the runner. It applies to Runner only;
+// NewEventLoopRunner ignores it and logs a warning.
+//
+// Example:
+//
+//	runner := jsrunner.New(jsrunner.WithErrorHandler(func(op, source string, err error) {
+//	    slog.Error("script failed", "op", op, "source", source, "err", err)
+//	    scriptErrors.Inc()
+//	}))
+func WithErrorHandler(fn func(op, source string, err error)) Option {
+	return func(r *Runner) {
+		r.errorHandler = fn
+	}
+}
+
+// reportError masks secrets registered with SetSecretGlobal in *err and then
+// passes it to the registered error handler when it is non-nil. Methods defer
+// it before acquiring the runner's lock so the handler runs after the lock is
+// released.
+func (r *Runner) reportError(op, source string, err *error) {
+	r.redactError(err)
+	if *err != nil && r.errorHandler != nil {
+		r.errorHandler(op, r.secrets.redact(source), *err)
+	}
+}
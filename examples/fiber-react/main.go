@@ -113,7 +113,8 @@ func NewReactRenderer() (*ReactRenderer, error) {
 	}
 
 	bundleDuration := time.Since(bootStart)
-	log.Printf("ReactApp bundled in %s", bundleDuration)
+	esbuildTime, loadTime := app.BuildStats()
+	log.Printf("ReactApp bundled in %s (esbuild %s, VM load %s)", bundleDuration, esbuildTime, loadTime)
 
 	return &ReactRenderer{app: app, bundleDuration: bundleDuration, metrics: metrics}, nil
 }
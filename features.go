@@ -0,0 +1,155 @@
+package jsrunner
+
+import (
+	"io"
+	"reflect"
+	"sync"
+
+	"github.com/dop251/goja"
+)
+
+// WithConsole installs a console object whose log, info, warn, error, and
+// debug methods write one line per call to w. Arguments are joined with
+// spaces; strings are written verbatim and objects as JSON, matching the
+// entries recorded by EvalCapture. Writes are serialized, so w may be shared
+// between runners.
+//
+// Example:
+//
+//	runner := jsrunner.New(jsrunner.WithConsole(os.Stderr))
+//	runner.Eval(`console.log("ready", { port: 8080 })`) // ready {"port":8080}
+func WithConsole(w io.Writer) Option {
+	return func(r *Runner) {
+		r.console = w
+	}
+}
+
+// features describes the globals contributed by feature options. Runner and
+// EventLoopRunner both build their feature globals from it, so every option
+// installs the same helpers under the same names in either runner type.
+type features struct {
+	webAccess    bool
+	fetchHelpers fetchHelpers
+	console      io.Writer
+	urlGlobals   bool
+	base64       bool
+	textCodecs   bool
+}
+
+// names returns the global names of every enabled feature.
+func (f features) names() []string {
+	var names []string
+	if f.webAccess {
+		names = append(names, f.fetchHelpers.names()...)
+	}
+	if f.console != nil {
+		names = append(names, "console")
+	}
+	if f.urlGlobals {
+		names = append(names, "URL", "URLSearchParams")
+	}
+	if f.base64 {
+		names = append(names, "atob", "btoa")
+	}
+	if f.textCodecs {
+		names = append(names, "TextEncoder", "TextDecoder")
+	}
+	return names
+}
+
+// globals returns the feature globals keyed by name. fetch performs the
+// runner's HTTP requests for the fetch helpers.
+func (f features) globals(fetch func(url string) ([]byte, error)) map[string]interface{} {
+	globals := make(map[string]interface{})
+
+	if f.webAccess && f.fetchHelpers.installText {
+		globals[f.fetchHelpers.name("fetchText")] = func(url string) (string, error) {
+			data, err := fetch(url)
+			if err != nil {
+				return "", err
+			}
+			return string(data), nil
+		}
+	}
+
+	if f.webAccess && f.fetchHelpers.installJSON {
+		globals[f.fetchHelpers.name("fetchJSON")] = func(url string) (interface{}, error) {
+			data, err := fetch(url)
+			if err != nil {
+				return nil, err
+			}
+			return f.fetchHelpers.parseJSON(data)
+		}
+	}
+
+	if f.webAccess && f.fetchHelpers.installBytes {
+		globals[f.fetchHelpers.name("fetchBytes")] = func(call goja.FunctionCall, vm *goja.Runtime) goja.Value {
+			data, err := fetch(call.Argument(0).String())
+			if err != nil {
+				panic(vm.NewGoError(err))
+			}
+			// data may be owned by the fetch cache; copy it so writes to the
+			// array cannot change later responses.
+			arr, err := newUint8Array(vm, append([]byte(nil), data...))
+			if err != nil {
+				panic(vm.NewGoError(err))
+			}
+			return arr
+		}
+	}
+
+	if f.console != nil {
+		globals["console"] = newConsole(f.console)
+	}
+
+	if f.urlGlobals {
+		for name, value := range urlGlobals() {
+			globals[name] = value
+		}
+	}
+
+	if f.base64 {
+		for name, value := range base64Globals() {
+			globals[name] = value
+		}
+	}
+
+	if f.textCodecs {
+		for name, value := range textCodecGlobals() {
+			globals[name] = value
+		}
+	}
+
+	return globals
+}
+
+// consoleLocks holds one mutex per console writer, so consoles installed in
+// different runtimes (the runner, its EvalIsolated runtimes, event loops, and
+// other runners) serialize their writes to the same writer.
+var consoleLocks sync.Map
+
+// consoleLock returns the mutex guarding writes to w. Writers whose dynamic
+// type cannot be a map key get a mutex of their own.
+func consoleLock(w io.Writer) *sync.Mutex {
+	if !reflect.TypeOf(w).Comparable() {
+		return new(sync.Mutex)
+	}
+	mu, _ := consoleLocks.LoadOrStore(w, new(sync.Mutex))
+	return mu.(*sync.Mutex)
+}
+
+// newConsole returns a console object writing formatted lines to w.
+func newConsole(w io.Writer) map[string]interface{} {
+	mu := consoleLock(w)
+	console := make(map[string]interface{}, len(consoleLevels))
+	for _, level := range consoleLevels {
+		console[level] = func(call goja.FunctionCall) goja.Value {
+			line := formatConsoleArgs(call.Arguments) + "\n"
+			mu.Lock()
+			_, _ = io.WriteString(w, line)
+			mu.Unlock()
+			return goja.Undefined()
+		}
+	}
+	return console
+}
@@ -0,0 +1,211 @@
+package jsrunner
+
+import (
+	"container/list"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// WithFetchCache memoizes successful fetch responses by URL for ttl, so scripts
+// that repeatedly request the same reference data do not hit the network each
+// time. At most maxEntries responses are kept; the least recently used entry is
+// evicted first. Responses carrying "Cache-Control: no-store" are never cached.
+//
+// The cache is shared by every call made through the runner. It has no effect
+// unless web access is enabled.
+//
+// Example:
+//
+//	runner := jsrunner.New(
+//	    jsrunner.WithWebAccess(nil),
+//	    jsrunner.WithFetchCache(time.Minute, 100),
+//	)
+func WithFetchCache(ttl time.Duration, maxEntries int) Option {
+	return func(r *Runner) {
+		r.fetchCache = newFetchCache(ttl, maxEntries)
+	}
+}
+
+type fetchCacheEntry struct {
+	url     string
+	data    []byte
+	expires time.Time
+}
+
+// fetchCache is a TTL-bounded LRU of byte payloads. The fetch helpers key it
+// by URL; ReactApp.RenderCached reuses it for markup keyed by cache key.
+type fetchCache struct {
+	mu         sync.Mutex
+	ttl        time.Duration
+	maxEntries int
+	entries    map[string]*list.Element
+	order      *list.List
+	now        func() time.Time
+}
+
+func newFetchCache(ttl time.Duration, maxEntries int) *fetchCache {
+	return &fetchCache{
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		entries:    make(map[string]*list.Element),
+		order:      list.New(),
+		now:        time.Now,
+	}
+}
+
+func (c *fetchCache) get(url string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[url]
+	if !ok {
+		return nil, false
+	}
+	entry := elem.Value.(*fetchCacheEntry)
+	if !c.now().Before(entry.expires) {
+		c.order.Remove(elem)
+		delete(c.entries, url)
+		return nil, false
+	}
+	c.order.MoveToFront(elem)
+	return entry.data, true
+}
+
+func (c *fetchCache) put(url string, data []byte) {
+	c.putFor(url, data, c.ttl)
+}
+
+// putFor stores data under url with its own ttl instead of the cache-wide one.
+func (c *fetchCache) putFor(url string, data []byte, ttl time.Duration) {
+	if ttl <= 0 || c.maxEntries <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	expires := c.now().Add(ttl)
+	if elem, ok := c.entries[url]; ok {
+		entry := elem.Value.(*fetchCacheEntry)
+		entry.data = data
+		entry.expires = expires
+		c.order.MoveToFront(elem)
+		return
+	}
+
+	c.entries[url] = c.order.PushFront(&fetchCacheEntry{url: url, data: data, expires: expires})
+	for c.order.Len() > c.maxEntries {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*fetchCacheEntry).url)
+	}
+}
+
+// keys returns the URLs of unexpired entries, most recently used first.
+func (c *fetchCache) keys() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	keys := make([]string, 0, c.order.Len())
+	for elem := c.order.Front(); elem != nil; elem = elem.Next() {
+		entry := elem.Value.(*fetchCacheEntry)
+		if now.Before(entry.expires) {
+			keys = append(keys, entry.url)
+		}
+	}
+	return keys
+}
+
+// remove drops the entry stored under url, if any.
+func (c *fetchCache) remove(url string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.entries[url]; ok {
+		c.order.Remove(elem)
+		delete(c.entries, url)
+	}
+}
+
+// clear drops every entry.
+func (c *fetchCache) clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries = make(map[string]*list.Element)
+	c.order.Init()
+}
+
+// FetchCacheKeys returns the URLs currently held by the cache configured with
+// WithFetchCache, most recently used first. Expired entries are omitted. It
+// returns nil when the runner has no fetch cache. Like the other fetch cache
+// methods, it may be called while the runner is busy.
+//
+// Example:
+//
+//	for _, url := range runner.FetchCacheKeys() {
+//	    log.Println("cached:", url)
+//	}
+func (r *Runner) FetchCacheKeys() []string {
+	if r.fetchCache == nil {
+		return nil
+	}
+	return r.fetchCache.keys()
+}
+
+// InvalidateFetchCache removes the cached response for url, so the next fetch
+// of it goes to the network. url must match the fetched URL exactly, as
+// listed by FetchCacheKeys. Other entries are kept.
+//
+// Example:
+//
+//	// The upstream config changed; refetch it on the next render.
+//	runner.InvalidateFetchCache("https://config.example.com/flags.json")
+func (r *Runner) InvalidateFetchCache(url string) {
+	if r.fetchCache != nil {
+		r.fetchCache.remove(url)
+	}
+}
+
+// ClearFetchCache removes every cached response.
+func (r *Runner) ClearFetchCache() {
+	if r.fetchCache != nil {
+		r.fetchCache.clear()
+	}
+}
+
+// FetchCacheKeys is the EventLoopRunner counterpart of Runner.FetchCacheKeys.
+func (r *EventLoopRunner) FetchCacheKeys() []string {
+	if r.fetchCache == nil {
+		return nil
+	}
+	return r.fetchCache.keys()
+}
+
+// InvalidateFetchCache is the EventLoopRunner counterpart of
+// Runner.InvalidateFetchCache.
+func (r *EventLoopRunner) InvalidateFetchCache(url string) {
+	if r.fetchCache != nil {
+		r.fetchCache.remove(url)
+	}
+}
+
+// ClearFetchCache is the EventLoopRunner counterpart of Runner.ClearFetchCache.
+func (r *EventLoopRunner) ClearFetchCache() {
+	if r.fetchCache != nil {
+		r.fetchCache.clear()
+	}
+}
+
+// cacheable reports whether a response may be stored in the fetch cache.
+func cacheable(header http.Header) bool {
+	for _, directive := range strings.Split(header.Get("Cache-Control"), ",") {
+		if strings.EqualFold(strings.TrimSpace(directive), "no-store") {
+			return false
+		}
+	}
+	return true
+}
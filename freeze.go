@@ -0,0 +1,176 @@
+package jsrunner
+
+import (
+	"fmt"
+
+	"github.com/dop251/goja"
+)
+
+// WithFrozenGlobals makes every host-provided global tamper-proof once the runner
+// has been constructed. Each global installed through options (WithGlobals,
+// WithWebAccess, ...) is passed to Object.freeze and redefined as a non-writable,
+// non-configurable property, so untrusted scripts cannot replace helpers such as
+// fetchText with their own implementation.
+//
+// Reassigning a frozen global silently fails in sloppy mode and throws a TypeError
+// in strict mode. Globals set later via SetGlobal are not frozen, which keeps
+// per-call values (for example ReactApp's SERVER_PROPS) writable. Host objects that
+// cannot be frozen (such as wrapped Go maps) keep their binding locked but remain
+// mutable through their own properties. EventLoopRunner does not support this
+// option: NewEventLoopRunner ignores it and logs a warning.
+//
+// Example:
+//
+//	runner := jsrunner.New(
+//	    jsrunner.WithWebAccess(nil),
+//	    jsrunner.WithFrozenGlobals(),
+//	)
+//	runner.LoadScriptString(`fetchText = function() { return "evil"; }`) // no effect
+func WithFrozenGlobals() Option {
+	return func(r *Runner) {
+		r.frozenGlobals = true
+	}
+}
+
+// freezeGlobals freezes the named globals, or every tracked global when no names
+// are given.
+func (r *Runner) freezeGlobals(names ...string) {
+	if len(names) == 0 {
+		for name := range r.globals {
+			names = append(names, name)
+		}
+	}
+
+	global := r.vm.GlobalObject()
+	freeze, _ := goja.AssertFunction(r.vm.Get("Object").ToObject(r.vm).Get("freeze"))
+
+	for _, name := range names {
+		value := global.Get(name)
+		if value == nil {
+			continue
+		}
+		if obj, ok := value.(*goja.Object); ok && freeze != nil {
+			// Host objects may refuse to be frozen; the binding is still locked below.
+			_, _ = freeze(goja.Undefined(), obj)
+		}
+		_ = global.DefineDataProperty(name, value, goja.FLAG_FALSE, goja.FLAG_FALSE, goja.FLAG_TRUE)
+	}
+}
+
+// WithFrozenIntrinsics freezes the built-in constructors and their prototypes
+// (Object.prototype, Array.prototype, Function.prototype, the Error types,
+// Promise, Map, typed arrays, iterator prototypes, and the Math, JSON, and
+// Reflect namespaces) once the runner has been constructed, so untrusted
+// scripts cannot pollute them. Without it, a script that assigns
+// Object.prototype.isAdmin = true changes every object created afterwards,
+// including the ones passed to host functions.
+//
+// Adding or replacing a property on a frozen intrinsic silently fails in
+// sloppy mode and throws a TypeError in strict mode. Replacing one of the
+// commonly shadowed members (constructor, name, message, toString,
+// toLocaleString, valueOf) on the intrinsic itself throws a TypeError in both
+// modes, because those members are turned into accessors. Scripts that
+// legitimately extend built-in prototypes, such as core-js shims or helpers
+// like Array.prototype.last, stop working: expose such helpers as plain
+// functions (for example via WithGlobals) or leave the option off.
+// Shadowing inherited members on your own objects keeps working: assignments
+// like this.name = "ValidationError" in an Error subclass or
+// Foo.prototype.toString = ... define an own property as usual, even though
+// the inherited property belongs to a frozen prototype. The option also
+// applies to runtimes created by EvalIsolated. It applies to Runner only;
+// NewEventLoopRunner ignores it and logs a warning.
+//
+// Example:
+//
+//	runner := jsrunner.New(jsrunner.WithFrozenIntrinsics())
+//	runner.Eval(`Object.prototype.evil = true; ({}).evil`) // undefined
+func WithFrozenIntrinsics() Option {
+	return func(r *Runner) {
+		r.frozenIntrinsics = true
+	}
+}
+
+// freezeIntrinsicsSource freezes the intrinsics reachable from the global
+// object. Before freezing a prototype, the members scripts commonly shadow on
+// their own objects are turned into accessors whose setter defines an own
+// property on the receiver, working around the "override mistake" that would
+// otherwise make such assignments fail.
+const freezeIntrinsicsSource = `(function(global) {
+	var constructors = [
+		"Object", "Function", "Array", "String", "Number", "Boolean", "Symbol",
+		"Error", "EvalError", "RangeError", "ReferenceError", "SyntaxError",
+		"TypeError", "URIError", "RegExp", "Date", "Promise", "Map", "Set",
+		"WeakMap", "WeakSet", "ArrayBuffer", "DataView", "Int8Array",
+		"Uint8Array", "Uint8ClampedArray", "Int16Array", "Uint16Array",
+		"Int32Array", "Uint32Array", "Float32Array", "Float64Array", "Proxy"
+	];
+	var namespaces = ["Math", "JSON", "Reflect"];
+	var overridable = ["constructor", "name", "message", "toString", "toLocaleString", "valueOf"];
+	var getProto = Object.getPrototypeOf;
+	var freeze = Object.freeze;
+
+	function enableOverrides(proto) {
+		overridable.forEach(function(key) {
+			var desc = Object.getOwnPropertyDescriptor(proto, key);
+			if (!desc || !("value" in desc) || !desc.writable || !desc.configurable) {
+				return;
+			}
+			var value = desc.value;
+			Object.defineProperty(proto, key, {
+				get: function() { return value; },
+				set: function(v) {
+					if (this === proto) {
+						throw new TypeError("Cannot assign to read only property '" + key + "' of a frozen intrinsic");
+					}
+					Object.defineProperty(this, key, { value: v, writable: true, enumerable: true, configurable: true });
+				},
+				enumerable: desc.enumerable,
+				configurable: false
+			});
+		});
+	}
+
+	function freezeProto(proto) {
+		if (proto && !Object.isFrozen(proto)) {
+			enableOverrides(proto);
+			freeze(proto);
+		}
+	}
+
+	var hidden = [
+		getProto(Int8Array),
+		getProto(function*() {}),
+		getProto(async function() {}),
+		getProto([][Symbol.iterator]()),
+		getProto(getProto([][Symbol.iterator]())),
+		getProto(new Map()[Symbol.iterator]()),
+		getProto(new Set()[Symbol.iterator]()),
+		getProto(""[Symbol.iterator]())
+	];
+	constructors.forEach(function(name) {
+		var ctor = global[name];
+		if (typeof ctor === "function") {
+			freezeProto(ctor.prototype);
+		}
+	});
+	hidden.forEach(function(obj) {
+		if (obj.prototype && typeof obj.prototype === "object") {
+			freezeProto(obj.prototype);
+		}
+		freezeProto(obj);
+	});
+	constructors.concat(namespaces).forEach(function(name) {
+		if (global[name] !== undefined) {
+			freeze(global[name]);
+		}
+	});
+})(globalThis);`
+
+var freezeIntrinsicsProgram = goja.MustCompile("freeze-intrinsics.js", freezeIntrinsicsSource, true)
+
+// freezeIntrinsics freezes the built-in prototypes and constructors of vm.
+func freezeIntrinsics(vm *goja.Runtime) {
+	if _, err := vm.RunProgram(freezeIntrinsicsProgram); err != nil {
+		panic(fmt.Sprintf("jsrunner: failed to freeze intrinsics: %v", err))
+	}
+}
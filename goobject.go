@@ -0,0 +1,133 @@
+package jsrunner
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/dop251/goja"
+)
+
+// SetGlobalObject binds a Go value to a global JavaScript object whose
+// properties are the value's exported methods and, for structs, its exported
+// fields. Methods are callable as ordinary JavaScript functions; a method whose
+// last result is a non-nil error throws it as an exception (with the error's
+// text as its message) instead of returning it. Fields are live: reads see the
+// current Go value and assignments write through to it.
+//
+// Receiver requirements: pass a pointer (SetGlobalObject("svc", &svc)) so that
+// methods with pointer receivers are included and state changes made by
+// methods or field assignments are visible to Go. A non-pointer value is
+// copied first; all of its methods, including pointer-receiver ones, are still
+// callable, but they operate on the copy. Property names are the Go names, so
+// a method Greet is called as svc.Greet(). Fields that share a name with a
+// method are skipped.
+//
+// Arguments are converted to the method's parameter types the same way goja
+// converts arguments for functions passed to SetGlobal.
+//
+// Example:
+//
+//	type Greeter struct{ Prefix string }
+//
+//	func (g *Greeter) Greet(name string) (string, error) {
+//	    if name == "" {
+//	        return "", errors.New("name is required")
+//	    }
+//	    return g.Prefix + name, nil
+//	}
+//
+//	runner.SetGlobalObject("greeter", &Greeter{Prefix: "Hello, "})
+//	runner.Eval(`greeter.Greet("Ada")`) // "Hello, Ada"
+//	runner.Eval(`try { greeter.Greet("") } catch (e) { e.message }`) // "name is required"
+func (r *Runner) SetGlobalObject(name string, v interface{}) {
+	r.syncLock()
+	defer r.syncUnlock()
+
+	ptr, err := goObjectPointer(v)
+	if err != nil {
+		r.logger.Warn("failed to bind global object", "name", name, "error", err)
+		return
+	}
+	obj, err := r.wrapGoObject(ptr)
+	if err != nil {
+		r.logger.Warn("failed to bind global object", "name", name, "error", err)
+		return
+	}
+	r.vm.Set(name, obj)
+	r.globals[name] = boundObject{ptr: ptr}
+}
+
+// boundObject marks entries in Runner.globals that were installed through
+// SetGlobalObject.
+type boundObject struct {
+	ptr reflect.Value
+}
+
+// goObjectPointer returns a pointer to v, copying v when it is not already a
+// pointer so that pointer-receiver methods are part of the method set.
+func goObjectPointer(v interface{}) (reflect.Value, error) {
+	rv := reflect.ValueOf(v)
+	if !rv.IsValid() {
+		return reflect.Value{}, errors.New("value is nil")
+	}
+	if rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return reflect.Value{}, errors.New("value is a nil pointer")
+		}
+		return rv, nil
+	}
+	ptr := reflect.New(rv.Type())
+	ptr.Elem().Set(rv)
+	return ptr, nil
+}
+
+// wrapGoObject builds the JavaScript object exposing ptr's methods and, when
+// it points to a struct, accessor properties for its exported fields.
+func (r *Runner) wrapGoObject(ptr reflect.Value) (*goja.Object, error) {
+	obj := r.vm.NewObject()
+	methods := make(map[string]struct{})
+
+	for i := 0; i < ptr.NumMethod(); i++ {
+		name := ptr.Type().Method(i).Name
+		methods[name] = struct{}{}
+		if err := obj.Set(name, r.vm.ToValue(r.returnBoundErrors(ptr.Method(i).Interface()))); err != nil {
+			return nil, fmt.Errorf("failed to bind method %s: %w", name, err)
+		}
+	}
+
+	elem := ptr.Elem()
+	if elem.Kind() != reflect.Struct {
+		return obj, nil
+	}
+	for i := 0; i < elem.NumField(); i++ {
+		field := elem.Type().Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		if _, clash := methods[field.Name]; clash {
+			continue
+		}
+		if err := r.defineFieldAccessor(obj, field.Name, elem.Field(i)); err != nil {
+			return nil, fmt.Errorf("failed to bind field %s: %w", field.Name, err)
+		}
+	}
+	return obj, nil
+}
+
+// defineFieldAccessor exposes a struct field as a getter/setter pair that
+// reads and writes the Go value directly.
+func (r *Runner) defineFieldAccessor(obj *goja.Object, name string, field reflect.Value) error {
+	getter := r.vm.ToValue(func(goja.FunctionCall) goja.Value {
+		return r.toValue(field.Interface())
+	})
+	setter := r.vm.ToValue(func(call goja.FunctionCall) goja.Value {
+		target := reflect.New(field.Type())
+		if err := r.vm.ExportTo(call.Argument(0), target.Interface()); err != nil {
+			panic(r.vm.NewTypeError("cannot assign %s: %v", name, err))
+		}
+		field.Set(target.Elem())
+		return goja.Undefined()
+	})
+	return obj.DefineAccessorProperty(name, getter, setter, goja.FLAG_FALSE, goja.FLAG_TRUE)
+}
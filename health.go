@@ -0,0 +1,81 @@
+package jsrunner
+
+import (
+	"fmt"
+	"time"
+)
+
+// pingTimeout bounds how long Ping waits for the runtime to answer.
+const pingTimeout = time.Second
+
+// Ping verifies that the runner can still execute JavaScript by evaluating
+// 1+1 and checking the result, which makes it a cheap liveness probe. It
+// returns an error when evaluation fails (for example because the runtime was
+// left with a pending interrupt), produces the wrong value, or does not
+// complete within one second — including when a synchronized runner is stuck
+// in a long-running call that holds its lock.
+//
+// Like every other method, Ping must not run concurrently with other calls
+// unless the runner was created with WithSynchronized. Concurrent or repeated
+// Pings share one probe while it is in flight, so probing a wedged runner
+// does not pile up goroutines waiting for it.
+//
+// Example:
+//
+//	http.HandleFunc("/healthz", func(w http.ResponseWriter, _ *http.Request) {
+//	    if err := runner.Ping(); err != nil {
+//	        http.Error(w, err.Error(), http.StatusServiceUnavailable)
+//	        return
+//	    }
+//	    w.WriteHeader(http.StatusNoContent)
+//	})
+func (r *Runner) Ping() error {
+	probe := r.startPing()
+	timer := time.NewTimer(pingTimeout)
+	defer timer.Stop()
+
+	select {
+	case <-probe.done:
+		if probe.err != nil {
+			return fmt.Errorf("ping failed: %w", probe.err)
+		}
+		if probe.value != 2 {
+			return fmt.Errorf("ping failed: 1+1 evaluated to %d", probe.value)
+		}
+		return nil
+	case <-timer.C:
+		return fmt.Errorf("ping failed: runner did not respond within %v", pingTimeout)
+	}
+}
+
+// pingProbe is one evaluation of 1+1 started by Ping. value and err are set
+// before done is closed.
+type pingProbe struct {
+	done  chan struct{}
+	value int64
+	err   error
+}
+
+// startPing returns the probe in flight, starting one if there is none. The
+// probe's goroutine stays blocked for as long as the runner is, but at most
+// one exists per runner.
+func (r *Runner) startPing() *pingProbe {
+	r.pingMu.Lock()
+	defer r.pingMu.Unlock()
+
+	if r.ping != nil {
+		return r.ping
+	}
+	probe := &pingProbe{done: make(chan struct{})}
+	r.ping = probe
+	go func() {
+		result, err := r.Eval("1+1")
+		probe.value, probe.err = ExportInt(result), err
+
+		r.pingMu.Lock()
+		r.ping = nil
+		r.pingMu.Unlock()
+		close(probe.done)
+	}()
+	return probe
+}
@@ -0,0 +1,214 @@
+package jsrunner
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/dop251/goja"
+)
+
+const (
+	inspectMaxDepth     = 2
+	inspectMaxItems     = 20
+	inspectMaxStringLen = 80
+)
+
+// Inspect renders a goja.Value as a readable, type-annotated string for
+// debugging, in the spirit of Node's util.inspect:
+//
+//	object { id: 1, name: 'Ada', tags: array(2) [ 'x', 'y' ] }
+//	array(3) [ 1, 2, 3 ]
+//	object User { name: 'Ada' }
+//	[Function: greet]
+//	'a very long string…' (+120 chars)
+//
+// Objects are prefixed with "object" (and their constructor name for class
+// instances) and list their enumerable keys; arrays show their length. Long
+// strings and collections are truncated, nesting deeper than two levels is
+// collapsed, and circular references print as [Circular]. The output is meant
+// for logs and test failure messages, not for parsing; use JSON.stringify or
+// Export for that.
+//
+// Example:
+//
+//	val, _ := runner.Eval(`({ id: 1, tags: ["x", "y"] })`)
+//	log.Println(jsrunner.Inspect(val)) // object { id: 1, tags: array(2) [ 'x', 'y' ] }
+func Inspect(val goja.Value) (out string) {
+	defer func() {
+		// Getters and proxies may throw while being inspected.
+		if recovered := recover(); recovered != nil {
+			out = fmt.Sprintf("[uninspectable: %v]", recovered)
+		}
+	}()
+
+	var b strings.Builder
+	inspectValue(&b, val, 0, map[*goja.Object]bool{})
+	return b.String()
+}
+
+func inspectValue(b *strings.Builder, val goja.Value, depth int, seen map[*goja.Object]bool) {
+	if val == nil || goja.IsUndefined(val) {
+		b.WriteString("undefined")
+		return
+	}
+	if goja.IsNull(val) {
+		b.WriteString("null")
+		return
+	}
+	if goja.IsString(val) {
+		inspectString(b, val.String())
+		return
+	}
+	if goja.IsBigInt(val) {
+		b.WriteString(val.String() + "n")
+		return
+	}
+
+	obj, ok := val.(*goja.Object)
+	if !ok {
+		b.WriteString(val.String())
+		return
+	}
+
+	if _, isFunc := goja.AssertFunction(obj); isFunc {
+		name := obj.Get("name")
+		if name == nil || name.String() == "" {
+			b.WriteString("[Function (anonymous)]")
+			return
+		}
+		b.WriteString("[Function: " + name.String() + "]")
+		return
+	}
+	if seen[obj] {
+		b.WriteString("[Circular]")
+		return
+	}
+
+	switch obj.ClassName() {
+	case "Date":
+		b.WriteString("Date " + obj.String())
+		return
+	case "Error":
+		b.WriteString("[" + obj.String() + "]")
+		return
+	case "Array":
+		inspectArray(b, obj, depth, seen)
+		return
+	}
+	inspectObject(b, obj, depth, seen)
+}
+
+func inspectString(b *strings.Builder, s string) {
+	runes := []rune(s)
+	if len(runes) <= inspectMaxStringLen {
+		b.WriteString(quoteInspectString(s))
+		return
+	}
+	b.WriteString(quoteInspectString(string(runes[:inspectMaxStringLen]) + "…"))
+	fmt.Fprintf(b, " (+%d chars)", len(runes)-inspectMaxStringLen)
+}
+
+func quoteInspectString(s string) string {
+	quoted := strconv.Quote(s)
+	inner := strings.ReplaceAll(quoted[1:len(quoted)-1], `\"`, `"`)
+	return "'" + strings.ReplaceAll(inner, "'", `\'`) + "'"
+}
+
+func inspectArray(b *strings.Builder, obj *goja.Object, depth int, seen map[*goja.Object]bool) {
+	length := obj.Get("length").ToInteger()
+	fmt.Fprintf(b, "array(%d)", length)
+	if length == 0 {
+		b.WriteString(" []")
+		return
+	}
+	if depth >= inspectMaxDepth {
+		b.WriteString(" [...]")
+		return
+	}
+
+	seen[obj] = true
+	defer delete(seen, obj)
+
+	b.WriteString(" [ ")
+	shown := min(length, inspectMaxItems)
+	for i := int64(0); i < shown; i++ {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		inspectValue(b, obj.Get(strconv.FormatInt(i, 10)), depth+1, seen)
+	}
+	if length > shown {
+		fmt.Fprintf(b, ", ... %d more items", length-shown)
+	}
+	b.WriteString(" ]")
+}
+
+func inspectObject(b *strings.Builder, obj *goja.Object, depth int, seen map[*goja.Object]bool) {
+	b.WriteString("object")
+	if name := constructorName(nil, obj); name != "" && name != "Object" {
+		b.WriteString(" " + name)
+	}
+
+	keys := obj.Keys()
+	if len(keys) == 0 {
+		b.WriteString(" {}")
+		return
+	}
+	if depth >= inspectMaxDepth {
+		b.WriteString(" {...}")
+		return
+	}
+
+	seen[obj] = true
+	defer delete(seen, obj)
+
+	b.WriteString(" { ")
+	for i, key := range keys {
+		if i == inspectMaxItems {
+			fmt.Fprintf(b, ", ... %d more keys", len(keys)-i)
+			break
+		}
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(key + ": ")
+		inspectValue(b, obj.Get(key), depth+1, seen)
+	}
+	b.WriteString(" }")
+}
+
+// constructorName returns the name of obj's constructor, or "" when it has
+// none (for example objects created with Object.create(null)). Reading the
+// constructor runs script code when it is a getter or obj is a Proxy; if that
+// throws, the name falls back to obj's class name. The read runs in vm.Try
+// when vm is given, so the runtime stays usable after the exception; Inspect,
+// which has no runtime at hand, passes nil.
+func constructorName(vm *goja.Runtime, obj *goja.Object) (name string) {
+	read := func() {
+		ctor, ok := obj.Get("constructor").(*goja.Object)
+		if !ok {
+			return
+		}
+		if value := ctor.Get("name"); value != nil {
+			name = value.String()
+		}
+	}
+	if vm != nil {
+		if vm.Try(read) != nil {
+			return obj.ClassName()
+		}
+		return name
+	}
+
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			if _, ok := recovered.(*goja.Exception); !ok {
+				panic(recovered)
+			}
+			name = obj.ClassName()
+		}
+	}()
+	read()
+	return name
+}
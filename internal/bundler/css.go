@@ -0,0 +1,100 @@
+package bundler
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/evanw/esbuild/pkg/api"
+)
+
+// CSSMode selects how `import "./styles.css"` statements are compiled.
+type CSSMode string
+
+const (
+	// CSSExtract turns CSS imports into modules exporting the stylesheet text
+	// and collects the text into ReactBundles.CSS, so the server can inline it
+	// in the page or serve it as a stylesheet.
+	CSSExtract CSSMode = "extract"
+	// CSSInject additionally makes the client bundle insert each stylesheet
+	// into document.head as a <style> element when it loads. The SSR bundle
+	// skips the insertion because there is no document on the server.
+	CSSInject CSSMode = "inject"
+)
+
+// cssFile is one stylesheet imported while building a bundle.
+type cssFile struct {
+	path string
+	text string
+}
+
+// cssCollector records the stylesheets a build imports, in load order.
+type cssCollector struct {
+	mu    sync.Mutex
+	files []cssFile
+}
+
+func (c *cssCollector) add(path, text string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.files = append(c.files, cssFile{path: path, text: text})
+}
+
+// cssPlugin loads local .css files as JavaScript modules according to mode
+// and reports their text to collector.
+func cssPlugin(mode CSSMode, collector *cssCollector) (api.Plugin, error) {
+	switch mode {
+	case "", CSSExtract, CSSInject:
+	default:
+		return api.Plugin{}, fmt.Errorf("unknown css mode %q", mode)
+	}
+
+	return api.Plugin{
+		Name: "css",
+		Setup: func(build api.PluginBuild) {
+			build.OnLoad(api.OnLoadOptions{Filter: `\.css$`, Namespace: "file"}, func(args api.OnLoadArgs) (api.OnLoadResult, error) {
+				data, err := os.ReadFile(args.Path)
+				if err != nil {
+					return api.OnLoadResult{}, err
+				}
+				text := string(data)
+				collector.add(args.Path, text)
+
+				literal, err := json.Marshal(text)
+				if err != nil {
+					return api.OnLoadResult{}, err
+				}
+				contents := "export default " + string(literal) + ";\n"
+				if mode == CSSInject {
+					contents = "const css = " + string(literal) + ";\n" +
+						"if (typeof document !== \"undefined\") {\n" +
+						"  const style = document.createElement(\"style\");\n" +
+						"  style.textContent = css;\n" +
+						"  document.head.appendChild(style);\n" +
+						"}\n" +
+						"export default css;\n"
+				}
+				return api.OnLoadResult{Contents: &contents, Loader: api.LoaderJS}, nil
+			})
+		},
+	}, nil
+}
+
+// mergeCSS concatenates the stylesheets of both bundles in import order,
+// including each file once.
+func mergeCSS(outputs ...*bundleOutput) string {
+	seen := make(map[string]struct{})
+	var parts []string
+	for _, out := range outputs {
+		for _, file := range out.css {
+			if _, dup := seen[file.path]; dup {
+				continue
+			}
+			seen[file.path] = struct{}{}
+			parts = append(parts, file.text)
+		}
+	}
+	return strings.Join(parts, "\n")
+}
@@ -0,0 +1,157 @@
+package bundler
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+
+	"github.com/dop251/goja/ast"
+	"github.com/dop251/goja/parser"
+	"github.com/evanw/esbuild/pkg/api"
+)
+
+// mountFunctions are the React and Preact APIs that attach a client app to
+// the DOM.
+var mountFunctions = map[string]bool{
+	"hydrateRoot": true,
+	"hydrate":     true,
+	"createRoot":  true,
+}
+
+// appSource is a module written by the app rather than fetched from the CDN.
+type appSource struct {
+	contents string
+	loader   api.Loader
+}
+
+// sourceRecorder collects the app's own modules as esbuild loads them, so the
+// hydration check never looks at library code that merely defines the mount
+// functions.
+type sourceRecorder struct {
+	mu      sync.Mutex
+	sources []appSource
+}
+
+func (s *sourceRecorder) add(contents string, loader api.Loader) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sources = append(s.sources, appSource{contents: contents, loader: loader})
+}
+
+// Plugin records local script files and inline modules. Its callbacks return
+// no contents, so esbuild and the remote resolver still load every module.
+func (s *sourceRecorder) Plugin(inlineModules map[string]string) api.Plugin {
+	return api.Plugin{
+		Name: "source-recorder",
+		Setup: func(build api.PluginBuild) {
+			build.OnLoad(api.OnLoadOptions{Filter: `\.[cm]?[jt]sx?$`, Namespace: "file"}, func(args api.OnLoadArgs) (api.OnLoadResult, error) {
+				if data, err := os.ReadFile(args.Path); err == nil {
+					s.add(string(data), scriptLoader(args.Path))
+				}
+				return api.OnLoadResult{}, nil
+			})
+			build.OnLoad(api.OnLoadOptions{Filter: ".*", Namespace: inlineNamespace}, func(args api.OnLoadArgs) (api.OnLoadResult, error) {
+				s.add(inlineModules[args.Path], api.LoaderTSX)
+				return api.OnLoadResult{}, nil
+			})
+		},
+	}
+}
+
+// scriptLoader picks the esbuild loader for a local script file.
+func scriptLoader(path string) api.Loader {
+	switch filepath.Ext(path) {
+	case ".ts", ".mts", ".cts":
+		return api.LoaderTS
+	case ".tsx":
+		return api.LoaderTSX
+	case ".jsx":
+		return api.LoaderJSX
+	default:
+		return api.LoaderJS
+	}
+}
+
+// mountsApp reports whether any of the app's own modules calls an API that
+// mounts the app in the browser. Each module is compiled to CommonJS and
+// parsed, so names in comments, strings, and property definitions do not
+// count. A module that cannot be parsed counts as mounting, so the check
+// never warns about code it could not read.
+func mountsApp(sources []appSource, opts ReactOptions) bool {
+	jsx, err := opts.JSXMode.esbuildJSX()
+	if err != nil {
+		return true
+	}
+	for _, source := range sources {
+		result := api.Transform(source.contents, api.TransformOptions{
+			Loader:          source.loader,
+			Format:          api.FormatCommonJS,
+			Target:          api.ES2018,
+			JSX:             jsx,
+			JSXImportSource: opts.JSXImportSource,
+		})
+		if len(result.Errors) > 0 {
+			return true
+		}
+		program, err := parser.ParseFile(nil, "", string(result.Code), 0, parser.WithDisableSourceMaps)
+		if err != nil {
+			return true
+		}
+		if containsCall(reflect.ValueOf(program), isMountCall) {
+			return true
+		}
+	}
+	return false
+}
+
+// isMountCall matches hydrateRoot(...), ReactDOM.hydrateRoot(...), and the
+// (0, import_client.hydrateRoot)(...) form esbuild emits for named imports.
+func isMountCall(call *ast.CallExpression) bool {
+	callee := call.Callee
+	if seq, ok := callee.(*ast.SequenceExpression); ok && len(seq.Sequence) > 0 {
+		callee = seq.Sequence[len(seq.Sequence)-1]
+	}
+	switch callee := callee.(type) {
+	case *ast.Identifier:
+		return mountFunctions[callee.Name.String()]
+	case *ast.DotExpression:
+		return mountFunctions[callee.Identifier.Name.String()]
+	}
+	return false
+}
+
+// astPackage is the import path of goja's AST node types.
+var astPackage = reflect.TypeOf(ast.Program{}).PkgPath()
+
+// containsCall walks a goja AST and reports whether match accepts any call
+// expression in it. goja has no AST visitor, so the walk follows the exported
+// fields of the node structs by reflection.
+func containsCall(v reflect.Value, match func(*ast.CallExpression) bool) bool {
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return false
+		}
+		if call, ok := v.Interface().(*ast.CallExpression); ok && match(call) {
+			return true
+		}
+		return containsCall(v.Elem(), match)
+	case reflect.Struct:
+		if v.Type().PkgPath() != astPackage {
+			return false
+		}
+		for i := 0; i < v.NumField(); i++ {
+			if v.Type().Field(i).IsExported() && containsCall(v.Field(i), match) {
+				return true
+			}
+		}
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			if containsCall(v.Index(i), match) {
+				return true
+			}
+		}
+	}
+	return false
+}
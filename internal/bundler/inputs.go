@@ -0,0 +1,71 @@
+package bundler
+
+import (
+	"os"
+	"sync"
+	"time"
+
+	"github.com/evanw/esbuild/pkg/api"
+)
+
+// fileStamp identifies the version of a local file a build read.
+type fileStamp struct {
+	path    string
+	modTime time.Time
+	size    int64
+}
+
+// inputRecorder stamps every file esbuild loads from disk. Files are stamped
+// before esbuild reads them, so an edit racing with the build is reported as
+// a change rather than missed.
+type inputRecorder struct {
+	mu     sync.Mutex
+	stamps []fileStamp
+}
+
+// Plugin returns no contents from its callback, so the other plugins and
+// esbuild itself still load every file.
+func (r *inputRecorder) Plugin() api.Plugin {
+	return api.Plugin{
+		Name: "input-recorder",
+		Setup: func(build api.PluginBuild) {
+			build.OnLoad(api.OnLoadOptions{Filter: ".*", Namespace: "file"}, func(args api.OnLoadArgs) (api.OnLoadResult, error) {
+				stamp := fileStamp{path: args.Path}
+				if info, err := os.Stat(args.Path); err == nil {
+					stamp.modTime, stamp.size = info.ModTime(), info.Size()
+				}
+				r.mu.Lock()
+				r.stamps = append(r.stamps, stamp)
+				r.mu.Unlock()
+				return api.OnLoadResult{}, nil
+			})
+		},
+	}
+}
+
+// changed reports whether any stamped file was modified, resized, or removed.
+func (s fileStamp) changed() bool {
+	info, err := os.Stat(s.path)
+	if err != nil {
+		return true
+	}
+	return !info.ModTime().Equal(s.modTime) || info.Size() != s.size
+}
+
+// InputsChanged reports whether a file the bundles were built from has been
+// modified, resized, or removed since the build, judged by its modification
+// time and size. Entries and InlineModules are part of ReactOptions and are
+// not covered. Bundles from PrecompiledBundles always report false.
+func (b *ReactBundles) InputsChanged() bool {
+	for _, out := range []*bundleOutput{b.ssr, b.client} {
+		if out == nil {
+			continue
+		}
+		for _, stamp := range out.inputs {
+			if stamp.changed() {
+				return true
+			}
+		}
+	}
+	return false
+}
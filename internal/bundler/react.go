@@ -1,11 +1,16 @@
 package bundler
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
+	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -18,16 +23,183 @@ type ReactOptions struct {
 	ReactVersion string
 	SSREntry     string
 	ClientEntry  string
+
+	// SourceMap requests external source maps for both bundles.
+	SourceMap bool
+
+	// Metafile requests esbuild's metafile so ReactBundles.Meta reports
+	// bundle sizes and the remote modules that were pulled in.
+	Metafile bool
+
+	// JSXMode selects the JSX transform. Defaults to JSXAutomatic.
+	JSXMode JSXMode
+
+	// JSXImportSource names the package the automatic JSX runtime is
+	// imported from. Defaults to "react"; set "preact" to bundle against
+	// Preact, which also makes preact, preact/compat, preact/hooks, and
+	// preact-render-to-string resolvable from the CDN.
+	JSXImportSource string
+
+	// DevMode keeps bundles readable: whitespace is not minified, function
+	// and class names are preserved, and an inline source map is embedded so
+	// goja reports original positions in stack traces.
+	DevMode bool
+
+	// ResolveDir is the directory relative imports in the entries (such as
+	// "./styles.css" or "./Button") are resolved from. Defaults to ".".
+	ResolveDir string
+
+	// CSSMode selects how CSS imports are compiled. Defaults to CSSExtract.
+	CSSMode CSSMode
+
+	// InlineModules maps bare import specifiers to module sources (TS/JSX
+	// allowed). They take precedence over the CDN aliases and the file system,
+	// which makes builds hermetic: tests can stub any dependency without
+	// network access.
+	InlineModules map[string]string
+
+	// Define maps global identifiers or member expressions to JavaScript
+	// expressions substituted at build time, such as
+	// {"__API_URL__": `"https://api.example.com"`}. Values are code, so string
+	// constants must be quoted. Entries are merged over the built-in
+	// process.env.NODE_ENV definition, which only changes when Define names it
+	// explicitly.
+	Define map[string]string
+
+	// CheckHydration checks the client build and adds a warning to
+	// ReactBundles.Warnings when none of the app's own modules (the entry,
+	// local files, and InlineModules) calls hydrateRoot, hydrate, or
+	// createRoot, which usually means the client entry forgot to mount the
+	// app and the page will silently stay static. Modules fetched from the
+	// CDN are not inspected, since they define these functions themselves.
+	CheckHydration bool
+}
+
+// CacheKey returns a digest of every option that affects the build output,
+// so builds with equal keys produce identical bundles as long as the files
+// they read are unchanged. Files read from ResolveDir are identified by the
+// directory only, not by their contents; check ReactBundles.InputsChanged
+// before reusing bundles cached under the key.
+func (o ReactOptions) CacheKey() string {
+	// encoding/json sorts map keys, so equal options encode identically.
+	data, err := json.Marshal(o)
+	if err != nil {
+		panic(fmt.Sprintf("bundler: failed to encode react options: %v", err))
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// JSXMode selects how esbuild transforms JSX syntax.
+type JSXMode string
+
+const (
+	// JSXAutomatic imports jsx helpers from the "react/jsx-runtime" module.
+	JSXAutomatic JSXMode = "automatic"
+	// JSXClassic compiles JSX to React.createElement calls, or to the factory
+	// named by a `/** @jsx h */` pragma comment.
+	JSXClassic JSXMode = "classic"
+	// JSXPreserve leaves JSX syntax untouched in the output.
+	JSXPreserve JSXMode = "preserve"
+)
+
+func (m JSXMode) esbuildJSX() (api.JSX, error) {
+	switch m {
+	case "", JSXAutomatic:
+		return api.JSXAutomatic, nil
+	case JSXClassic:
+		return api.JSXTransform, nil
+	case JSXPreserve:
+		return api.JSXPreserve, nil
+	default:
+		return 0, fmt.Errorf("unknown jsx mode %q", m)
+	}
+}
+
+// BundleMeta summarizes the outputs of a React build.
+type BundleMeta struct {
+	// SSRBytes and ClientBytes are the sizes of the emitted JavaScript.
+	SSRBytes    int
+	ClientBytes int
+
+	// Dependencies lists the resolved remote import URLs, sorted and
+	// de-duplicated across both bundles.
+	Dependencies []string
 }
 
 // ReactBundles contains the compiled server and client bundles.
 type ReactBundles struct {
 	SSR    string
 	Client string
+
+	// SSRSourceMap and ClientSourceMap hold the source map JSON for the
+	// respective bundles when ReactOptions.SourceMap is set.
+	SSRSourceMap    []byte
+	ClientSourceMap []byte
+
+	// Meta is populated when ReactOptions.Metafile is set.
+	Meta *BundleMeta
+
+	// CSS is the text of every stylesheet imported by either bundle, in
+	// import order, with each file included once.
+	CSS string
+
+	// ClientHash is a short hex digest of Client, suitable for cache-busting
+	// file names. It changes whenever the client bundle's contents change.
+	ClientHash string
+
+	// Warnings lists problems found by post-build checks such as
+	// ReactOptions.CheckHydration. They do not fail the build.
+	Warnings []string
+
+	opts     ReactOptions
+	resolver *remoteResolver
+	ssr      *bundleOutput
+	client   *bundleOutput
+}
+
+// Bundle file names used as the generated source names for the bundles.
+const (
+	SSRBundleName    = "app-ssr.js"
+	ClientBundleName = "app-client.js"
+)
+
+type bundleOutput struct {
+	code      string
+	sourceMap []byte
+	metafile  string
+	css       []cssFile
+
+	// appSources holds the app's own modules when CheckHydration is set.
+	appSources []appSource
+
+	// inputs stamps the local files the build read.
+	inputs []fileStamp
 }
 
+// esbuildMetafile mirrors the parts of esbuild's metafile JSON we consume.
+type esbuildMetafile struct {
+	Inputs  map[string]json.RawMessage `json:"inputs"`
+	Outputs map[string]struct {
+		Bytes int `json:"bytes"`
+	} `json:"outputs"`
+}
+
+// cdnBaseURL is the origin remote React packages are fetched from.
+var cdnBaseURL = "https://esm.sh"
+
+const httpNamespace = "http-url"
+
+// inlineNamespace holds modules supplied through ReactOptions.InlineModules.
+const inlineNamespace = "inline"
+
 const defaultReactVersion = "18.3.1"
 
+const (
+	defaultPreactVersion               = "10.24.3"
+	defaultPreactRenderToStringVersion = "6.5.11"
+)
+
 // BuildReactBundles produces bundled JavaScript suitable for SSR and
 // client-side hydration. The entry points should export `renderApp` on the
 // server side and call `hydrateRoot` on the client side.
@@ -39,88 +211,299 @@ func BuildReactBundles(opts ReactOptions) (*ReactBundles, error) {
 		return nil, errors.New("client entry is required")
 	}
 
+	if _, err := opts.JSXMode.esbuildJSX(); err != nil {
+		return nil, err
+	}
+
 	reactVersion := opts.ReactVersion
 	if reactVersion == "" {
 		reactVersion = defaultReactVersion
 	}
 
-	resolver := newRemoteResolver(reactVersion)
+	resolver := newRemoteResolver(reactVersion, opts.JSXImportSource, opts.InlineModules)
+
+	ssr, err := buildBundle(opts.SSREntry, "app-ssr.tsx", SSRBundleName, api.PlatformNode, opts, resolver)
+	if err != nil {
+		return nil, fmt.Errorf("bundle ssr: %w", err)
+	}
+
+	client, err := buildBundle(opts.ClientEntry, "app-client.tsx", ClientBundleName, api.PlatformBrowser, opts, resolver)
+	if err != nil {
+		return nil, fmt.Errorf("bundle client: %w", err)
+	}
+
+	return assembleBundles(opts, resolver, ssr, client)
+}
+
+// PrecompiledBundles wraps a client bundle built ahead of time. The result
+// carries no SSR source and cannot be rebuilt with WithSSREntry or
+// WithClientEntry.
+func PrecompiledBundles(client string) *ReactBundles {
+	return &ReactBundles{Client: client, ClientHash: contentHash(client)}
+}
+
+// errPrecompiled is returned when rebuilding bundles that were not built from
+// source.
+var errPrecompiled = errors.New("precompiled bundles cannot be rebuilt")
 
-	ssr, err := buildBundle(opts.SSREntry, "app-ssr.tsx", api.PlatformNode, resolver)
+// WithSSREntry returns a copy of b whose SSR bundle is rebuilt from entry,
+// reusing the client bundle and the remote modules already downloaded.
+func (b *ReactBundles) WithSSREntry(entry string) (*ReactBundles, error) {
+	if strings.TrimSpace(entry) == "" {
+		return nil, errors.New("ssr entry is required")
+	}
+	if b.resolver == nil {
+		return nil, errPrecompiled
+	}
+
+	opts := b.opts
+	opts.SSREntry = entry
+	ssr, err := buildBundle(entry, "app-ssr.tsx", SSRBundleName, api.PlatformNode, opts, b.resolver)
 	if err != nil {
 		return nil, fmt.Errorf("bundle ssr: %w", err)
 	}
 
-	client, err := buildBundle(opts.ClientEntry, "app-client.tsx", api.PlatformBrowser, resolver)
+	return assembleBundles(opts, b.resolver, ssr, b.client)
+}
+
+// WithClientEntry returns a copy of b whose client bundle is rebuilt from
+// entry, reusing the SSR bundle and the remote modules already downloaded.
+func (b *ReactBundles) WithClientEntry(entry string) (*ReactBundles, error) {
+	if strings.TrimSpace(entry) == "" {
+		return nil, errors.New("client entry is required")
+	}
+	if b.resolver == nil {
+		return nil, errPrecompiled
+	}
+
+	opts := b.opts
+	opts.ClientEntry = entry
+	client, err := buildBundle(entry, "app-client.tsx", ClientBundleName, api.PlatformBrowser, opts, b.resolver)
 	if err != nil {
 		return nil, fmt.Errorf("bundle client: %w", err)
 	}
 
-	return &ReactBundles{SSR: ssr, Client: client}, nil
+	return assembleBundles(opts, b.resolver, b.ssr, client)
+}
+
+func assembleBundles(opts ReactOptions, resolver *remoteResolver, ssr, client *bundleOutput) (*ReactBundles, error) {
+	bundles := &ReactBundles{
+		SSR:             ssr.code,
+		Client:          client.code,
+		ClientHash:      contentHash(client.code),
+		SSRSourceMap:    ssr.sourceMap,
+		ClientSourceMap: client.sourceMap,
+		CSS:             mergeCSS(ssr, client),
+		opts:            opts,
+		resolver:        resolver,
+		ssr:             ssr,
+		client:          client,
+	}
+
+	if opts.CheckHydration && !mountsApp(client.appSources, opts) {
+		bundles.Warnings = append(bundles.Warnings, "client bundle never calls hydrateRoot, hydrate, or createRoot; the page will not become interactive")
+	}
+
+	if opts.Metafile {
+		meta, err := buildMeta(ssr, client)
+		if err != nil {
+			return nil, err
+		}
+		bundles.Meta = meta
+	}
+
+	return bundles, nil
+}
+
+// contentHash returns the first 12 hex digits of the SHA-256 of code.
+func contentHash(code string) string {
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:6])
 }
 
-func buildBundle(entry, sourceFile string, platform api.Platform, resolver *remoteResolver) (string, error) {
-	result := api.Build(api.BuildOptions{
+func buildMeta(ssr, client *bundleOutput) (*BundleMeta, error) {
+	meta := &BundleMeta{}
+	seen := make(map[string]struct{})
+
+	for _, out := range []*bundleOutput{ssr, client} {
+		var mf esbuildMetafile
+		if err := json.Unmarshal([]byte(out.metafile), &mf); err != nil {
+			return nil, fmt.Errorf("parse metafile: %w", err)
+		}
+
+		size := 0
+		for path, output := range mf.Outputs {
+			if strings.HasSuffix(path, ".map") {
+				continue
+			}
+			size += output.Bytes
+		}
+		if out == ssr {
+			meta.SSRBytes = size
+		} else {
+			meta.ClientBytes = size
+		}
+
+		for input := range mf.Inputs {
+			dep, ok := strings.CutPrefix(input, httpNamespace+":")
+			if !ok {
+				continue
+			}
+			if _, dup := seen[dep]; dup {
+				continue
+			}
+			seen[dep] = struct{}{}
+			meta.Dependencies = append(meta.Dependencies, dep)
+		}
+	}
+
+	sort.Strings(meta.Dependencies)
+	return meta, nil
+}
+
+func buildBundle(entry, sourceFile, outFile string, platform api.Platform, opts ReactOptions, resolver *remoteResolver) (*bundleOutput, error) {
+	jsx, err := opts.JSXMode.esbuildJSX()
+	if err != nil {
+		return nil, err
+	}
+	css := &cssCollector{}
+	cssLoader, err := cssPlugin(opts.CSSMode, css)
+	if err != nil {
+		return nil, err
+	}
+	resolveDir := opts.ResolveDir
+	if resolveDir == "" {
+		resolveDir = "."
+	}
+	inputs := &inputRecorder{}
+
+	buildOpts := api.BuildOptions{
 		Bundle:           true,
 		Format:           api.FormatIIFE,
 		Platform:         platform,
 		Target:           api.ES2018,
 		MinifyWhitespace: true,
 		Write:            false,
-		JSX:              api.JSXAutomatic,
+		JSX:              jsx,
+		JSXImportSource:  opts.JSXImportSource,
 		Define: map[string]string{
 			"process.env.NODE_ENV": "\"development\"",
 		},
-		Plugins: []api.Plugin{resolver.Plugin()},
+		Plugins: []api.Plugin{inputs.Plugin(), resolver.Plugin(), cssLoader},
 		Stdin: &api.StdinOptions{
 			Contents:   entry,
 			Loader:     api.LoaderTSX,
-			ResolveDir: ".",
+			ResolveDir: resolveDir,
 			Sourcefile: sourceFile,
 		},
-	})
+	}
+	if opts.DevMode {
+		buildOpts.MinifyWhitespace = false
+		buildOpts.KeepNames = true
+		buildOpts.Sourcemap = api.SourceMapInline
+		buildOpts.Define["process.env.NODE_ENV"] = "\"development\""
+	}
+	for name, value := range opts.Define {
+		buildOpts.Define[name] = value
+	}
+	if opts.SourceMap {
+		// External maps keep the sourceMappingURL comment out of the bundle, so
+		// goja does not try to load the map from disk.
+		buildOpts.Sourcemap = api.SourceMapExternal
+		if opts.DevMode {
+			buildOpts.Sourcemap = api.SourceMapInlineAndExternal
+		}
+		buildOpts.Outfile = outFile
+	}
+	if opts.Metafile {
+		buildOpts.Metafile = true
+		buildOpts.Outfile = outFile
+	}
+	var recorder *sourceRecorder
+	if opts.CheckHydration && platform == api.PlatformBrowser {
+		// The recorder runs first so it sees inline modules before the
+		// resolver supplies their contents.
+		recorder = &sourceRecorder{}
+		recorder.add(entry, api.LoaderTSX)
+		buildOpts.Plugins = append([]api.Plugin{recorder.Plugin(resolver.inlineModules)}, buildOpts.Plugins...)
+	}
+
+	result := api.Build(buildOpts)
 
 	if len(result.Errors) > 0 {
-		return "", fmt.Errorf("esbuild error: %s", result.Errors[0].Text)
+		return nil, fmt.Errorf("esbuild error: %s", result.Errors[0].Text)
 	}
 	if len(result.OutputFiles) == 0 {
-		return "", fmt.Errorf("esbuild produced no output")
+		return nil, fmt.Errorf("esbuild produced no output")
 	}
-	return string(result.OutputFiles[0].Contents), nil
+
+	out := &bundleOutput{metafile: result.Metafile, css: css.files, inputs: inputs.stamps}
+	if recorder != nil {
+		out.appSources = recorder.sources
+	}
+	for _, file := range result.OutputFiles {
+		if strings.HasSuffix(file.Path, ".map") {
+			out.sourceMap = file.Contents
+			continue
+		}
+		out.code = string(file.Contents)
+	}
+	return out, nil
 }
 
 type remoteResolver struct {
-	client       *http.Client
-	cache        sync.Map
-	reactVersion string
+	client          *http.Client
+	cache           sync.Map
+	reactVersion    string
+	jsxImportSource string
+	inlineModules   map[string]string
 }
 
-func newRemoteResolver(reactVersion string) *remoteResolver {
+func newRemoteResolver(reactVersion, jsxImportSource string, inlineModules map[string]string) *remoteResolver {
+	inline := make(map[string]string, len(inlineModules))
+	for specifier, source := range inlineModules {
+		inline[specifier] = source
+	}
 	return &remoteResolver{
-		client:       &http.Client{Timeout: 15 * time.Second},
-		reactVersion: reactVersion,
+		client:          &http.Client{Timeout: 15 * time.Second},
+		reactVersion:    reactVersion,
+		jsxImportSource: jsxImportSource,
+		inlineModules:   inline,
 	}
 }
 
 func (r *remoteResolver) Plugin() api.Plugin {
 	aliases := map[string]string{
-		"react":                 fmt.Sprintf("https://esm.sh/react@%s?dev", r.reactVersion),
-		"react/jsx-runtime":     fmt.Sprintf("https://esm.sh/react@%s/jsx-runtime?dev", r.reactVersion),
-		"react/jsx-dev-runtime": fmt.Sprintf("https://esm.sh/react@%s/jsx-dev-runtime?dev", r.reactVersion),
-		"react-dom/server":      fmt.Sprintf("https://esm.sh/react-dom@%s/server?dev", r.reactVersion),
-		"react-dom/client":      fmt.Sprintf("https://esm.sh/react-dom@%s/client?dev", r.reactVersion),
+		"react":                 fmt.Sprintf("%s/react@%s?dev", cdnBaseURL, r.reactVersion),
+		"react/jsx-runtime":     fmt.Sprintf("%s/react@%s/jsx-runtime?dev", cdnBaseURL, r.reactVersion),
+		"react/jsx-dev-runtime": fmt.Sprintf("%s/react@%s/jsx-dev-runtime?dev", cdnBaseURL, r.reactVersion),
+		"react-dom/server":      fmt.Sprintf("%s/react-dom@%s/server?dev", cdnBaseURL, r.reactVersion),
+		"react-dom/client":      fmt.Sprintf("%s/react-dom@%s/client?dev", cdnBaseURL, r.reactVersion),
+	}
+	if r.jsxImportSource == "preact" {
+		preact := fmt.Sprintf("%s/preact@%s", cdnBaseURL, defaultPreactVersion)
+		aliases["preact"] = preact
+		aliases["preact/compat"] = preact + "/compat"
+		aliases["preact/hooks"] = preact + "/hooks"
+		aliases["preact/jsx-runtime"] = preact + "/jsx-runtime"
+		aliases["preact/jsx-dev-runtime"] = preact + "/jsx-runtime"
+		aliases["preact-render-to-string"] = fmt.Sprintf("%s/preact-render-to-string@%s?deps=preact@%s", cdnBaseURL, defaultPreactRenderToStringVersion, defaultPreactVersion)
 	}
 
 	return api.Plugin{
 		Name: "remote-react",
 		Setup: func(build api.PluginBuild) {
 			build.OnResolve(api.OnResolveOptions{Filter: "^https?://"}, func(args api.OnResolveArgs) (api.OnResolveResult, error) {
-				return api.OnResolveResult{Path: args.Path, Namespace: "http-url"}, nil
+				return api.OnResolveResult{Path: args.Path, Namespace: httpNamespace}, nil
 			})
 
 			build.OnResolve(api.OnResolveOptions{Filter: ".*"}, func(args api.OnResolveArgs) (api.OnResolveResult, error) {
+				if _, ok := r.inlineModules[args.Path]; ok {
+					return api.OnResolveResult{Path: args.Path, Namespace: inlineNamespace}, nil
+				}
+
 				if target, ok := aliases[args.Path]; ok {
-					return api.OnResolveResult{Path: target, Namespace: "http-url"}, nil
+					return api.OnResolveResult{Path: target, Namespace: httpNamespace}, nil
 				}
 
 				if args.Importer != "" && strings.HasPrefix(args.Importer, "http") {
@@ -131,7 +514,7 @@ func (r *remoteResolver) Plugin() api.Plugin {
 
 					if strings.HasPrefix(args.Path, "./") || strings.HasPrefix(args.Path, "../") {
 						resolved := base.ResolveReference(&url.URL{Path: args.Path})
-						return api.OnResolveResult{Path: resolved.String(), Namespace: "http-url"}, nil
+						return api.OnResolveResult{Path: resolved.String(), Namespace: httpNamespace}, nil
 					}
 
 					if strings.HasPrefix(args.Path, "/") {
@@ -140,14 +523,24 @@ func (r *remoteResolver) Plugin() api.Plugin {
 							Host:   base.Host,
 							Path:   args.Path,
 						}
-						return api.OnResolveResult{Path: resolved.String(), Namespace: "http-url"}, nil
+						return api.OnResolveResult{Path: resolved.String(), Namespace: httpNamespace}, nil
 					}
 				}
 
+				if isLocalPath(args.Path) {
+					// Let esbuild resolve local files from disk.
+					return api.OnResolveResult{}, nil
+				}
+
 				return api.OnResolveResult{}, fmt.Errorf("unable to resolve %q", args.Path)
 			})
 
-			build.OnLoad(api.OnLoadOptions{Filter: ".*", Namespace: "http-url"}, func(args api.OnLoadArgs) (api.OnLoadResult, error) {
+			build.OnLoad(api.OnLoadOptions{Filter: ".*", Namespace: inlineNamespace}, func(args api.OnLoadArgs) (api.OnLoadResult, error) {
+				source := r.inlineModules[args.Path]
+				return api.OnLoadResult{Contents: &source, Loader: api.LoaderTSX}, nil
+			})
+
+			build.OnLoad(api.OnLoadOptions{Filter: ".*", Namespace: httpNamespace}, func(args api.OnLoadArgs) (api.OnLoadResult, error) {
 				if cached, ok := r.cache.Load(args.Path); ok {
 					text := cached.(string)
 					return api.OnLoadResult{Contents: &text, Loader: api.LoaderJS}, nil
@@ -172,3 +565,9 @@ func (r *remoteResolver) Plugin() api.Plugin {
 		},
 	}
 }
+
+// isLocalPath reports whether an import path refers to a file on disk rather
+// than a package.
+func isLocalPath(path string) bool {
+	return strings.HasPrefix(path, "./") || strings.HasPrefix(path, "../") || filepath.IsAbs(path)
+}
@@ -0,0 +1,455 @@
+package bundler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dop251/goja"
+)
+
+// fakeReactModules serves minimal stand-ins for the CDN packages so bundling
+// tests run without network access.
+var fakeReactModules = map[string]string{
+	"/react@18.3.1":                 `export function createElement(type, props) { return { type: type, props: props }; } export default { createElement: createElement };`,
+	"/react-dom@18.3.1/server":      `export function renderToString(el) { return "<" + el.type + ">"; } export default { renderToString: renderToString };`,
+	"/react-dom@18.3.1/client":      `export function hydrateRoot() {}`,
+	"/react@18.3.1/jsx-runtime":     `export function jsx(type, props) { return { type: type, props: props }; } export const jsxs = jsx; export const Fragment = "fragment";`,
+	"/react@18.3.1/jsx-dev-runtime": `export function jsxDEV(type, props) { return { type: type, props: props }; } export const Fragment = "fragment";`,
+	"/preact@10.24.3":               `export function h(type, props) { return { type: type, props: props || {} }; } export const Fragment = "fragment";`,
+	"/preact@10.24.3/jsx-runtime":   `export function jsx(type, props) { return { type: type, props: props }; } export const jsxs = jsx; export const Fragment = "fragment";`,
+	"/preact-render-to-string@6.5.11": `export default function render(vnode) {
+		if (typeof vnode !== "object" || vnode === null) { return String(vnode); }
+		if (typeof vnode.type === "function") { return render(vnode.type(vnode.props)); }
+		var children = [].concat(vnode.props.children || []);
+		return "<" + vnode.type + ">" + children.map(render).join("") + "</" + vnode.type + ">";
+	}`,
+}
+
+func useFakeCDN(t *testing.T) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		src, ok := fakeReactModules[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/javascript")
+		w.Write([]byte(src))
+	}))
+	prev := cdnBaseURL
+	cdnBaseURL = srv.URL
+	t.Cleanup(func() {
+		cdnBaseURL = prev
+		srv.Close()
+	})
+	return srv
+}
+
+const (
+	testSSREntry = `import React from "react";
+import { renderToString } from "react-dom/server";
+
+export function renderApp(props: Record<string, unknown>) {
+	return renderToString(React.createElement("div", props));
+}
+
+(globalThis as any).renderApp = renderApp;
+`
+	testClientEntry = `import React from "react";
+import { hydrateRoot } from "react-dom/client";
+
+hydrateRoot(document.getElementById("root"), React.createElement("div", {}));
+`
+)
+
+func TestBuildReactBundlesMeta(t *testing.T) {
+	srv := useFakeCDN(t)
+
+	bundles, err := BuildReactBundles(ReactOptions{
+		SSREntry:    testSSREntry,
+		ClientEntry: testClientEntry,
+		Metafile:    true,
+	})
+	if err != nil {
+		t.Fatalf("BuildReactBundles() failed: %v", err)
+	}
+	if bundles.Meta == nil {
+		t.Fatal("expected bundle metadata")
+	}
+	if bundles.Meta.SSRBytes != len(bundles.SSR) {
+		t.Errorf("SSRBytes = %d, want %d", bundles.Meta.SSRBytes, len(bundles.SSR))
+	}
+	if bundles.Meta.ClientBytes != len(bundles.Client) {
+		t.Errorf("ClientBytes = %d, want %d", bundles.Meta.ClientBytes, len(bundles.Client))
+	}
+
+	want := []string{
+		srv.URL + "/react-dom@18.3.1/client?dev",
+		srv.URL + "/react-dom@18.3.1/server?dev",
+		srv.URL + "/react@18.3.1?dev",
+	}
+	if strings.Join(bundles.Meta.Dependencies, ",") != strings.Join(want, ",") {
+		t.Errorf("Dependencies = %v, want %v", bundles.Meta.Dependencies, want)
+	}
+}
+
+func TestBuildReactBundlesWithoutMeta(t *testing.T) {
+	useFakeCDN(t)
+
+	bundles, err := BuildReactBundles(ReactOptions{
+		SSREntry:    testSSREntry,
+		ClientEntry: testClientEntry,
+	})
+	if err != nil {
+		t.Fatalf("BuildReactBundles() failed: %v", err)
+	}
+	if bundles.Meta != nil {
+		t.Error("expected no metadata when Metafile is unset")
+	}
+}
+
+func TestBuildReactBundlesPreact(t *testing.T) {
+	srv := useFakeCDN(t)
+
+	ssrEntry := `import render from "preact-render-to-string";
+
+function App(props: { name: string }) {
+	return <p>Hello {props.name}</p>;
+}
+
+(globalThis as any).renderApp = (props: { name: string }) => render(<App {...props} />);
+`
+	clientEntry := `import { h } from "preact";
+
+(globalThis as any).boot = () => h("div", null);
+`
+
+	bundles, err := BuildReactBundles(ReactOptions{
+		SSREntry:        ssrEntry,
+		ClientEntry:     clientEntry,
+		JSXImportSource: "preact",
+		Metafile:        true,
+	})
+	if err != nil {
+		t.Fatalf("BuildReactBundles() failed: %v", err)
+	}
+	for _, dep := range bundles.Meta.Dependencies {
+		if strings.Contains(dep, "/react@") {
+			t.Errorf("expected no React dependency, got %s", dep)
+		}
+	}
+	if !strings.Contains(strings.Join(bundles.Meta.Dependencies, ","), srv.URL+"/preact@10.24.3/jsx-runtime") {
+		t.Errorf("expected preact jsx-runtime dependency, got %v", bundles.Meta.Dependencies)
+	}
+
+	vm := goja.New()
+	if _, err := vm.RunString(bundles.SSR); err != nil {
+		t.Fatalf("running SSR bundle failed: %v", err)
+	}
+	html, err := vm.RunString(`renderApp({ name: "preact" })`)
+	if err != nil {
+		t.Fatalf("renderApp failed: %v", err)
+	}
+	if got := html.String(); got != "<p>Hello preact</p>" {
+		t.Errorf("unexpected markup: %s", got)
+	}
+}
+
+func TestBuildReactBundlesDevMode(t *testing.T) {
+	useFakeCDN(t)
+
+	ssrEntry := `function formatGreeting(name: string) {
+	return "Hello " + name;
+}
+
+(globalThis as any).renderApp = (props: any) => formatGreeting(props.name);
+`
+
+	prod, err := BuildReactBundles(ReactOptions{SSREntry: ssrEntry, ClientEntry: testClientEntry})
+	if err != nil {
+		t.Fatalf("BuildReactBundles() failed: %v", err)
+	}
+	dev, err := BuildReactBundles(ReactOptions{SSREntry: ssrEntry, ClientEntry: testClientEntry, DevMode: true})
+	if err != nil {
+		t.Fatalf("BuildReactBundles(DevMode) failed: %v", err)
+	}
+
+	for _, want := range []string{"function formatGreeting(name)", "return \"Hello \" + name;\n", "sourceMappingURL=data:application/json;base64,"} {
+		if !strings.Contains(dev.SSR, want) {
+			t.Errorf("expected dev bundle to contain %q", want)
+		}
+	}
+	if strings.Contains(prod.SSR, "sourceMappingURL") {
+		t.Error("expected production bundle to omit the inline source map")
+	}
+	if strings.Count(prod.SSR, "\n") >= strings.Count(dev.SSR, "\n") {
+		t.Error("expected production bundle to be minified")
+	}
+}
+
+func TestReactBundlesWithClientEntry(t *testing.T) {
+	useFakeCDN(t)
+
+	bundles, err := BuildReactBundles(ReactOptions{
+		SSREntry:    testSSREntry,
+		ClientEntry: testClientEntry,
+		Metafile:    true,
+	})
+	if err != nil {
+		t.Fatalf("BuildReactBundles() failed: %v", err)
+	}
+
+	updated, err := bundles.WithClientEntry(`console.log("client only");`)
+	if err != nil {
+		t.Fatalf("WithClientEntry() failed: %v", err)
+	}
+	if updated.SSR != bundles.SSR {
+		t.Error("expected the SSR bundle to be reused")
+	}
+	if !strings.Contains(updated.Client, "client only") {
+		t.Errorf("client bundle does not contain the new entry: %s", updated.Client)
+	}
+	if updated.Meta.ClientBytes != len(updated.Client) {
+		t.Errorf("ClientBytes = %d, want %d", updated.Meta.ClientBytes, len(updated.Client))
+	}
+	if strings.Contains(bundles.Client, "client only") {
+		t.Error("expected the original bundles to be left unchanged")
+	}
+}
+
+func TestBuildReactBundlesCSS(t *testing.T) {
+	useFakeCDN(t)
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "styles.css"), []byte(".card { color: red; }\n"), 0o644); err != nil {
+		t.Fatalf("failed to write stylesheet: %v", err)
+	}
+	ssrEntry := `import css from "./styles.css";
+
+(globalThis as any).renderApp = () => "<style>" + css + "</style>";
+`
+	clientEntry := `import "./styles.css";
+console.log("client boot");
+`
+
+	for _, mode := range []CSSMode{CSSExtract, CSSInject} {
+		bundles, err := BuildReactBundles(ReactOptions{
+			SSREntry:    ssrEntry,
+			ClientEntry: clientEntry,
+			ResolveDir:  dir,
+			CSSMode:     mode,
+		})
+		if err != nil {
+			t.Fatalf("BuildReactBundles(%s) failed: %v", mode, err)
+		}
+		if bundles.CSS != ".card { color: red; }\n" {
+			t.Errorf("%s: CSS = %q, want the stylesheet once", mode, bundles.CSS)
+		}
+
+		injects := strings.Contains(bundles.Client, "appendChild")
+		if injects != (mode == CSSInject) {
+			t.Errorf("%s: client bundle style injection = %v", mode, injects)
+		}
+
+		vm := goja.New()
+		if _, err := vm.RunString(bundles.SSR); err != nil {
+			t.Fatalf("%s: failed to run SSR bundle: %v", mode, err)
+		}
+		html, err := vm.RunString(`renderApp()`)
+		if err != nil {
+			t.Fatalf("%s: renderApp failed: %v", mode, err)
+		}
+		if !strings.Contains(html.String(), ".card { color: red; }") {
+			t.Errorf("%s: rendered %q, want the imported CSS text", mode, html.String())
+		}
+	}
+}
+
+func TestBuildReactBundlesInlineModules(t *testing.T) {
+	bundles, err := BuildReactBundles(ReactOptions{
+		SSREntry: `import { greet } from "greet";
+
+(globalThis as any).renderApp = (props: any) => greet(props.name);
+`,
+		ClientEntry: `import { greet } from "greet";
+console.log(greet("client"));
+`,
+		InlineModules: map[string]string{
+			"greet": `export function greet(name: string): string { return "Hello, " + name + "!"; }`,
+		},
+	})
+	if err != nil {
+		t.Fatalf("BuildReactBundles() failed: %v", err)
+	}
+
+	vm := goja.New()
+	if _, err := vm.RunString(bundles.SSR); err != nil {
+		t.Fatalf("failed to run SSR bundle: %v", err)
+	}
+	html, err := vm.RunString(`renderApp({ name: "Ada" })`)
+	if err != nil {
+		t.Fatalf("renderApp failed: %v", err)
+	}
+	if html.String() != "Hello, Ada!" {
+		t.Errorf("renderApp = %q, want %q", html.String(), "Hello, Ada!")
+	}
+	if !strings.Contains(bundles.Client, "Hello, ") {
+		t.Errorf("client bundle does not contain the inline module: %s", bundles.Client)
+	}
+}
+
+func TestBuildReactBundlesDefine(t *testing.T) {
+	bundles, err := BuildReactBundles(ReactOptions{
+		SSREntry: `declare const __API_URL__: string;
+(globalThis as any).renderApp = () => process.env.NODE_ENV + " " + __API_URL__;
+`,
+		ClientEntry: `declare const __API_URL__: string;
+fetch(__API_URL__ + "/users");
+`,
+		Define: map[string]string{
+			"__API_URL__": `"https://api.example.com"`,
+		},
+	})
+	if err != nil {
+		t.Fatalf("BuildReactBundles() failed: %v", err)
+	}
+
+	if !strings.Contains(bundles.Client, `"https://api.example.com/users"`) {
+		t.Errorf("client bundle does not inline __API_URL__: %s", bundles.Client)
+	}
+	if strings.Contains(bundles.Client, "__API_URL__") {
+		t.Errorf("client bundle still references __API_URL__: %s", bundles.Client)
+	}
+
+	vm := goja.New()
+	if _, err := vm.RunString(bundles.SSR); err != nil {
+		t.Fatalf("failed to run SSR bundle: %v", err)
+	}
+	got, err := vm.RunString(`renderApp()`)
+	if err != nil {
+		t.Fatalf("renderApp failed: %v", err)
+	}
+	if want := "development https://api.example.com"; got.String() != want {
+		t.Errorf("renderApp = %q, want %q (NODE_ENV must keep its default)", got.String(), want)
+	}
+
+	overridden, err := BuildReactBundles(ReactOptions{
+		SSREntry:    `(globalThis as any).renderApp = () => process.env.NODE_ENV;`,
+		ClientEntry: `console.log("client boot");`,
+		Define:      map[string]string{"process.env.NODE_ENV": `"production"`},
+	})
+	if err != nil {
+		t.Fatalf("BuildReactBundles(NODE_ENV) failed: %v", err)
+	}
+	if !strings.Contains(overridden.SSR, `"production"`) {
+		t.Errorf("expected an explicit NODE_ENV to win: %s", overridden.SSR)
+	}
+}
+
+func TestBuildReactBundlesCheckHydration(t *testing.T) {
+	useFakeCDN(t)
+
+	missing, err := BuildReactBundles(ReactOptions{
+		SSREntry:       testSSREntry,
+		ClientEntry:    `console.log("client boot");`,
+		CheckHydration: true,
+	})
+	if err != nil {
+		t.Fatalf("BuildReactBundles() failed: %v", err)
+	}
+	if len(missing.Warnings) != 1 || !strings.Contains(missing.Warnings[0], "hydrateRoot") {
+		t.Errorf("Warnings = %q, want a missing hydrateRoot warning", missing.Warnings)
+	}
+
+	hydrated, err := BuildReactBundles(ReactOptions{
+		SSREntry:       testSSREntry,
+		ClientEntry:    testClientEntry,
+		CheckHydration: true,
+	})
+	if err != nil {
+		t.Fatalf("BuildReactBundles() failed: %v", err)
+	}
+	if len(hydrated.Warnings) != 0 {
+		t.Errorf("Warnings = %q, want none for a client entry calling hydrateRoot", hydrated.Warnings)
+	}
+
+	unchecked, err := BuildReactBundles(ReactOptions{
+		SSREntry:    testSSREntry,
+		ClientEntry: `console.log("client boot");`,
+	})
+	if err != nil {
+		t.Fatalf("BuildReactBundles() failed: %v", err)
+	}
+	if len(unchecked.Warnings) != 0 {
+		t.Errorf("Warnings = %q, want none without CheckHydration", unchecked.Warnings)
+	}
+}
+
+func TestBuildReactBundlesCheckHydrationIgnoresLibraryCode(t *testing.T) {
+	useFakeCDN(t)
+	// A trimmed react-dom/client as served by esm.sh: it defines and calls
+	// the mount functions itself.
+	prev := fakeReactModules["/react-dom@18.3.1/client"]
+	fakeReactModules["/react-dom@18.3.1/client"] = `/* esm.sh - react-dom@18.3.1/client */
+function createRoot(container, options) { return { render: function(children) {}, unmount: function() {} }; }
+function hydrateRoot(container, children, options) { var root = createRoot(container, options); root.render(children); return root; }
+var client_default = { createRoot: createRoot, hydrateRoot: hydrateRoot };
+export { createRoot, client_default as default, hydrateRoot };`
+	t.Cleanup(func() { fakeReactModules["/react-dom@18.3.1/client"] = prev })
+
+	exposed, err := BuildReactBundles(ReactOptions{
+		SSREntry: testSSREntry,
+		ClientEntry: `import * as ReactDOM from "react-dom/client";
+// hydrateRoot runs later, from the page script.
+(window as any).ReactDOM = ReactDOM;
+console.log("call hydrate(root) when ready", ReactDOM.createRoot !== undefined);
+`,
+		CheckHydration: true,
+	})
+	if err != nil {
+		t.Fatalf("BuildReactBundles() failed: %v", err)
+	}
+	if len(exposed.Warnings) != 1 {
+		t.Errorf("Warnings = %q, want a warning when only library code calls the mount functions", exposed.Warnings)
+	}
+
+	viaModule, err := BuildReactBundles(ReactOptions{
+		SSREntry:    testSSREntry,
+		ClientEntry: `import { mount } from "app/mount"; mount();`,
+		InlineModules: map[string]string{
+			"app/mount": `import * as ReactDOM from "react-dom/client";
+export function mount() { ReactDOM.hydrateRoot(document.getElementById("root"), null); }`,
+		},
+		CheckHydration: true,
+	})
+	if err != nil {
+		t.Fatalf("BuildReactBundles() failed: %v", err)
+	}
+	if len(viaModule.Warnings) != 0 {
+		t.Errorf("Warnings = %q, want none when an app module calls hydrateRoot", viaModule.Warnings)
+	}
+
+	dir := t.TempDir()
+	mount := `import { createRoot } from "react-dom/client";
+export const mount = (el: Element) => createRoot(el).render(null);
+`
+	if err := os.WriteFile(filepath.Join(dir, "mount.ts"), []byte(mount), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	viaFile, err := BuildReactBundles(ReactOptions{
+		SSREntry:       testSSREntry,
+		ClientEntry:    `import { mount } from "./mount"; mount(document.body);`,
+		ResolveDir:     dir,
+		CheckHydration: true,
+	})
+	if err != nil {
+		t.Fatalf("BuildReactBundles() failed: %v", err)
+	}
+	if len(viaFile.Warnings) != 0 {
+		t.Errorf("Warnings = %q, want none when a local file calls createRoot", viaFile.Warnings)
+	}
+}
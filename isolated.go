@@ -0,0 +1,107 @@
+package jsrunner
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/dop251/goja"
+)
+
+// EvalIsolated evaluates code in a throwaway runtime created for this call
+// alone, so nothing it defines or mutates is visible to the runner or to any
+// later call. Use it for untrusted snippets that must not observe or tamper
+// with shared state.
+//
+// The fresh runtime only holds the globals of the runner's feature options
+// (WithConsole, WithURLGlobals, WithBase64Globals, WithTextCodecs, and the
+// fetch helpers of WithWebAccess) plus the standard JavaScript built-ins.
+// Globals from SetGlobal, WithGlobals, and loaded scripts are not copied.
+// Strict mode, frozen intrinsics, async iteration lowering, and the program
+// cache apply as for Eval. The call does not take the runner's lock, so
+// isolated evaluations may run concurrently with each other and with the
+// runner's other methods.
+//
+// Isolation is paid for on every call: setting up the runtime adds roughly
+// 30µs and 10KB of allocations, so a small expression takes two to three
+// times as long as with Eval (compare BenchmarkEvalIsolated with
+// BenchmarkEvalProgramCache). Prefer Eval or EvalWith for trusted code on hot
+// paths.
+//
+// The returned value belongs to the discarded runtime; export it with Export
+// or the Export* helpers rather than passing it back into the runner.
+//
+// EvalIsolated runs without a time limit; use EvalIsolatedContext to bound
+// untrusted code that may loop forever.
+//
+// Example:
+//
+//	runner := jsrunner.New(jsrunner.WithBase64Globals())
+//	result, err := runner.EvalIsolated(`globalThis.leak = 1; btoa("hi")`)
+//	jsrunner.ExportString(result)      // "aGk="
+//	runner.EvalIsolated(`typeof leak`) // "undefined"
+func (r *Runner) EvalIsolated(code string) (value goja.Value, err error) {
+	defer r.reportError("EvalIsolated", code, &err)
+
+	return r.evalIsolated(context.Background(), code)
+}
+
+// EvalIsolatedContext is like EvalIsolated but interrupts the evaluation when
+// ctx is cancelled or its deadline passes, returning an error that wraps a
+// *goja.InterruptedError. Fetch helpers in the isolated runtime use ctx for
+// their requests.
+//
+// Example:
+//
+//	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+//	defer cancel()
+//	result, err := runner.EvalIsolatedContext(ctx, untrustedCode)
+func (r *Runner) EvalIsolatedContext(ctx context.Context, code string) (value goja.Value, err error) {
+	defer r.reportError("EvalIsolatedContext", code, &err)
+
+	return r.evalIsolated(ctx, code)
+}
+
+func (r *Runner) evalIsolated(ctx context.Context, code string) (goja.Value, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	program, err := r.compile("", code)
+	if err != nil {
+		return nil, fmt.Errorf("failed to evaluate isolated code: %w", err)
+	}
+	vm := r.newIsolatedRuntime(ctx)
+	if ctx.Done() != nil {
+		stop := context.AfterFunc(ctx, func() {
+			vm.Interrupt(ctx.Err())
+		})
+		defer stop()
+	}
+	result, err := vm.RunProgram(program)
+	if err != nil {
+		return nil, fmt.Errorf("failed to evaluate isolated code: %w", err)
+	}
+	return result, nil
+}
+
+// newIsolatedRuntime creates a runtime holding only the runner's feature
+// globals. Fetch helpers use ctx rather than the runner's active context,
+// which belongs to whichever call holds the runner's lock.
+func (r *Runner) newIsolatedRuntime(ctx context.Context) *goja.Runtime {
+	vm := goja.New()
+	if r.randomSeed != nil {
+		vm.SetRandSource(seededRandSource(*r.randomSeed))
+	}
+	if r.asyncIteration {
+		installAsyncIteratorSymbol(vm)
+	}
+	fetch := func(url string) ([]byte, error) {
+		return r.fetcher().fetch(ctx, url)
+	}
+	for name, value := range r.features().globals(fetch) {
+		_ = vm.Set(name, value)
+	}
+	if r.frozenIntrinsics {
+		freezeIntrinsics(vm)
+	}
+	return vm
+}
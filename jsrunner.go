@@ -27,12 +27,17 @@ package jsrunner
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"io"
+	"io/fs"
+	"math/big"
 	"net/http"
 	"os"
+	"regexp"
+	"sort"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/dop251/goja"
@@ -52,11 +57,46 @@ import (
 //	runner.LoadScript("script.js")
 //	result, err := runner.Call("processData", input)
 type Runner struct {
-	vm               *goja.Runtime
-	globals          map[string]interface{}
-	httpClient       *http.Client
-	webAccessEnabled bool
-	webAccessTimeout time.Duration
+	vm                *goja.Runtime
+	globals           map[string]interface{}
+	httpClient        *http.Client
+	httpClientFunc    func(url string) *http.Client
+	webAccessEnabled  bool
+	webAccessTimeout  time.Duration
+	fetchPolicy       fetchPolicy
+	fetchHelpers      fetchHelpers
+	fetchCache        *fetchCache
+	loopback          loopbackRoutes
+	console           io.Writer
+	valueConverter    ValueConverter
+	exportConverter   ExportConverter
+	classExporters    map[string]func(goja.Value) interface{}
+	integerNumbers    bool
+	strictMode        bool
+	programCache      *programCache
+	initialGlobals    map[string]interface{}
+	frozenGlobals     bool
+	frozenIntrinsics  bool
+	timeConversion    bool
+	logger            Logger
+	synchronized      bool
+	mu                sync.Mutex
+	ctx               context.Context
+	errorHandler      func(op, source string, err error)
+	asyncIteration    bool
+	urlGlobals        bool
+	base64Globals     bool
+	textCodecs        bool
+	boundErrorMode    BoundErrorMode
+	pendingBoundError error
+	secrets           secretSet
+	snapshot          *globalSnapshot
+	profiler          *profiler
+	randomSeed        *int64
+	loopWatchdog      time.Duration
+	rejectionTracker  goja.PromiseRejectionTracker
+	pingMu            sync.Mutex
+	ping              *pingProbe
 }
 
 const defaultWebAccessTimeout = 10 * time.Second
@@ -68,13 +108,62 @@ type Option func(*Runner)
 type WebAccessConfig struct {
 	Client  *http.Client
 	Timeout time.Duration
+
+	// AllowedHosts restricts fetches, including every redirect they follow,
+	// to the listed host names. Entries of the form "*.example.com" match any
+	// subdomain. Empty allows every host.
+	AllowedHosts []string
+
+	// BlockPrivateNetworks rejects requests whose host resolves to a loopback,
+	// private, carrier-grade NAT, link-local, or unspecified address. The
+	// check is repeated for every redirect and for the address each
+	// connection dials, so redirects and DNS rebinding cannot get around it;
+	// the dial check requires the client's Transport to be an
+	// *http.Transport (or nil). Enable it when running untrusted scripts to
+	// prevent server-side request forgery.
+	BlockPrivateNetworks bool
+
+	// MaxResponseBytes caps the size of a response body. Larger responses fail
+	// with an error instead of being buffered. Zero means no limit.
+	MaxResponseBytes int64
+
+	// MaxConcurrentFetches bounds how many requests may be in flight at once;
+	// excess requests wait for a free slot (or their timeout). Runners created
+	// from the same WithWebAccess option value share the limit, so a pool of
+	// runners cannot overwhelm an upstream together. Zero means no limit.
+	MaxConcurrentFetches int
+
+	// InstallText, InstallJSON, and InstallBytes select which helpers are
+	// installed. When all are false (the default), every helper is installed.
+	InstallText  bool
+	InstallJSON  bool
+	InstallBytes bool
+
+	// Prefix renames the helpers to avoid clashing with a script's own
+	// globals: with Prefix "go" they become goFetchText, goFetchJSON, and
+	// goFetchBytes.
+	Prefix string
+
+	// JSONParser decodes response bodies for fetchJSON. Replace it with a
+	// JSON5 or other relaxed parser to accept trailing commas and comments
+	// from config-style endpoints. The result is converted to JavaScript like
+	// any other Go value. Defaults to encoding/json.
+	JSONParser func(data []byte) (interface{}, error)
 }
 
-// WithWebAccess enables the built-in fetch helpers (`fetchJSON`, `fetchText`).
+// WithWebAccess enables the built-in fetch helpers (`fetchJSON`, `fetchText`,
+// and `fetchBytes`, which returns the raw body as a Uint8Array).
 // Provide a custom HTTP client or timeout via WebAccessConfig; when nil, sensible defaults are used.
 func WithWebAccess(cfg *WebAccessConfig) Option {
+	var slots chan struct{}
+	if cfg != nil && cfg.MaxConcurrentFetches > 0 {
+		slots = make(chan struct{}, cfg.MaxConcurrentFetches)
+	}
+	transports := new(sync.Map)
+
 	return func(r *Runner) {
 		r.webAccessEnabled = true
+		r.fetchHelpers = newFetchHelpers(cfg)
 		if cfg == nil {
 			return
 		}
@@ -84,6 +173,36 @@ func WithWebAccess(cfg *WebAccessConfig) Option {
 		if cfg.Timeout > 0 {
 			r.webAccessTimeout = cfg.Timeout
 		}
+		r.fetchPolicy = fetchPolicy{
+			allowedHosts:         cfg.AllowedHosts,
+			blockPrivateNetworks: cfg.BlockPrivateNetworks,
+			maxResponseBytes:     cfg.MaxResponseBytes,
+			slots:                slots,
+			transports:           transports,
+		}
+	}
+}
+
+// WithGlobals installs the provided global variables while the runner is being
+// constructed, keeping them in one place with the rest of the configuration.
+// Globals are applied after all other options, so converters configured via
+// WithValueConverter are honored regardless of option order. Multiple WithGlobals
+// options are merged; later values win for duplicate names.
+//
+// Example:
+//
+//	runner := jsrunner.New(jsrunner.WithGlobals(map[string]interface{}{
+//	    "apiUrl": "https://api.example.com",
+//	    "debug":  true,
+//	}))
+func WithGlobals(globals map[string]interface{}) Option {
+	return func(r *Runner) {
+		if r.initialGlobals == nil {
+			r.initialGlobals = make(map[string]interface{}, len(globals))
+		}
+		for k, v := range globals {
+			r.initialGlobals[k] = v
+		}
 	}
 }
 
@@ -94,27 +213,62 @@ func (r *Runner) applyOptions(opts ...Option) {
 		}
 		opt(r)
 	}
+	r.logger = loggerOrNoop(r.logger)
+
+	if r.randomSeed != nil {
+		r.vm.SetRandSource(seededRandSource(*r.randomSeed))
+	}
+	if r.rejectionTracker != nil {
+		r.vm.SetPromiseRejectionTracker(r.rejectionTracker)
+	}
+	if r.asyncIteration {
+		installAsyncIteratorSymbol(r.vm)
+	}
+	for name, value := range r.initialGlobals {
+		r.SetGlobal(name, value)
+	}
 
 	if r.webAccessEnabled {
-		r.initWebAccess()
+		r.webAccessTimeout, r.httpClient = webAccessDefaults(r.webAccessTimeout, r.httpClient)
+	}
+	r.installFeatures()
+
+	if r.frozenGlobals {
+		r.freezeGlobals()
+	}
+	if r.frozenIntrinsics {
+		freezeIntrinsics(r.vm)
+	}
+	if r.profiler != nil {
+		r.profiler.attach(r.vm)
 	}
 }
 
 // EnableWebAccess turns on the built-in fetch helpers after runner construction.
+// When the runner was created with WithFrozenGlobals, the helpers are frozen as well.
 func (r *Runner) EnableWebAccess(cfg *WebAccessConfig) {
 	WithWebAccess(cfg)(r)
 	r.webAccessEnabled = true
-	r.initWebAccess()
+	r.webAccessTimeout, r.httpClient = webAccessDefaults(r.webAccessTimeout, r.httpClient)
+	helpers := features{webAccess: true, fetchHelpers: r.fetchHelpers}
+	for name, value := range helpers.globals(r.fetchBytes) {
+		r.SetGlobal(name, value)
+	}
+	if r.frozenGlobals {
+		r.freezeGlobals(helpers.names()...)
+	}
 }
 
-func (r *Runner) initWebAccess() {
-	if r.webAccessTimeout <= 0 {
-		r.webAccessTimeout = defaultWebAccessTimeout
-	}
-	if r.httpClient == nil {
-		r.httpClient = &http.Client{Timeout: r.webAccessTimeout}
+// features returns the feature options enabled on the runner.
+func (r *Runner) features() features {
+	return features{webAccess: r.webAccessEnabled, fetchHelpers: r.fetchHelpers, console: r.console, urlGlobals: r.urlGlobals, base64: r.base64Globals, textCodecs: r.textCodecs}
+}
+
+// installFeatures sets the globals of every enabled feature option.
+func (r *Runner) installFeatures() {
+	for name, value := range r.features().globals(r.fetchBytes) {
+		r.SetGlobal(name, value)
 	}
-	r.installFetchGlobals()
 }
 
 // New creates and returns a new JavaScript runner with a fresh runtime environment.
@@ -167,8 +321,12 @@ func New(opts ...Option) *Runner {
 // affect other runners.
 func NewWithGlobals(globals map[string]interface{}, opts ...Option) *Runner {
 	r := New(opts...)
+	if r.initialGlobals == nil {
+		r.initialGlobals = make(map[string]interface{}, len(globals))
+	}
 	for k, v := range globals {
 		r.SetGlobal(k, v)
+		r.initialGlobals[k] = v
 	}
 	return r
 }
@@ -182,6 +340,12 @@ func NewWithGlobals(globals map[string]interface{}, opts ...Option) *Runner {
 //   - Slices and maps (converted to JavaScript arrays and objects)
 //   - Structs (fields become JavaScript object properties)
 //   - Functions (can be called from JavaScript)
+//   - time.Duration and time.Time (as milliseconds and Date) with WithTimeConversion
+//
+// Setting a name that already exists overwrites it: the last call wins, both
+// for the JavaScript binding and for the value tracked by ResetGlobals. This
+// also replaces globals defined by loaded scripts.
+// Use SetGlobalOnce to install a value only when the name is free.
 //
 // Example:
 //
@@ -191,8 +355,108 @@ func NewWithGlobals(globals map[string]interface{}, opts ...Option) *Runner {
 //	runner.SetGlobal("debug", true)
 //	runner.Eval(`console.log(apiUrl, timeout, debug)`)
 func (r *Runner) SetGlobal(name string, value interface{}) {
+	r.syncLock()
+	defer r.syncUnlock()
+
 	r.globals[name] = value
-	r.vm.Set(name, value)
+	r.vm.Set(name, r.toValue(value))
+}
+
+// SetGlobalOnce sets a global like SetGlobal, but only when no global with
+// that name exists yet, whether it was installed from Go, defined by a loaded
+// script, or is a built-in such as JSON. It reports whether the value was set;
+// an existing global is never overwritten. Use it to install defaults that
+// scripts or earlier setup code may already have provided.
+//
+// Example:
+//
+//	runner.LoadScriptString(`var config = { env: "test" }`)
+//	runner.SetGlobalOnce("config", defaultConfig) // false, script value kept
+//	runner.SetGlobalOnce("locale", "en-US")       // true
+func (r *Runner) SetGlobalOnce(name string, value interface{}) bool {
+	r.syncLock()
+	defer r.syncUnlock()
+
+	if r.vm.GlobalObject().Get(name) != nil {
+		return false
+	}
+	r.globals[name] = value
+	r.vm.Set(name, r.toValue(value))
+	return true
+}
+
+// SetGlobalGetter defines a computed global whose value is produced by calling
+// fn on every read, using a JavaScript getter under the hood. Use it for values
+// that must be fresh on each access, such as the current time or a nonce,
+// which a static SetGlobal cannot express.
+//
+// The returned value goes through the same conversion as SetGlobal. Assigning
+// to the global from JavaScript has no effect.
+//
+// Example:
+//
+//	runner.SetGlobalGetter("now", func() interface{} {
+//	    return time.Now().UnixMilli()
+//	})
+//	runner.Eval("now") // a fresh timestamp on every evaluation
+func (r *Runner) SetGlobalGetter(name string, fn func() interface{}) {
+	r.syncLock()
+	defer r.syncUnlock()
+
+	if err := r.defineGlobalGetter(name, fn); err != nil {
+		r.logger.Warn("failed to define global getter", "name", name, "error", err)
+		return
+	}
+	r.globals[name] = globalGetter(fn)
+}
+
+// globalGetter marks entries in Runner.globals that were installed through
+// SetGlobalGetter.
+type globalGetter func() interface{}
+
+func (r *Runner) defineGlobalGetter(name string, fn func() interface{}) error {
+	getter := r.vm.ToValue(func(goja.FunctionCall) goja.Value {
+		return r.toValue(fn())
+	})
+	return r.vm.GlobalObject().DefineAccessorProperty(name, getter, nil, goja.FLAG_TRUE, goja.FLAG_TRUE)
+}
+
+// ResetGlobals deletes every global installed through SetGlobal except the
+// names listed in keep, restoring a clean slate between pooled uses without
+// reloading bundles. Functions and variables defined by loaded scripts, globals
+// supplied at construction (WithGlobals, NewWithGlobals), and the built-in fetch
+// helpers are preserved. Frozen globals cannot be deleted and are left in place.
+//
+// Example:
+//
+//	runner.SetGlobal("SERVER_PROPS", props)
+//	html, _ := runner.Eval("renderApp(SERVER_PROPS)")
+//	runner.ResetGlobals() // SERVER_PROPS is gone, renderApp remains
+func (r *Runner) ResetGlobals(keep ...string) {
+	r.syncLock()
+	defer r.syncUnlock()
+
+	kept := make(map[string]struct{}, len(keep)+len(r.initialGlobals))
+	for _, name := range keep {
+		kept[name] = struct{}{}
+	}
+	for name := range r.initialGlobals {
+		kept[name] = struct{}{}
+	}
+	for _, name := range r.features().names() {
+		kept[name] = struct{}{}
+	}
+
+	global := r.vm.GlobalObject()
+	for name := range r.globals {
+		if _, ok := kept[name]; ok {
+			continue
+		}
+		if err := global.Delete(name); err != nil {
+			continue
+		}
+		delete(r.globals, name)
+	}
 }
 
 // LoadScript loads and executes a JavaScript file from the specified filepath.
@@ -204,6 +468,8 @@ func (r *Runner) SetGlobal(name string, value interface{}) {
 // or runtime errors, they will be returned as an error.
 //
 // This method is useful for loading external JavaScript libraries or configuration scripts.
+// The script is compiled with its filepath as the source name, so syntax errors and
+// stack traces (for example goja.Exception.String()) reference the real file.
 //
 // Example:
 //
@@ -218,17 +484,42 @@ func (r *Runner) SetGlobal(name string, value interface{}) {
 //   - The file cannot be read (e.g., file not found, permission denied)
 //   - The JavaScript code contains syntax errors
 //   - The JavaScript code throws a runtime error during execution
-func (r *Runner) LoadScript(filepath string) error {
+func (r *Runner) LoadScript(filepath string) (err error) {
+	defer r.reportError("LoadScript", filepath, &err)
+
 	code, err := os.ReadFile(filepath)
 	if err != nil {
 		return fmt.Errorf("failed to read script file: %w", err)
 	}
 
-	_, err = r.vm.RunString(string(code))
+	if err := r.runNamedScript(filepath, bytesToString(code)); err != nil {
+		return err
+	}
+
+	r.logger.Debug("script loaded", "name", filepath)
+	return nil
+}
+
+// runNamedScript compiles code under the given source name so that syntax errors
+// and stack traces reference the real file instead of a synthetic name.
+func (r *Runner) runNamedScript(name, code string) error {
+	r.syncLock()
+	defer r.syncUnlock()
+
+	return r.runNamedScriptLocked(name, code)
+}
+
+// runNamedScriptLocked is runNamedScript for callers that already hold the
+// lock taken by syncLock.
+func (r *Runner) runNamedScriptLocked(name, code string) error {
+	program, err := r.compile(name, code)
 	if err != nil {
 		return fmt.Errorf("failed to execute script: %w", err)
 	}
 
+	if _, err := r.runProgram(program); err != nil {
+		return fmt.Errorf("failed to execute script: %w", err)
+	}
 	return nil
 }
 
@@ -255,14 +546,69 @@ func (r *Runner) LoadScript(filepath string) error {
 // Returns an error if:
 //   - The JavaScript code contains syntax errors
 //   - The JavaScript code throws a runtime error during execution
-func (r *Runner) LoadScriptString(code string) error {
-	_, err := r.vm.RunString(code)
+func (r *Runner) LoadScriptString(code string) (err error) {
+	defer r.reportError("LoadScriptString", code, &err)
+
+	return r.runNamedScript("", code)
+}
+
+// LoadScriptFS loads and executes the named JavaScript file from the provided fs.FS.
+// This fits applications that embed their scripts with go:embed, where files are
+// exposed through an fs.FS rather than OS paths.
+//
+// Example:
+//
+//	//go:embed scripts/*.js
+//	var scripts embed.FS
+//
+//	runner := jsrunner.New()
+//	err := runner.LoadScriptFS(scripts, "scripts/utils.js")
+//
+// Returns an error if:
+//   - The file cannot be read from the filesystem
+//   - The JavaScript code contains syntax errors
+//   - The JavaScript code throws a runtime error during execution
+func (r *Runner) LoadScriptFS(fsys fs.FS, name string) (err error) {
+	defer r.reportError("LoadScriptFS", name, &err)
+
+	code, err := fs.ReadFile(fsys, name)
 	if err != nil {
-		return fmt.Errorf("failed to execute script: %w", err)
+		return fmt.Errorf("failed to read script file: %w", err)
+	}
+
+	if err := r.runNamedScript(name, bytesToString(code)); err != nil {
+		return err
 	}
+
+	r.logger.Debug("script loaded", "name", name)
 	return nil
 }
 
+// LoadScriptReader reads all JavaScript code from the provided io.Reader and executes it.
+// This allows loading scripts from embedded assets, HTTP bodies, or compressed streams
+// without writing them to a temporary file first.
+//
+// Example:
+//
+//	resp, _ := http.Get("https://example.com/lib.js")
+//	defer resp.Body.Close()
+//	err := runner.LoadScriptReader(resp.Body)
+//
+// Returns an error if:
+//   - Reading from the reader fails
+//   - The JavaScript code contains syntax errors
+//   - The JavaScript code throws a runtime error during execution
+func (r *Runner) LoadScriptReader(reader io.Reader) (err error) {
+	defer r.reportError("LoadScriptReader", "", &err)
+
+	code, err := io.ReadAll(reader)
+	if err != nil {
+		return fmt.Errorf("failed to read script: %w", err)
+	}
+
+	return r.runNamedScript("", bytesToString(code))
+}
+
 // Call invokes a JavaScript function with the provided arguments.
 // The function must be defined in the JavaScript environment (either through LoadScript,
 // LoadScriptString, or SetGlobal) before calling.
@@ -274,6 +620,19 @@ func (r *Runner) LoadScriptString(code string) error {
 //   - Go slices become JavaScript arrays
 //   - Go maps become JavaScript objects
 //
+// Conversion applies at every nesting level, so a []map[string]interface{}
+// whose values hold further slices arrives as an array of objects containing
+// arrays: Array.isArray, JSON.stringify, and property access behave as they
+// would for literals. Each argument is passed as a value, never spliced into
+// source code, so objects are not flattened to "[object Object]".
+//
+// When a ValueConverter is configured via WithValueConverter, it is consulted
+// for each argument, and for every element nested inside it, before the
+// default conversion.
+//
+// Dotted names such as "JSON.stringify" are resolved from the global object and
+// invoked with their parent object as the receiver.
+//
 // The result is returned as a goja.Value, which can be converted to Go types using
 // the Export helper functions (ExportString, ExportInt, ExportFloat, ExportBool, Export).
 //
@@ -291,33 +650,304 @@ func (r *Runner) LoadScriptString(code string) error {
 //   - The function does not exist in the JavaScript environment
 //   - The function throws a runtime error
 //   - Arguments cannot be converted to JavaScript types
-func (r *Runner) Call(functionName string, args ...interface{}) (goja.Value, error) {
-	// Build the function call with arguments
-	var jsArgs string
+//   - A bound Go function returns an error and the runner uses
+//     BoundErrorReturn (see WithBoundErrorMode); the value is then nil
+func (r *Runner) Call(functionName string, args ...interface{}) (value goja.Value, err error) {
+	defer r.reportError("Call", functionName, &err)
+
+	r.syncLock()
+	defer r.syncUnlock()
+
+	return r.call(functionName, args...)
+}
+
+func (r *Runner) call(functionName string, args ...interface{}) (goja.Value, error) {
+	fn, this, err := r.resolveFunction(functionName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call function %s: %w", functionName, err)
+	}
+
+	jsArgs := make([]goja.Value, len(args))
 	for i, arg := range args {
-		if i > 0 {
-			jsArgs += ", "
+		jsArgs[i] = r.toValue(arg)
+	}
+
+	result, err := fn(this, jsArgs...)
+	if err = r.boundError(err); err != nil {
+		return nil, fmt.Errorf("failed to call function %s: %w", functionName, err)
+	}
+
+	return result, nil
+}
+
+// ExportFunc resolves a JavaScript function once and returns a Go closure that
+// invokes it, avoiding the name lookup Call performs on every invocation. The
+// name is resolved like in Call (dotted names keep their parent object as the
+// receiver), and arguments are converted the same way. The closure takes the
+// runner's lock when the runner was created with WithSynchronized, and
+// failures are reported to the WithErrorHandler callback under the "Call"
+// operation.
+//
+// The closure keeps calling the function that existed when ExportFunc ran:
+// redefining the global later is not picked up. Call ExportFunc again in that
+// case.
+//
+// Example:
+//
+//	runner.LoadScriptString(`function add(a, b) { return a + b; }`)
+//	add, err := runner.ExportFunc("add")
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	for i := 0; i < 3; i++ {
+//	    result, _ := add(i, 10)
+//	    fmt.Println(jsrunner.ExportInt(result)) // 10, 11, 12
+//	}
+func (r *Runner) ExportFunc(name string) (func(args ...interface{}) (goja.Value, error), error) {
+	r.syncLock()
+	fn, this, err := r.resolveFunction(name)
+	r.syncUnlock()
+	if err != nil {
+		return nil, fmt.Errorf("failed to export function %s: %w", name, err)
+	}
+
+	return func(args ...interface{}) (value goja.Value, err error) {
+		defer r.reportError("Call", name, &err)
+
+		r.syncLock()
+		defer r.syncUnlock()
+
+		jsArgs := make([]goja.Value, len(args))
+		for i, arg := range args {
+			jsArgs[i] = r.toValue(arg)
 		}
-		// Format the argument based on its type
-		switch v := arg.(type) {
-		case string:
-			jsArgs += fmt.Sprintf("%q", v)
-		case int, int32, int64, float32, float64, bool:
-			jsArgs += fmt.Sprintf("%v", v)
-		default:
-			jsArgs += fmt.Sprintf("%v", v)
+
+		result, err := fn(this, jsArgs...)
+		if err = r.boundError(err); err != nil {
+			return nil, fmt.Errorf("failed to call function %s: %w", name, err)
 		}
+		return result, nil
+	}, nil
+}
+
+// CallOn invokes a JavaScript function value with an explicit `this` receiver.
+// It complements Call for cases where the function was pulled off an object or
+// prototype and must run against a different receiver.
+//
+// Arguments are converted the same way as in Call. Pass goja.Undefined() as
+// this for functions that do not rely on a receiver.
+//
+// Example:
+//
+//	join, _ := runner.Eval("Array.prototype.join")
+//	arr, _ := runner.Eval("['a', 'b', 'c']")
+//	result, err := runner.CallOn(arr, join, "-")
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	joined := jsrunner.ExportString(result) // "a-b-c"
+//
+// Returns an error if fn is not callable or the function throws.
+func (r *Runner) CallOn(this goja.Value, fn goja.Value, args ...interface{}) (value goja.Value, err error) {
+	defer r.reportError("CallOn", "", &err)
+
+	r.syncLock()
+	defer r.syncUnlock()
+
+	callable, ok := goja.AssertFunction(fn)
+	if !ok {
+		return nil, fmt.Errorf("failed to call function: value is not a function")
+	}
+	if this == nil {
+		this = goja.Undefined()
 	}
 
-	script := fmt.Sprintf("%s(%s)", functionName, jsArgs)
-	result, err := r.vm.RunString(script)
-	if err != nil {
-		return nil, fmt.Errorf("failed to call function %s: %w", functionName, err)
+	jsArgs := make([]goja.Value, len(args))
+	for i, arg := range args {
+		jsArgs[i] = r.toValue(arg)
+	}
+
+	result, err := callable(this, jsArgs...)
+	if err = r.boundError(err); err != nil {
+		return nil, fmt.Errorf("failed to call function: %w", err)
 	}
 
 	return result, nil
 }
 
+// Construct creates a new instance of a JavaScript class or constructor
+// function, equivalent to `new X(...args)` in JavaScript. The constructor is
+// resolved the same way as functions in Call, so classes declared by loaded
+// scripts and dotted names such as "Intl.NumberFormat" both work.
+//
+// Arguments are converted the same way as in Call.
+//
+// Example:
+//
+//	runner.LoadScriptString(`class Point { constructor(x, y) { this.x = x; this.y = y; } }`)
+//	point, err := runner.Construct("Point", 3, 4)
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	x := jsrunner.ExportInt(point.ToObject(runner.GetVM()).Get("x")) // 3
+//
+// Returns an error if the constructor does not exist, is not constructable, or
+// throws during construction.
+func (r *Runner) Construct(constructorName string, args ...interface{}) (instance goja.Value, err error) {
+	defer r.redactError(&err)
+
+	r.syncLock()
+	defer r.syncUnlock()
+
+	ctorValue, _, err := r.resolvePath(constructorName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to construct %s: %w", constructorName, err)
+	}
+
+	ctor, ok := goja.AssertConstructor(ctorValue)
+	if !ok {
+		return nil, fmt.Errorf("failed to construct %s: %s is not a constructor", constructorName, constructorName)
+	}
+
+	jsArgs := make([]goja.Value, len(args))
+	for i, arg := range args {
+		jsArgs[i] = r.toValue(arg)
+	}
+
+	instance, err = ctor(nil, jsArgs...)
+	if err = r.boundError(err); err != nil {
+		return nil, fmt.Errorf("failed to construct %s: %w", constructorName, err)
+	}
+
+	return instance, nil
+}
+
+// FunctionArity reports the declared parameter count (the JavaScript
+// `.length` property) of the named function and whether such a function
+// exists. Dotted names are resolved like in Call. Use it to fail fast on
+// contract mismatches before invoking user-supplied scripts.
+//
+// Note that rest parameters and parameters with default values do not count
+// towards `.length`.
+//
+// Example:
+//
+//	runner.LoadScriptString(`function handle(req, res) {}`)
+//	if arity, ok := runner.FunctionArity("handle"); !ok || arity != 2 {
+//	    log.Fatal("handle(req, res) is required")
+//	}
+func (r *Runner) FunctionArity(name string) (int, bool) {
+	r.syncLock()
+	defer r.syncUnlock()
+
+	value, _, err := r.resolvePath(name)
+	if err != nil {
+		return 0, false
+	}
+	if _, ok := goja.AssertFunction(value); !ok {
+		return 0, false
+	}
+	return int(value.ToObject(r.vm).Get("length").ToInteger()), true
+}
+
+// DefinedFunctions returns the sorted names of all callable, enumerable own
+// properties of the global object. This covers function declarations from
+// loaded scripts and Go functions installed via SetGlobal, but not built-ins
+// such as JSON or Math, which are non-enumerable. Use it to build dispatch
+// tables or documentation from user-supplied scripts.
+//
+// Example:
+//
+//	runner.LoadScriptString(`function onCreate() {} function onDelete() {} var version = 2;`)
+//	names := runner.DefinedFunctions() // ["onCreate", "onDelete"]
+func (r *Runner) DefinedFunctions() []string {
+	r.syncLock()
+	defer r.syncUnlock()
+
+	global := r.vm.GlobalObject()
+	var names []string
+	for _, key := range global.Keys() {
+		if _, ok := goja.AssertFunction(global.Get(key)); ok {
+			names = append(names, key)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// resolveFunction looks up a function by name on the global object. Dotted
+// names such as "JSON.stringify" are walked property by property and the
+// final parent object is returned as the receiver.
+func (r *Runner) resolveFunction(name string) (goja.Callable, goja.Value, error) {
+	current, this, err := r.resolvePath(name)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	fn, ok := goja.AssertFunction(current)
+	if !ok {
+		return nil, nil, fmt.Errorf("%s is not a function", name)
+	}
+
+	return fn, this, nil
+}
+
+// identifierPattern matches the shape of plain JavaScript identifiers. Use
+// isIdentifier, which also rules out reserved words, to validate a name.
+var identifierPattern = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$]*$`)
+
+// reservedWords lists the words that match identifierPattern but cannot name a
+// binding: keywords, literals, the words reserved in strict mode, and eval and
+// arguments, which strict mode does not allow as binding names either.
+var reservedWords = map[string]bool{
+	"await": true, "break": true, "case": true, "catch": true, "class": true,
+	"const": true, "continue": true, "debugger": true, "default": true,
+	"delete": true, "do": true, "else": true, "enum": true, "export": true,
+	"extends": true, "false": true, "finally": true, "for": true,
+	"function": true, "if": true, "import": true, "in": true,
+	"instanceof": true, "new": true, "null": true, "return": true,
+	"super": true, "switch": true, "this": true, "throw": true, "true": true,
+	"try": true, "typeof": true, "var": true, "void": true, "while": true,
+	"with": true, "yield": true, "let": true, "static": true,
+	"implements": true, "interface": true, "package": true, "private": true,
+	"protected": true, "public": true, "eval": true, "arguments": true,
+}
+
+// isIdentifier reports whether name is a plain JavaScript identifier that can
+// be declared as a variable or parameter, so it is safe to splice into code.
+func isIdentifier(name string) bool {
+	return identifierPattern.MatchString(name) && !reservedWords[name]
+}
+
+// resolvePath walks a dotted name starting at the global scope and returns the
+// value it refers to together with its parent object. The first segment falls
+// back to lexical bindings (class, let, const) declared by loaded scripts, which
+// are not properties of the global object.
+func (r *Runner) resolvePath(name string) (goja.Value, goja.Value, error) {
+	var this goja.Value = goja.Undefined()
+	current := goja.Value(r.vm.GlobalObject())
+
+	for i, part := range strings.Split(name, ".") {
+		if goja.IsUndefined(current) || goja.IsNull(current) {
+			return nil, nil, fmt.Errorf("%s is not defined", name)
+		}
+		this = current
+		current = current.ToObject(r.vm).Get(part)
+		if current == nil && i == 0 && isIdentifier(part) {
+			current, _ = r.vm.RunString("typeof " + part + " === 'undefined' ? undefined : " + part)
+		}
+		if current == nil {
+			current = goja.Undefined()
+		}
+	}
+
+	if goja.IsUndefined(current) {
+		return nil, nil, fmt.Errorf("%s is not defined", name)
+	}
+
+	return current, this, nil
+}
+
 // Eval evaluates a JavaScript expression and returns the result.
 // This method can execute any valid JavaScript expression, from simple arithmetic
 // to complex object manipulations. The expression is evaluated in the context of
@@ -350,81 +980,207 @@ func (r *Runner) Call(functionName string, args ...interface{}) (goja.Value, err
 // Returns an error if:
 //   - The expression contains syntax errors
 //   - The expression throws a runtime error during evaluation
-func (r *Runner) Eval(expression string) (goja.Value, error) {
-	result, err := r.vm.RunString(expression)
+func (r *Runner) Eval(expression string) (value goja.Value, err error) {
+	defer r.reportError("Eval", expression, &err)
+
+	r.syncLock()
+	defer r.syncUnlock()
+
+	return r.eval(expression)
+}
+
+func (r *Runner) eval(expression string) (goja.Value, error) {
+	program, err := r.compile("", expression)
+	if err != nil {
+		return nil, fmt.Errorf("failed to evaluate expression: %w", err)
+	}
+	result, err := r.runProgram(program)
 	if err != nil {
 		return nil, fmt.Errorf("failed to evaluate expression: %w", err)
 	}
 	return result, nil
 }
 
-// GetVM returns the underlying goja.Runtime for advanced usage.
-// This provides direct access to the JavaScript runtime for operations not covered
-// by the Runner's high-level API.
+// EvalWith evaluates a JavaScript expression with the given locals bound as
+// function parameters for the duration of the call. Unlike SetGlobal, the
+// bindings never touch the global scope, so temporary inputs cannot leak
+// between calls. Locals shadow globals of the same name.
 //
-// Use this method when you need:
-//   - Direct access to goja's low-level APIs
-//   - Custom type conversions or object wrapping
-//   - Advanced runtime configuration
-//   - Integration with other goja-based libraries
+// Local names must be valid JavaScript identifiers and not reserved words
+// such as this, new, or class.
 //
 // Example:
 //
-//	runner := jsrunner.New()
-//	vm := runner.GetVM()
-//	// Use vm for advanced goja operations
-//	vm.SetFieldNameMapper(goja.TagFieldNameMapper("json", true))
+//	result, err := runner.EvalWith("price * quantity", map[string]interface{}{
+//	    "price":    9.5,
+//	    "quantity": 3,
+//	})
+//	total := jsrunner.ExportFloat(result) // 28.5
 //
-// Note: Direct manipulation of the VM may affect the Runner's behavior.
-// Use with caution and ensure you understand goja's API.
-func (r *Runner) GetVM() *goja.Runtime {
-	return r.vm
+// Returns an error if a local name is not a valid identifier or is a reserved
+// word, the expression contains syntax errors, or it throws a runtime error.
+func (r *Runner) EvalWith(expression string, locals map[string]interface{}) (value goja.Value, err error) {
+	defer r.reportError("EvalWith", expression, &err)
+
+	r.syncLock()
+	defer r.syncUnlock()
+
+	names := make([]string, 0, len(locals))
+	for name := range locals {
+		if !isIdentifier(name) {
+			return nil, fmt.Errorf("failed to evaluate expression: invalid local name %q", name)
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	program, err := r.compile("", "(function("+strings.Join(names, ", ")+") {\nreturn (\n"+expression+"\n);\n})")
+	if err != nil {
+		return nil, fmt.Errorf("failed to evaluate expression: %w", err)
+	}
+	wrapper, err := r.vm.RunProgram(program)
+	if err != nil {
+		return nil, fmt.Errorf("failed to evaluate expression: %w", err)
+	}
+	fn, ok := goja.AssertFunction(wrapper)
+	if !ok {
+		return nil, fmt.Errorf("failed to evaluate expression: wrapper is not a function")
+	}
+
+	args := make([]goja.Value, len(names))
+	for i, name := range names {
+		args[i] = r.toValue(locals[name])
+	}
+
+	result, err := fn(goja.Undefined(), args...)
+	if err = r.boundError(err); err != nil {
+		return nil, fmt.Errorf("failed to evaluate expression: %w", err)
+	}
+	return result, nil
 }
 
-func (r *Runner) installFetchGlobals() {
-	r.SetGlobal("fetchText", func(url string) (string, error) {
-		data, err := r.fetchBytes(url)
+// EvalExports reads the named globals and returns them exported to Go values,
+// keyed by name, in one call. Use it after an initialization script that
+// leaves several results in globals instead of calling Eval once per name.
+// Values are converted with ExportWith, so the runner's export converters
+// apply.
+//
+// Example:
+//
+//	runner.LoadScriptString(`const total = 42; let label = "answer"; var tags = ["a", "b"];`)
+//	results, err := runner.EvalExports("total", "label", "tags")
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	total := results["total"].(int64) // 42
+//
+// Names are resolved the way Eval resolves them, so top-level let, const, and
+// class bindings are found as well as properties of the global object. Each
+// name must be a valid JavaScript identifier. Returns an error for the first
+// name that is not defined (a ReferenceError, as Eval would report). A global
+// that exists but holds undefined is returned as nil.
+func (r *Runner) EvalExports(names ...string) (exports map[string]interface{}, err error) {
+	defer r.reportError("EvalExports", strings.Join(names, ", "), &err)
+
+	r.syncLock()
+	defer r.syncUnlock()
+
+	exports = make(map[string]interface{}, len(names))
+	for _, name := range names {
+		if !isIdentifier(name) {
+			return nil, fmt.Errorf("failed to export globals: invalid name %q", name)
+		}
+		program, err := r.compile("", name)
 		if err != nil {
-			return "", err
+			return nil, fmt.Errorf("failed to export globals: %w", err)
 		}
-		return string(data), nil
-	})
-
-	r.SetGlobal("fetchJSON", func(url string) (interface{}, error) {
-		data, err := r.fetchBytes(url)
+		value, err := r.runProgram(program)
 		if err != nil {
-			return nil, err
+			return nil, fmt.Errorf("failed to export globals: %w", err)
 		}
-
-		var payload interface{}
-		if err := json.Unmarshal(data, &payload); err != nil {
-			return nil, err
+		exports[name], err = exportWith(r, value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to export global %s: %w", name, err)
 		}
-
-		return payload, nil
-	})
+	}
+	return exports, nil
 }
 
-func (r *Runner) fetchBytes(url string) ([]byte, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), r.webAccessTimeout)
-	defer cancel()
-
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+// Compile parses and compiles JavaScript code into a goja.Program that can be
+// executed many times, by any number of runners, without reparsing. The name
+// is used as the source file name in syntax errors and stack traces.
+//
+// A compiled Program is immutable and safe to share across goroutines.
+//
+// Example:
+//
+//	program, err := jsrunner.Compile("bundle.js", bundleSource)
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	for _, runner := range pool {
+//	    runner.RunProgram(program)
+//	}
+func Compile(name, code string) (*goja.Program, error) {
+	program, err := goja.Compile(name, code, false)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("failed to compile script: %w", err)
 	}
+	return program, nil
+}
 
-	resp, err := r.httpClient.Do(req)
-	if err != nil {
-		return nil, err
+// RunProgram executes a precompiled Program in the runner's JavaScript
+// environment and returns the value of its last expression. Use it with
+// Compile to load the same large bundle into many runners without paying the
+// parse cost each time.
+//
+// Example:
+//
+//	program, _ := jsrunner.Compile("app.js", source)
+//	runner := jsrunner.New()
+//	if _, err := runner.RunProgram(program); err != nil {
+//	    log.Fatal(err)
+//	}
+//
+// Returns an error if p is nil or the program throws a runtime error.
+func (r *Runner) RunProgram(p *goja.Program) (value goja.Value, err error) {
+	defer r.redactError(&err)
+
+	if p == nil {
+		return nil, fmt.Errorf("failed to run program: program is nil")
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode >= http.StatusBadRequest {
-		return nil, fmt.Errorf("fetch request failed with status %d", resp.StatusCode)
+	r.syncLock()
+	defer r.syncUnlock()
+
+	result, err := r.runProgram(p)
+	if err != nil {
+		return nil, fmt.Errorf("failed to run program: %w", err)
 	}
+	return result, nil
+}
 
-	return io.ReadAll(resp.Body)
+// GetVM returns the underlying goja.Runtime for advanced usage.
+// This provides direct access to the JavaScript runtime for operations not covered
+// by the Runner's high-level API.
+//
+// Use this method when you need:
+//   - Direct access to goja's low-level APIs
+//   - Custom type conversions or object wrapping
+//   - Advanced runtime configuration
+//   - Integration with other goja-based libraries
+//
+// Example:
+//
+//	runner := jsrunner.New()
+//	vm := runner.GetVM()
+//	// Use vm for advanced goja operations
+//	vm.SetFieldNameMapper(goja.TagFieldNameMapper("json", true))
+//
+// Note: Direct manipulation of the VM may affect the Runner's behavior.
+// Use with caution and ensure you understand goja's API.
+func (r *Runner) GetVM() *goja.Runtime {
+	return r.vm
 }
 
 // ExportString is a helper function that converts a goja.Value to a Go string.
@@ -545,6 +1301,7 @@ func ExportBool(val goja.Value) bool {
 //   - JavaScript strings become Go strings
 //   - JavaScript numbers become Go float64
 //   - JavaScript booleans become Go bool
+//   - JavaScript BigInts become Go *big.Int (without loss of precision)
 //   - JavaScript arrays become Go []interface{}
 //   - JavaScript objects become Go map[string]interface{}
 //   - JavaScript null becomes Go nil
@@ -570,9 +1327,34 @@ func Export(val goja.Value) interface{} {
 	if val == nil {
 		return nil
 	}
+	if n, ok := ExportBigInt(val); ok {
+		return n
+	}
 	return val.Export()
 }
 
+// ExportBigInt is a helper function that converts a JavaScript BigInt to a Go *big.Int.
+// Unlike ExportInt, the value is not truncated to 64 bits, which makes it suitable
+// for cryptographic or identifier code that relies on arbitrary-precision integers.
+//
+// The second return value reports whether val was a BigInt. If the value is nil or
+// not a BigInt, nil and false are returned.
+//
+// Example:
+//
+//	result, _ := runner.Eval("2n ** 80n")
+//	n, ok := jsrunner.ExportBigInt(result) // 1208925819614629174706176, true
+func ExportBigInt(val goja.Value) (*big.Int, bool) {
+	if val == nil || !goja.IsBigInt(val) {
+		return nil, false
+	}
+	n, ok := val.Export().(*big.Int)
+	if !ok {
+		return nil, false
+	}
+	return new(big.Int).Set(n), true
+}
+
 // EventLoopRunner represents a JavaScript runtime with an event loop that supports
 // asynchronous operations like Promises, setTimeout, setInterval, and setImmediate.
 // It wraps the goja runtime with an event loop for proper async/await support.
@@ -604,8 +1386,33 @@ type EventLoopRunner struct {
 	globals          map[string]interface{}
 	mu               sync.RWMutex
 	httpClient       *http.Client
+	httpClientFunc   func(url string) *http.Client
 	webAccessEnabled bool
 	webAccessTimeout time.Duration
+	fetchPolicy      fetchPolicy
+	fetchHelpers     fetchHelpers
+	fetchCache       *fetchCache
+	loopback         loopbackRoutes
+	console          io.Writer
+	logger           Logger
+	asyncIteration   bool
+	urlGlobals       bool
+	base64Globals    bool
+	textCodecs       bool
+	watchdog         *loopWatchdog
+	rejectionTracker goja.PromiseRejectionTracker
+
+	// Feature globals (fetch helpers, console, URL, base64, text codecs) installed on every loop entry.
+	featureGlobals map[string]interface{}
+
+	// Tasks scheduled through the Go wrappers that have not run yet. An
+	// interval maps to the function that stops its context watch, if any.
+	timersMu   sync.Mutex
+	timers     map[*eventloop.Timer]struct{}
+	intervals  map[*eventloop.Interval]func() bool
+	queuedJobs int64
+
+	stats loopCounters
 }
 
 // NewEventLoopRunner creates a new JavaScript runner with an event loop.
@@ -615,6 +1422,12 @@ type EventLoopRunner struct {
 // The runner must be started with Start() before executing async code,
 // and should be stopped with Stop() when done.
 //
+// Options that only a Runner supports, such as WithStrictMode,
+// WithProgramCache, the value and export converters, WithFrozenIntrinsics,
+// WithRandomSeed, WithProfiler, WithErrorHandler, and WithBoundErrorMode,
+// have no effect here; each one passed is reported with a warning through the
+// configured Logger.
+//
 // Example:
 //
 //	runner := jsrunner.NewEventLoopRunner()
@@ -626,8 +1439,10 @@ type EventLoopRunner struct {
 //	`)
 func NewEventLoopRunner(opts ...Option) *EventLoopRunner {
 	r := &EventLoopRunner{
-		loop:    eventloop.NewEventLoop(),
-		globals: make(map[string]interface{}),
+		loop:      eventloop.NewEventLoop(),
+		globals:   make(map[string]interface{}),
+		timers:    make(map[*eventloop.Timer]struct{}),
+		intervals: make(map[*eventloop.Interval]func() bool),
 	}
 	r.applyOptions(opts...)
 	return r
@@ -668,7 +1483,70 @@ func (r *EventLoopRunner) applyOptions(opts ...Option) {
 
 	r.webAccessEnabled = tempRunner.webAccessEnabled
 	r.httpClient = tempRunner.httpClient
+	r.httpClientFunc = tempRunner.httpClientFunc
 	r.webAccessTimeout = tempRunner.webAccessTimeout
+	r.fetchPolicy = tempRunner.fetchPolicy
+	r.fetchHelpers = tempRunner.fetchHelpers
+	r.fetchCache = tempRunner.fetchCache
+	r.loopback = tempRunner.loopback
+	r.console = tempRunner.console
+	r.asyncIteration = tempRunner.asyncIteration
+	r.urlGlobals = tempRunner.urlGlobals
+	r.base64Globals = tempRunner.base64Globals
+	r.textCodecs = tempRunner.textCodecs
+	r.rejectionTracker = tempRunner.rejectionTracker
+	r.logger = loggerOrNoop(tempRunner.logger)
+	for _, name := range runnerOnlyOptions(tempRunner) {
+		r.logger.Warn("option has no effect on EventLoopRunner", "option", name)
+	}
+	if tempRunner.loopWatchdog > 0 {
+		r.watchdog = &loopWatchdog{limit: tempRunner.loopWatchdog}
+	}
+
+	for name, value := range tempRunner.initialGlobals {
+		r.globals[name] = value
+	}
+
+	if r.webAccessEnabled {
+		r.webAccessTimeout, r.httpClient = webAccessDefaults(r.webAccessTimeout, r.httpClient)
+	}
+	r.featureGlobals = features{
+		webAccess:    r.webAccessEnabled,
+		fetchHelpers: r.fetchHelpers,
+		console:      r.console,
+		urlGlobals:   r.urlGlobals,
+		base64:       r.base64Globals,
+		textCodecs:   r.textCodecs,
+	}.globals(r.fetchBytes)
+}
+
+// runnerOnlyOptions returns the names of the options set on r that only a
+// Runner supports, so NewEventLoopRunner can report that it ignores them.
+func runnerOnlyOptions(r *Runner) []string {
+	var names []string
+	for _, opt := range []struct {
+		name string
+		set  bool
+	}{
+		{"WithStrictMode", r.strictMode},
+		{"WithProgramCache", r.programCache != nil},
+		{"WithValueConverter", r.valueConverter != nil},
+		{"WithExportConverter", r.exportConverter != nil},
+		{"WithIntegerNumbers", r.integerNumbers},
+		{"WithTimeConversion", r.timeConversion},
+		{"WithFrozenGlobals", r.frozenGlobals},
+		{"WithFrozenIntrinsics", r.frozenIntrinsics},
+		{"WithRandomSeed", r.randomSeed != nil},
+		{"WithProfiler", r.profiler != nil},
+		{"WithErrorHandler", r.errorHandler != nil},
+		{"WithBoundErrorMode", r.boundErrorMode != BoundErrorThrow},
+		{"WithSynchronized", r.synchronized},
+	} {
+		if opt.set {
+			names = append(names, opt.name)
+		}
+	}
+	return names
 }
 
 // Start starts the event loop in the background.
@@ -731,6 +1609,7 @@ func (r *EventLoopRunner) SetGlobal(name string, value interface{}) {
 func (r *EventLoopRunner) Run(fn func(*goja.Runtime)) {
 	r.loop.Run(func(vm *goja.Runtime) {
 		r.setupVM(vm)
+		defer r.beginTick(vm)()
 		fn(vm)
 	})
 }
@@ -739,7 +1618,9 @@ func (r *EventLoopRunner) Run(fn func(*goja.Runtime)) {
 // Returns the result of the last expression evaluated.
 //
 // This method blocks until all asynchronous operations (promises, timeouts, intervals)
-// have completed or an error occurs.
+// have completed or an error occurs. The loop is fully drained before RunAsync returns,
+// so no timer or microtask scheduled by the code can leak into the next call. Note that
+// an interval that is never cleared keeps the loop (and therefore RunAsync) running.
 //
 // Example:
 //
@@ -763,9 +1644,44 @@ func (r *EventLoopRunner) RunAsync(code string) (goja.Value, error) {
 	var result goja.Value
 	var runErr error
 
+	atomic.AddInt64(&r.stats.asyncRuns, 1)
 	r.loop.Run(func(vm *goja.Runtime) {
 		r.setupVM(vm)
-		result, runErr = vm.RunString(code)
+		defer r.beginTick(vm)()
+		result, runErr = vm.RunString(r.prepareSource(code))
+	})
+
+	return result, runErr
+}
+
+// RunProgramAsync executes a precompiled Program on the event loop and waits
+// for all promises and timers it schedules to complete, like RunAsync. The
+// Program can be produced once with Compile and shared across runners, which
+// avoids reparsing large async bundles on every call.
+//
+// Example:
+//
+//	program, _ := jsrunner.Compile("jobs.js", source)
+//	runner := jsrunner.NewEventLoopRunner()
+//	result, err := runner.RunProgramAsync(program)
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//
+// Returns an error if p is nil or the program throws a runtime error.
+func (r *EventLoopRunner) RunProgramAsync(p *goja.Program) (goja.Value, error) {
+	if p == nil {
+		return nil, fmt.Errorf("failed to run program: program is nil")
+	}
+
+	var result goja.Value
+	var runErr error
+
+	atomic.AddInt64(&r.stats.asyncRuns, 1)
+	r.loop.Run(func(vm *goja.Runtime) {
+		r.setupVM(vm)
+		defer r.beginTick(vm)()
+		result, runErr = vm.RunProgram(p)
 	})
 
 	return result, runErr
@@ -784,10 +1700,12 @@ func (r *EventLoopRunner) RunAsyncWithTimeout(code string, timeout time.Duration
 	var runErr error
 	done := make(chan struct{})
 
+	atomic.AddInt64(&r.stats.asyncRuns, 1)
 	go func() {
 		r.loop.Run(func(vm *goja.Runtime) {
 			r.setupVM(vm)
-			result, runErr = vm.RunString(code)
+			defer r.beginTick(vm)()
+			result, runErr = vm.RunString(r.prepareSource(code))
 		})
 		close(done)
 	}()
@@ -816,72 +1734,178 @@ func (r *EventLoopRunner) RunAsyncWithTimeout(code string, timeout time.Duration
 //	        .then(response => response.json())
 //	`)
 func (r *EventLoopRunner) AwaitPromise(code string) (interface{}, error) {
-	var resolvedValue interface{}
-	var promiseErr error
-	done := make(chan struct{})
+	result := <-r.await(code, true)
+	return result.exported, result.err
+}
+
+// AwaitPromiseTimeout is like AwaitPromise but gives up after d if the promise
+// has not settled, returning a timeout error instead of blocking forever on a
+// promise that never resolves. The loop keeps running: the abandoned promise
+// may still settle later (its result is discarded), and subsequent calls work
+// normally.
+//
+// Example:
+//
+//	runner.Start()
+//	defer runner.Stop()
+//	result, err := runner.AwaitPromiseTimeout(`loadConfig()`, 2*time.Second)
+//	if err != nil {
+//	    log.Printf("config unavailable: %v", err)
+//	}
+func (r *EventLoopRunner) AwaitPromiseTimeout(code string, d time.Duration) (interface{}, error) {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case result := <-r.await(code, true):
+		return result.exported, result.err
+	case <-timer.C:
+		return nil, fmt.Errorf("promise did not settle within %v", d)
+	}
+}
+
+// AwaitValue is like AwaitPromise but returns the resolved value as a raw
+// goja.Value instead of exporting it, preserving JavaScript object identity,
+// prototypes, and functions. The value belongs to the event loop's runtime, so
+// only inspect it from code running on the loop (Run, RunOnLoop, or a timer
+// callback).
+//
+// Example:
+//
+//	runner.Start()
+//	defer runner.Stop()
+//	user, err := runner.AwaitValue(`loadUser(42)`)
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	runner.RunOnLoop(func(vm *goja.Runtime) {
+//	    city := user.ToObject(vm).Get("address").ToObject(vm).Get("city")
+//	    fmt.Println(city.String())
+//	})
+func (r *EventLoopRunner) AwaitValue(code string) (goja.Value, error) {
+	result := <-r.await(code, false)
+	return result.value, result.err
+}
+
+// InspectPromise evaluates code on the loop and immediately reports the state of
+// the promise it returns, without waiting for it to settle: "pending",
+// "fulfilled" with the exported resolution value, or "rejected" with the
+// exported rejection reason. Use it to debug async flows that never complete,
+// for example by inspecting a promise a script stored in a global. A rejected
+// promise is reported with a nil error; err is set only when code throws or
+// does not evaluate to a promise.
+//
+// Note: like AwaitPromise, the event loop must be started with Start() first.
+//
+// Example:
+//
+//	runner.Start()
+//	defer runner.Stop()
+//	runner.RunOnLoop(func(vm *goja.Runtime) {
+//	    vm.RunString(`globalThis.pendingLoad = loadConfig();`)
+//	})
+//	state, value, err := runner.InspectPromise(`pendingLoad`)
+//	log.Printf("loadConfig is %s (value %v, err %v)", state, value, err)
+func (r *EventLoopRunner) InspectPromise(code string) (state string, value interface{}, err error) {
+	type inspection struct {
+		state string
+		value interface{}
+		err   error
+	}
+	results := make(chan inspection, 1)
 
 	r.loop.RunOnLoop(func(vm *goja.Runtime) {
 		r.setupVM(vm)
+		defer r.beginTick(vm)()
 
-		// Wrap the code to capture the promise result
-		wrappedCode := fmt.Sprintf(`
-			(function() {
-				var __result = { value: undefined, error: undefined, done: false };
-				var __promise = %s;
-				if (__promise && typeof __promise.then === 'function') {
-					__promise.then(function(v) {
-						__result.value = v;
-						__result.done = true;
-					}).catch(function(e) {
-						__result.error = e;
-						__result.done = true;
-					});
-				} else {
-					__result.value = __promise;
-					__result.done = true;
-				}
-				return __result;
-			})()
-		`, code)
-
-		result, err := vm.RunString(wrappedCode)
+		result, err := vm.RunString(r.prepareSource(code))
 		if err != nil {
-			promiseErr = err
-			close(done)
+			results <- inspection{err: err}
+			return
+		}
+		promise, ok := result.Export().(*goja.Promise)
+		if !ok {
+			results <- inspection{err: fmt.Errorf("value is not a promise: %s", result.String())}
 			return
 		}
 
-		obj := result.ToObject(vm)
-
-		// Set up a check function that will be called after the event loop processes
-		var checkResult func()
-		checkResult = func() {
-			doneVal := obj.Get("done")
-			if doneVal.ToBoolean() {
-				errorVal := obj.Get("error")
-				if !goja.IsUndefined(errorVal) && !goja.IsNull(errorVal) {
-					promiseErr = fmt.Errorf("promise rejected: %v", errorVal.Export())
-				} else {
-					valueVal := obj.Get("value")
-					resolvedValue = valueVal.Export()
-				}
-				close(done)
-			} else {
-				// Check again on next tick
-				r.loop.RunOnLoop(func(vm *goja.Runtime) {
-					checkResult()
-				})
+		switch promise.State() {
+		case goja.PromiseStateFulfilled:
+			results <- inspection{state: "fulfilled", value: promise.Result().Export()}
+		case goja.PromiseStateRejected:
+			results <- inspection{state: "rejected", value: promise.Result().Export()}
+		default:
+			results <- inspection{state: "pending"}
+		}
+	})
+
+	inspected := <-results
+	return inspected.state, inspected.value, inspected.err
+}
+
+// awaitResult is the outcome of a promise awaited on the event loop.
+type awaitResult struct {
+	value    goja.Value
+	exported interface{}
+	err      error
+}
+
+// await evaluates code on the loop and delivers the settled outcome on the
+// returned channel. Thenables are settled through Go callbacks passed to their
+// then method, so waiting never busy-polls the loop. Values that are not
+// thenables are delivered immediately. When export is set the value is also
+// exported on the loop goroutine.
+func (r *EventLoopRunner) await(code string, export bool) <-chan awaitResult {
+	results := make(chan awaitResult, 1)
+
+	r.loop.RunOnLoop(func(vm *goja.Runtime) {
+		r.setupVM(vm)
+		defer r.beginTick(vm)()
+
+		var settled bool
+		settle := func(value goja.Value, err error) {
+			if settled {
+				return
 			}
+			settled = true
+			result := awaitResult{value: value, err: err}
+			if export && err == nil && value != nil {
+				result.exported = value.Export()
+			}
+			results <- result
 		}
 
-		// Start checking after the current execution
-		r.loop.RunOnLoop(func(vm *goja.Runtime) {
-			checkResult()
-		})
+		value, err := vm.RunString(r.prepareSource(code))
+		if err != nil {
+			settle(nil, err)
+			return
+		}
+
+		obj, isObject := value.(*goja.Object)
+		if !isObject {
+			settle(value, nil)
+			return
+		}
+		then, isThenable := goja.AssertFunction(obj.Get("then"))
+		if !isThenable {
+			settle(value, nil)
+			return
+		}
+
+		onFulfilled := func(call goja.FunctionCall) goja.Value {
+			settle(call.Argument(0), nil)
+			return goja.Undefined()
+		}
+		onRejected := func(call goja.FunctionCall) goja.Value {
+			settle(nil, fmt.Errorf("promise rejected: %v", call.Argument(0).Export()))
+			return goja.Undefined()
+		}
+		if _, err := then(obj, vm.ToValue(onFulfilled), vm.ToValue(onRejected)); err != nil {
+			settle(nil, err)
+		}
 	})
 
-	<-done
-	return resolvedValue, promiseErr
+	return results
 }
 
 // SetTimeout schedules a Go function to be called after the specified duration.
@@ -894,10 +1918,25 @@ func (r *EventLoopRunner) AwaitPromise(code string) (interface{}, error) {
 //	    vm.RunString("console.log('Timer fired!')")
 //	}, 1*time.Second)
 func (r *EventLoopRunner) SetTimeout(fn func(*goja.Runtime), delay time.Duration) *eventloop.Timer {
-	return r.loop.SetTimeout(func(vm *goja.Runtime) {
+	r.timersMu.Lock()
+	defer r.timersMu.Unlock()
+
+	atomic.AddInt64(&r.stats.timeoutsScheduled, 1)
+	var timer *eventloop.Timer
+	timer = r.loop.SetTimeout(func(vm *goja.Runtime) {
+		r.timersMu.Lock()
+		delete(r.timers, timer)
+		r.timersMu.Unlock()
+		atomic.AddInt64(&r.stats.timersFired, 1)
+
 		r.setupVM(vm)
+		defer r.beginTick(vm)()
 		fn(vm)
 	}, delay)
+	if timer != nil {
+		r.timers[timer] = struct{}{}
+	}
+	return timer
 }
 
 // SetInterval schedules a Go function to be called repeatedly at the specified interval.
@@ -913,10 +1952,59 @@ func (r *EventLoopRunner) SetTimeout(fn func(*goja.Runtime), delay time.Duration
 //	// Later, stop the interval
 //	runner.ClearInterval(interval)
 func (r *EventLoopRunner) SetInterval(fn func(*goja.Runtime), interval time.Duration) *eventloop.Interval {
-	return r.loop.SetInterval(func(vm *goja.Runtime) {
+	atomic.AddInt64(&r.stats.intervalsScheduled, 1)
+	i := r.loop.SetInterval(func(vm *goja.Runtime) {
+		atomic.AddInt64(&r.stats.timersFired, 1)
 		r.setupVM(vm)
+		defer r.beginTick(vm)()
+		fn(vm)
+	}, interval)
+	if i != nil {
+		r.timersMu.Lock()
+		r.intervals[i] = nil
+		r.timersMu.Unlock()
+	}
+	return i
+}
+
+// SetIntervalContext is like SetInterval but ties the interval to ctx: once ctx
+// is cancelled or its deadline passes, the interval is cleared and fn is not
+// called again. Use it for request-scoped polling so handlers cannot leak
+// intervals. The interval may still be cleared earlier with ClearInterval or
+// ClearAllTimers, which also stop watching ctx.
+//
+// Example:
+//
+//	ctx, cancel := context.WithCancel(r.Context())
+//	defer cancel()
+//	runner.SetIntervalContext(ctx, func(vm *goja.Runtime) {
+//	    vm.RunString("pollStatus()")
+//	}, time.Second)
+func (r *EventLoopRunner) SetIntervalContext(ctx context.Context, fn func(*goja.Runtime), interval time.Duration) *eventloop.Interval {
+	i := r.SetInterval(func(vm *goja.Runtime) {
+		// A tick may already be queued when ctx is cancelled.
+		if ctx.Err() != nil {
+			return
+		}
 		fn(vm)
 	}, interval)
+	if i != nil {
+		stop := context.AfterFunc(ctx, func() {
+			r.ClearInterval(i)
+		})
+		// ClearInterval stops the context watch, so a long-lived ctx does not
+		// keep a callback for every interval cleared before it ends.
+		r.timersMu.Lock()
+		_, pending := r.intervals[i]
+		if pending {
+			r.intervals[i] = stop
+		}
+		r.timersMu.Unlock()
+		if !pending {
+			stop()
+		}
+	}
+	return i
 }
 
 // ClearInterval cancels an Interval returned by SetInterval.
@@ -930,6 +2018,13 @@ func (r *EventLoopRunner) SetInterval(fn func(*goja.Runtime), interval time.Dura
 //	// ... later ...
 //	runner.ClearInterval(interval)
 func (r *EventLoopRunner) ClearInterval(i *eventloop.Interval) {
+	r.timersMu.Lock()
+	stop := r.intervals[i]
+	delete(r.intervals, i)
+	r.timersMu.Unlock()
+	if stop != nil {
+		stop()
+	}
 	r.loop.ClearInterval(i)
 }
 
@@ -944,9 +2039,44 @@ func (r *EventLoopRunner) ClearInterval(i *eventloop.Interval) {
 //	// Cancel before it fires
 //	runner.ClearTimeout(timer)
 func (r *EventLoopRunner) ClearTimeout(t *eventloop.Timer) {
+	r.timersMu.Lock()
+	delete(r.timers, t)
+	r.timersMu.Unlock()
 	r.loop.ClearTimeout(t)
 }
 
+// ClearAllTimers cancels every timeout and interval created through SetTimeout
+// and SetInterval that has not fired or been cleared yet. It simplifies
+// teardown when references to individual timers were not kept. Timers created
+// from JavaScript are not affected. It is safe to call inside or outside the
+// event loop.
+//
+// Example:
+//
+//	for _, feed := range feeds {
+//	    runner.SetInterval(pollFeed(feed), time.Minute)
+//	}
+//	// ... on shutdown ...
+//	runner.ClearAllTimers()
+func (r *EventLoopRunner) ClearAllTimers() {
+	r.timersMu.Lock()
+	timers := r.timers
+	intervals := r.intervals
+	r.timers = make(map[*eventloop.Timer]struct{})
+	r.intervals = make(map[*eventloop.Interval]func() bool)
+	r.timersMu.Unlock()
+
+	for t := range timers {
+		r.loop.ClearTimeout(t)
+	}
+	for i, stop := range intervals {
+		if stop != nil {
+			stop()
+		}
+		r.loop.ClearInterval(i)
+	}
+}
+
 // RunOnLoop schedules a Go function to be executed on the next iteration of the event loop.
 // This is useful for executing code that needs to run in the context of the event loop
 // from a different goroutine.
@@ -959,10 +2089,29 @@ func (r *EventLoopRunner) ClearTimeout(t *eventloop.Timer) {
 //	    })
 //	}()
 func (r *EventLoopRunner) RunOnLoop(fn func(*goja.Runtime)) {
-	r.loop.RunOnLoop(func(vm *goja.Runtime) {
+	atomic.AddInt64(&r.queuedJobs, 1)
+	scheduled := r.loop.RunOnLoop(func(vm *goja.Runtime) {
+		atomic.AddInt64(&r.queuedJobs, -1)
 		r.setupVM(vm)
+		defer r.beginTick(vm)()
 		fn(vm)
 	})
+	if !scheduled {
+		atomic.AddInt64(&r.queuedJobs, -1)
+	}
+}
+
+// PendingTasks returns a best-effort count of work scheduled through the Go wrappers
+// (SetTimeout, SetInterval, and RunOnLoop) that has not run or been cleared yet.
+// Active intervals count until they are cleared. Timers created from JavaScript are
+// not included; RunAsync drains those before returning.
+//
+// This is intended for diagnostics, for example asserting that a request handler
+// did not leave stray timers behind.
+func (r *EventLoopRunner) PendingTasks() int {
+	r.timersMu.Lock()
+	defer r.timersMu.Unlock()
+	return len(r.timers) + len(r.intervals) + int(atomic.LoadInt64(&r.queuedJobs))
 }
 
 // setupVM initializes the VM with globals and optional features.
@@ -970,64 +2119,25 @@ func (r *EventLoopRunner) setupVM(vm *goja.Runtime) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
-	for name, value := range r.globals {
+	for name, value := range r.featureGlobals {
 		vm.Set(name, value)
 	}
-
-	if r.webAccessEnabled {
-		r.installFetchGlobals(vm)
+	for name, value := range r.globals {
+		vm.Set(name, value)
 	}
-}
 
-func (r *EventLoopRunner) installFetchGlobals(vm *goja.Runtime) {
-	if r.webAccessTimeout <= 0 {
-		r.webAccessTimeout = defaultWebAccessTimeout
+	if r.asyncIteration {
+		installAsyncIteratorSymbol(vm)
 	}
-	if r.httpClient == nil {
-		r.httpClient = &http.Client{Timeout: r.webAccessTimeout}
-	}
-
-	vm.Set("fetchText", func(url string) (string, error) {
-		data, err := r.fetchBytes(url)
-		if err != nil {
-			return "", err
-		}
-		return string(data), nil
-	})
-
-	vm.Set("fetchJSON", func(url string) (interface{}, error) {
-		data, err := r.fetchBytes(url)
-		if err != nil {
-			return nil, err
-		}
-
-		var payload interface{}
-		if err := json.Unmarshal(data, &payload); err != nil {
-			return nil, err
-		}
-
-		return payload, nil
-	})
+	r.trackRejections(vm)
+	r.watchTimers(vm)
 }
 
-func (r *EventLoopRunner) fetchBytes(url string) ([]byte, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), r.webAccessTimeout)
-	defer cancel()
-
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
-	if err != nil {
-		return nil, err
-	}
-
-	resp, err := r.httpClient.Do(req)
-	if err != nil {
-		return nil, err
+// prepareSource applies the source rewrites enabled by options before code is
+// run on the loop.
+func (r *EventLoopRunner) prepareSource(code string) string {
+	if r.asyncIteration {
+		return lowerAsyncIteration(code)
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode >= http.StatusBadRequest {
-		return nil, fmt.Errorf("fetch request failed with status %d", resp.StatusCode)
-	}
-
-	return io.ReadAll(resp.Body)
+	return code
 }
@@ -0,0 +1,182 @@
+package jsrunner
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/dop251/goja"
+)
+
+func TestLoadScriptsAtomicSyntaxError(t *testing.T) {
+	runner := New()
+
+	err := runner.LoadScriptsAtomic([]ScriptSource{
+		{Name: "one.js", Code: `var one = 1;`},
+		{Name: "two.js", Code: `function two( {`},
+		{Name: "three.js", Code: `var three = 3;`},
+	})
+	if err == nil {
+		t.Fatal("expected LoadScriptsAtomic to fail")
+	}
+
+	result, err := runner.Eval("typeof one + ',' + typeof two + ',' + typeof three")
+	if err != nil {
+		t.Fatalf("Eval() failed: %v", err)
+	}
+	if got := ExportString(result); got != "undefined,undefined,undefined" {
+		t.Errorf("Expected no script to take effect, got %s", got)
+	}
+}
+
+func TestLoadScriptsAtomicRuntimeErrorRollsBack(t *testing.T) {
+	runner := New()
+	runner.SetGlobal("prefix", "v")
+	counter := 0
+	runner.SetGlobalGetter("tick", func() interface{} {
+		counter++
+		return counter
+	})
+	if err := runner.LoadScriptString(`var base = prefix + "1"; var calls = 0;`); err != nil {
+		t.Fatalf("LoadScriptString() failed: %v", err)
+	}
+	if _, err := runner.Eval("calls = 5"); err != nil {
+		t.Fatalf("Eval() failed: %v", err)
+	}
+
+	err := runner.LoadScriptsAtomic([]ScriptSource{
+		{Name: "one.js", Code: `var one = 1; base = "clobbered";`},
+		{Name: "two.js", Code: `throw new Error("boom");`},
+		{Name: "three.js", Code: `var three = 3;`},
+	})
+	if err == nil {
+		t.Fatal("expected LoadScriptsAtomic to fail")
+	}
+
+	result, err := runner.Eval("typeof one + ',' + typeof three + ',' + base")
+	if err != nil {
+		t.Fatalf("Eval() failed: %v", err)
+	}
+	if got := ExportString(result); got != "undefined,undefined,v1" {
+		t.Errorf("Expected failed batch to be rolled back, got %s", got)
+	}
+
+	result, err = runner.Eval("typeof tick")
+	if err != nil {
+		t.Fatalf("Eval() failed: %v", err)
+	}
+	if got := ExportString(result); got != "number" {
+		t.Errorf("Expected getter global to be restored, got typeof %s", got)
+	}
+
+	if err := runner.LoadScriptsAtomic([]ScriptSource{
+		{Name: "a.js", Code: `var a = base + "a";`},
+		{Name: "b.js", Code: `var b = a + "b";`},
+	}); err != nil {
+		t.Fatalf("LoadScriptsAtomic() failed: %v", err)
+	}
+	result, err = runner.Eval("b")
+	if err != nil {
+		t.Fatalf("Eval() failed: %v", err)
+	}
+	if got := ExportString(result); got != "v1ab" {
+		t.Errorf("Expected 'v1ab', got '%s'", got)
+	}
+}
+
+func TestLoadScriptsAtomicRollbackKeepsRuntime(t *testing.T) {
+	runner := New()
+	runs := 0
+	runner.SetGlobal("record", func() { runs++ })
+	runner.GetVM().SetFieldNameMapper(goja.UncapFieldNameMapper())
+	runner.SetGlobal("user", struct{ Name string }{Name: "Ada"})
+
+	if err := runner.LoadScriptString(`record(); function greet() { return "hi " + user.name; }`); err != nil {
+		t.Fatalf("LoadScriptString() failed: %v", err)
+	}
+
+	err := runner.LoadScriptsAtomic([]ScriptSource{
+		{Name: "one.js", Code: `function greet() { return "replaced"; } globalThis.extra = 1;`},
+		{Name: "two.js", Code: `throw new Error("boom");`},
+	})
+	if err == nil {
+		t.Fatal("expected LoadScriptsAtomic to fail")
+	}
+
+	result, err := runner.Eval(`greet() + "," + typeof extra`)
+	if err != nil {
+		t.Fatalf("Eval() failed: %v", err)
+	}
+	if got := ExportString(result); got != "hi Ada,undefined" {
+		t.Errorf("expected the batch to be rolled back with the field name mapper intact, got %s", got)
+	}
+	if runs != 1 {
+		t.Errorf("expected earlier scripts not to be re-run, got %d runs", runs)
+	}
+}
+
+func TestLoadScriptsAtomicRejectsLexicalDeclarations(t *testing.T) {
+	runner := New()
+
+	for _, code := range []string{`const limit = 1;`, `let count = 0;`, `class Model {}`} {
+		err := runner.LoadScriptsAtomic([]ScriptSource{
+			{Name: "ok.js", Code: `var ran = true;`},
+			{Name: "lexical.js", Code: code},
+		})
+		if err == nil || !strings.Contains(err.Error(), "cannot be rolled back") {
+			t.Errorf("LoadScriptsAtomic(%q) error = %v, want a rejection", code, err)
+		}
+	}
+
+	// Nothing ran, and a failed batch can be retried with the same names.
+	batch := []ScriptSource{
+		{Name: "one.js", Code: `var limit = 1; var [first, second] = [1, 2];`},
+		{Name: "two.js", Code: `if (!globalThis.ready) { throw new Error("not ready"); }`},
+	}
+	if err := runner.LoadScriptsAtomic(batch); err == nil {
+		t.Fatal("expected LoadScriptsAtomic to fail")
+	}
+	result, err := runner.Eval(`["ran", "limit", "first", "second"].filter(function(n) { return n in globalThis; }).join(",")`)
+	if err != nil {
+		t.Fatalf("Eval() failed: %v", err)
+	}
+	if got := ExportString(result); got != "" {
+		t.Errorf("expected the failed batch to leave no globals, got %q", got)
+	}
+
+	runner.SetGlobal("ready", true)
+	if err := runner.LoadScriptsAtomic(batch); err != nil {
+		t.Fatalf("LoadScriptsAtomic() retry failed: %v", err)
+	}
+	if err := runner.LoadScriptString(`let limitLabel = "limit " + limit;`); err != nil {
+		t.Fatalf("LoadScriptString() failed: %v", err)
+	}
+}
+
+func TestLoadScriptsAtomicRollsBackObjectMutations(t *testing.T) {
+	runner := New()
+	if err := runner.LoadScriptString(`var config = { mode: "prod" };`); err != nil {
+		t.Fatalf("LoadScriptString() failed: %v", err)
+	}
+
+	err := runner.LoadScriptsAtomic([]ScriptSource{
+		{Name: "patch.js", Code: `
+			Array.prototype.sum = function() { return 0; };
+			Object.prototype.injected = true;
+			String.prototype.trim = function() { return "patched"; };
+			config.mode = "dev";
+			config.debug = true;
+		`},
+		{Name: "fail.js", Code: `throw new Error("boom");`},
+	})
+	if err == nil {
+		t.Fatal("expected LoadScriptsAtomic to fail")
+	}
+
+	result, err := runner.Eval(`[typeof [].sum, typeof ({}).injected, " x ".trim(), config.mode, "debug" in config].join(",")`)
+	if err != nil {
+		t.Fatalf("Eval() failed: %v", err)
+	}
+	if got := ExportString(result); got != "undefined,undefined,x,prod,false" {
+		t.Errorf("expected object and prototype changes to be rolled back, got %s", got)
+	}
+}
@@ -0,0 +1,44 @@
+package jsrunner
+
+import "testing"
+
+func TestWithBase64Globals(t *testing.T) {
+	runner := New(WithBase64Globals())
+
+	result, err := runner.Eval(`
+		const binary = "hélloÿ\u0000";
+		const encoded = btoa(binary);
+		[encoded, atob(encoded) === binary, atob(" aGk \n"), atob("aGk=")].join("|");
+	`)
+	if err != nil {
+		t.Fatalf("Eval() failed: %v", err)
+	}
+	if want := "aOlsbG//AA==|true|hi|hi"; result.String() != want {
+		t.Errorf("result = %q, want %q", result.String(), want)
+	}
+}
+
+func TestBase64GlobalsRejectInvalidInput(t *testing.T) {
+	script := `
+		const names = [];
+		try { btoa("snow ☃"); } catch (e) { names.push(e.name); }
+		try { atob("not*base64"); } catch (e) { names.push(e.name); }
+		names.join(",");
+	`
+
+	runner := New(WithBase64Globals())
+	result, err := runner.Eval(script)
+	if err != nil {
+		t.Fatalf("Eval() failed: %v", err)
+	}
+	loop := NewEventLoopRunner(WithBase64Globals())
+	looped, err := loop.RunAsync(script)
+	if err != nil {
+		t.Fatalf("RunAsync() failed: %v", err)
+	}
+
+	want := "InvalidCharacterError,InvalidCharacterError"
+	if result.String() != want || looped.String() != want {
+		t.Errorf("errors = %q and %q, want %q", result.String(), looped.String(), want)
+	}
+}
@@ -0,0 +1,109 @@
+package jsrunner
+
+import (
+	"errors"
+	"testing"
+)
+
+var errDeclined = errors.New("card declined")
+
+func TestBoundErrorThrow(t *testing.T) {
+	runner := New()
+	runner.SetGlobal("charge", func(cents int) (string, error) {
+		return "", errDeclined
+	})
+
+	result, err := runner.Eval(`try { charge(500) } catch (e) { "caught: " + e.message }`)
+	if err != nil {
+		t.Fatalf("Eval() failed: %v", err)
+	}
+	if got := ExportString(result); got != "caught: card declined" {
+		t.Errorf("Expected the script to catch the error, got %q", got)
+	}
+
+	runner.LoadScriptString(`function pay() { return charge(100); }`)
+	if _, err := runner.Call("pay"); !errors.Is(err, errDeclined) {
+		t.Errorf("Expected an uncaught error to unwrap to errDeclined, got %v", err)
+	}
+}
+
+func TestBoundErrorReturn(t *testing.T) {
+	runner := New(WithBoundErrorMode(BoundErrorReturn))
+	runner.SetGlobal("charge", func(cents int) (string, error) {
+		if cents > 100 {
+			return "", errDeclined
+		}
+		return "ok", nil
+	})
+	runner.SetGlobal("audit", func(string) error { return nil })
+
+	result, err := runner.Eval(`var reached = false; try { charge(500); reached = true } catch (e) { "caught" } finally { reached = true }`)
+	if !errors.Is(err, errDeclined) {
+		t.Fatalf("Expected Eval to return errDeclined, got %v", err)
+	}
+	if result != nil {
+		t.Errorf("Expected a nil value, got %v", result)
+	}
+
+	reached, err := runner.Eval(`reached`)
+	if err != nil {
+		t.Fatalf("Expected the runner to stay usable, got %v", err)
+	}
+	if ExportBool(reached) {
+		t.Error("Expected the script to stop at the failing call")
+	}
+
+	runner.LoadScriptString(`function pay(cents) { audit("pay"); return charge(cents); }`)
+	value, err := runner.Call("pay", 50)
+	if err != nil {
+		t.Fatalf("Call() failed: %v", err)
+	}
+	if got := ExportString(value); got != "ok" {
+		t.Errorf("Expected ok, got %q", got)
+	}
+	if _, err := runner.Call("pay", 500); !errors.Is(err, errDeclined) {
+		t.Errorf("Expected Call to return errDeclined, got %v", err)
+	}
+}
+
+func TestBoundErrorReturnDirectCall(t *testing.T) {
+	runner := New(WithBoundErrorMode(BoundErrorReturn))
+	runner.SetGlobal("charge", func(cents int) (string, error) {
+		if cents > 100 {
+			return "", errDeclined
+		}
+		return "ok", nil
+	})
+
+	// Nothing runs after the bound function, so goja never sees the interrupt.
+	if value, err := runner.Call("charge", 500); !errors.Is(err, errDeclined) || value != nil {
+		t.Errorf("Call() = (%v, %v), want (nil, errDeclined)", value, err)
+	}
+	if _, err := runner.Eval("charge(500)"); !errors.Is(err, errDeclined) {
+		t.Errorf("Eval() = %v, want errDeclined", err)
+	}
+
+	charge, err := runner.ExportFunc("charge")
+	if err != nil {
+		t.Fatalf("ExportFunc() failed: %v", err)
+	}
+	if _, err := charge(500); !errors.Is(err, errDeclined) {
+		t.Errorf("exported charge() = %v, want errDeclined", err)
+	}
+
+	fn, err := runner.Eval("charge")
+	if err != nil {
+		t.Fatalf("Eval() failed: %v", err)
+	}
+	if _, err := runner.CallOn(nil, fn, 500); !errors.Is(err, errDeclined) {
+		t.Errorf("CallOn() = %v, want errDeclined", err)
+	}
+
+	value, err := runner.Call("charge", 50)
+	if err != nil {
+		t.Fatalf("Expected the runner to stay usable, got %v", err)
+	}
+	if got := ExportString(value); got != "ok" {
+		t.Errorf("Expected ok, got %q", got)
+	}
+}
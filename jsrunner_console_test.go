@@ -0,0 +1,99 @@
+package jsrunner
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestEvalCapture(t *testing.T) {
+	runner := New()
+
+	value, logs, err := runner.EvalCapture(`
+		console.log("first", 1);
+		console.warn("second");
+		console.log({a: 1}, [1, 2]);
+		"done";
+	`)
+	if err != nil {
+		t.Fatalf("EvalCapture() failed: %v", err)
+	}
+	if got := ExportString(value); got != "done" {
+		t.Errorf("Expected 'done', got '%s'", got)
+	}
+
+	want := []LogEntry{
+		{Level: "log", Message: "first 1"},
+		{Level: "warn", Message: "second"},
+		{Level: "log", Message: `{"a":1} [1,2]`},
+	}
+	if len(logs) != len(want) {
+		t.Fatalf("Expected %d entries, got %d: %v", len(want), len(logs), logs)
+	}
+	for i := range want {
+		if logs[i] != want[i] {
+			t.Errorf("entry %d: expected %+v, got %+v", i, want[i], logs[i])
+		}
+	}
+
+	result, err := runner.Eval("typeof console")
+	if err != nil {
+		t.Fatalf("Eval() failed: %v", err)
+	}
+	if got := ExportString(result); got != "undefined" {
+		t.Errorf("Expected capturing console to be removed, got typeof %s", got)
+	}
+}
+
+func TestEvalCaptureRestoresConsole(t *testing.T) {
+	runner := New()
+	if err := runner.LoadScriptString(`var console = { marker: true, log: function() {} };`); err != nil {
+		t.Fatalf("LoadScriptString() failed: %v", err)
+	}
+
+	_, logs, err := runner.EvalCapture(`console.error("boom"); throw new Error("fail");`)
+	if err == nil {
+		t.Fatal("expected EvalCapture to return the script error")
+	}
+	if len(logs) != 1 || logs[0].Level != "error" || logs[0].Message != "boom" {
+		t.Errorf("Expected logs captured before the error, got %v", logs)
+	}
+
+	result, err := runner.Eval("console.marker === true")
+	if err != nil {
+		t.Fatalf("Eval() failed: %v", err)
+	}
+	if !ExportBool(result) {
+		t.Error("expected previous console to be restored")
+	}
+}
+
+func TestWithConsoleInBothRunners(t *testing.T) {
+	script := `console.log("hello", { n: 1 }); console.error("boom"); typeof fetchText`
+
+	var plain bytes.Buffer
+	runner := New(WithConsole(&plain), WithWebAccess(nil))
+	result, err := runner.Eval(script)
+	if err != nil {
+		t.Fatalf("Eval failed: %v", err)
+	}
+	runnerHelper := ExportString(result)
+
+	var looped bytes.Buffer
+	loop := NewEventLoopRunner(WithConsole(&looped), WithWebAccess(nil))
+	result, err = loop.RunAsync(script)
+	if err != nil {
+		t.Fatalf("RunAsync failed: %v", err)
+	}
+	loopHelper := ExportString(result)
+
+	want := "hello {\"n\":1}\nboom\n"
+	if plain.String() != want {
+		t.Errorf("Runner console output = %q, want %q", plain.String(), want)
+	}
+	if looped.String() != want {
+		t.Errorf("EventLoopRunner console output = %q, want %q", looped.String(), want)
+	}
+	if runnerHelper != "function" || loopHelper != "function" {
+		t.Errorf("Expected fetch helpers in both runners, got %s and %s", runnerHelper, loopHelper)
+	}
+}
@@ -0,0 +1,174 @@
+package jsrunner
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dop251/goja"
+)
+
+type contextKey string
+
+func TestCallContextExposesDeadline(t *testing.T) {
+	runner := New()
+
+	var sawDeadline time.Time
+	var sawValue interface{}
+	runner.SetGlobal("inspect", func() bool {
+		ctx := RunnerContext(runner)
+		deadline, ok := ctx.Deadline()
+		sawDeadline = deadline
+		sawValue = ctx.Value(contextKey("requestID"))
+		return ok
+	})
+	if err := runner.LoadScriptString(`function handle() { return inspect(); }`); err != nil {
+		t.Fatalf("LoadScriptString() failed: %v", err)
+	}
+
+	deadline := time.Now().Add(time.Minute)
+	ctx, cancel := context.WithDeadline(context.WithValue(context.Background(), contextKey("requestID"), "req-1"), deadline)
+	defer cancel()
+
+	result, err := runner.CallContext(ctx, "handle")
+	if err != nil {
+		t.Fatalf("CallContext() failed: %v", err)
+	}
+	if !ExportBool(result) {
+		t.Fatal("expected bound function to observe a deadline")
+	}
+	if !sawDeadline.Equal(deadline) {
+		t.Errorf("Expected deadline %v, got %v", deadline, sawDeadline)
+	}
+	if sawValue != "req-1" {
+		t.Errorf("Expected request ID 'req-1', got %v", sawValue)
+	}
+
+	result, err = runner.EvalContext(ctx, "inspect()")
+	if err != nil {
+		t.Fatalf("EvalContext() failed: %v", err)
+	}
+	if !ExportBool(result) {
+		t.Error("expected EvalContext to expose the deadline")
+	}
+
+	if RunnerContext(runner) != context.Background() {
+		t.Error("expected RunnerContext to reset after the call")
+	}
+}
+
+func TestEvalContextInterruptsOnCancel(t *testing.T) {
+	runner := New()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	_, err := runner.EvalContext(ctx, "while (true) {}")
+	if err == nil {
+		t.Fatal("expected EvalContext to be interrupted")
+	}
+	var interrupted *goja.InterruptedError
+	if !errors.As(err, &interrupted) {
+		t.Errorf("Expected *goja.InterruptedError, got %T: %v", err, err)
+	}
+
+	result, err := runner.Eval("1 + 1")
+	if err != nil {
+		t.Fatalf("Eval() after interrupt failed: %v", err)
+	}
+	if ExportInt(result) != 2 {
+		t.Errorf("Expected 2, got %d", ExportInt(result))
+	}
+}
+
+func TestEvalTimeout(t *testing.T) {
+	runner := New()
+
+	result, err := runner.EvalTimeout("6 * 7", time.Second)
+	if err != nil {
+		t.Fatalf("EvalTimeout() failed: %v", err)
+	}
+	if ExportInt(result) != 42 {
+		t.Errorf("Expected 42, got %d", ExportInt(result))
+	}
+
+	start := time.Now()
+	_, err = runner.EvalTimeout("while (true) {}", 20*time.Millisecond)
+	if !errors.Is(err, ErrTimeout) {
+		t.Fatalf("Expected ErrTimeout, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected the loop to stop near the timeout, took %v", elapsed)
+	}
+
+	if _, err := runner.Eval("1 + 1"); err != nil {
+		t.Fatalf("Eval() after timeout failed: %v", err)
+	}
+}
+
+func TestCallTimeout(t *testing.T) {
+	runner := New()
+	runner.LoadScriptString(`
+		function add(a, b) { return a + b; }
+		function spin() { while (true) {} }
+	`)
+
+	result, err := runner.CallTimeout("add", time.Second, 2, 3)
+	if err != nil {
+		t.Fatalf("CallTimeout() failed: %v", err)
+	}
+	if ExportInt(result) != 5 {
+		t.Errorf("Expected 5, got %d", ExportInt(result))
+	}
+
+	_, err = runner.CallTimeout("spin", 20*time.Millisecond)
+	if !errors.Is(err, ErrTimeout) {
+		t.Fatalf("Expected ErrTimeout, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "20ms") {
+		t.Errorf("Expected the limit in the error, got %q", err.Error())
+	}
+
+	result, err = runner.Call("add", 1, 1)
+	if err != nil || ExportInt(result) != 2 {
+		t.Fatalf("Call() after timeout = %v, %v", result, err)
+	}
+}
+
+func TestPing(t *testing.T) {
+	runner := New()
+	if err := runner.Ping(); err != nil {
+		t.Fatalf("Ping on fresh runner failed: %v", err)
+	}
+
+	runner.GetVM().Interrupt("wedged")
+	if err := runner.Ping(); err == nil {
+		t.Fatal("expected Ping to fail on an interrupted runner")
+	}
+
+	busy := New(WithSynchronized())
+	busy.Lock()
+	defer busy.Unlock()
+	start := time.Now()
+	if err := busy.Ping(); err == nil {
+		t.Fatal("expected Ping to fail while the runner is held")
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Ping took %v, expected it to give up promptly", elapsed)
+	}
+
+	// Further timed-out Pings reuse the probe still waiting for the lock
+	// instead of leaving another goroutine behind each time.
+	before := runtime.NumGoroutine()
+	for i := 0; i < 2; i++ {
+		if err := busy.Ping(); err == nil {
+			t.Fatal("expected Ping to fail while the runner is held")
+		}
+	}
+	if after := runtime.NumGoroutine(); after > before {
+		t.Errorf("goroutines grew from %d to %d across timed-out Pings", before, after)
+	}
+}
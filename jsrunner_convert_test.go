@@ -0,0 +1,545 @@
+package jsrunner
+
+import (
+	"bytes"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dop251/goja"
+)
+
+type testMoney struct {
+	cents    int64
+	currency string
+}
+
+func TestWithValueConverter(t *testing.T) {
+	var runner *Runner
+	runner = New(WithValueConverter(func(v interface{}) (goja.Value, bool) {
+		m, ok := v.(testMoney)
+		if !ok {
+			return nil, false
+		}
+		return runner.GetVM().ToValue(map[string]interface{}{
+			"amount":   float64(m.cents) / 100,
+			"currency": m.currency,
+		}), true
+	}))
+
+	runner.SetGlobal("price", testMoney{cents: 1999, currency: "EUR"})
+	result, err := runner.Eval("price.amount + ' ' + price.currency")
+	if err != nil {
+		t.Fatalf("Eval() failed: %v", err)
+	}
+	if got := ExportString(result); got != "19.99 EUR" {
+		t.Errorf("Expected '19.99 EUR', got '%s'", got)
+	}
+
+	if err := runner.LoadScriptString(`function describe(m, n) { return m.currency + ":" + m.amount + ":" + n; }`); err != nil {
+		t.Fatalf("LoadScriptString() failed: %v", err)
+	}
+	result, err = runner.Call("describe", testMoney{cents: 250, currency: "USD"}, 7)
+	if err != nil {
+		t.Fatalf("Call() failed: %v", err)
+	}
+	if got := ExportString(result); got != "USD:2.5:7" {
+		t.Errorf("Expected 'USD:2.5:7', got '%s'", got)
+	}
+}
+
+func TestCallDottedName(t *testing.T) {
+	runner := New()
+
+	result, err := runner.Call("JSON.stringify", map[string]interface{}{"a": 1})
+	if err != nil {
+		t.Fatalf("Call() failed: %v", err)
+	}
+	if got := ExportString(result); got != `{"a":1}` {
+		t.Errorf(`Expected '{"a":1}', got '%s'`, got)
+	}
+
+	if _, err := runner.Call("JSON.missing"); err == nil {
+		t.Error("expected error calling undefined method")
+	}
+	if _, err := runner.Call("Math.PI"); err == nil {
+		t.Error("expected error calling non-function")
+	}
+}
+
+func TestCallOn(t *testing.T) {
+	runner := New()
+
+	join, err := runner.Eval("Array.prototype.join")
+	if err != nil {
+		t.Fatalf("Eval() failed: %v", err)
+	}
+	arr, err := runner.Eval("['a', 'b', 'c']")
+	if err != nil {
+		t.Fatalf("Eval() failed: %v", err)
+	}
+
+	result, err := runner.CallOn(arr, join, "-")
+	if err != nil {
+		t.Fatalf("CallOn() failed: %v", err)
+	}
+	if got := ExportString(result); got != "a-b-c" {
+		t.Errorf("Expected 'a-b-c', got '%s'", got)
+	}
+
+	if _, err := runner.CallOn(arr, arr); err == nil {
+		t.Error("expected error calling non-function")
+	}
+}
+
+func TestWithExportConverter(t *testing.T) {
+	runner := New(WithExportConverter(func(val goja.Value) (interface{}, bool) {
+		if !goja.IsBigInt(val) {
+			return nil, false
+		}
+		n, ok := new(big.Int).SetString(val.String(), 10)
+		return n, ok
+	}))
+
+	result, err := runner.Eval("123456789012345678901234567890n")
+	if err != nil {
+		t.Fatalf("Eval() failed: %v", err)
+	}
+	n, ok := ExportWith(runner, result).(*big.Int)
+	if !ok {
+		t.Fatalf("Expected *big.Int, got %T", ExportWith(runner, result))
+	}
+	want, _ := new(big.Int).SetString("123456789012345678901234567890", 10)
+	if n.Cmp(want) != 0 {
+		t.Errorf("Expected %s, got %s", want, n)
+	}
+
+	result, err = runner.Eval("'plain'")
+	if err != nil {
+		t.Fatalf("Eval() failed: %v", err)
+	}
+	if got := ExportWith(runner, result); got != "plain" {
+		t.Errorf("Expected fallback export 'plain', got %v", got)
+	}
+}
+
+func TestRegisterClassExporter(t *testing.T) {
+	runner := New()
+	runner.RegisterClassExporter("Money", func(val goja.Value) interface{} {
+		obj := val.ToObject(runner.GetVM())
+		return testMoney{cents: obj.Get("cents").ToInteger(), currency: obj.Get("currency").String()}
+	})
+	if err := runner.LoadScriptString(`
+		class Money {
+			constructor(cents, currency) { this.cents = cents; this.currency = currency; }
+		}
+		class Point { constructor(x) { this.x = x; } }
+	`); err != nil {
+		t.Fatalf("LoadScriptString() failed: %v", err)
+	}
+
+	result, err := runner.Eval(`new Money(1250, "EUR")`)
+	if err != nil {
+		t.Fatalf("Eval() failed: %v", err)
+	}
+	money, ok := ExportWith(runner, result).(testMoney)
+	if !ok {
+		t.Fatalf("Expected testMoney, got %T", ExportWith(runner, result))
+	}
+	if money != (testMoney{cents: 1250, currency: "EUR"}) {
+		t.Errorf("Unexpected money: %+v", money)
+	}
+
+	result, err = runner.Eval(`new Point(3)`)
+	if err != nil {
+		t.Fatalf("Eval() failed: %v", err)
+	}
+	point, ok := ExportWith(runner, result).(map[string]interface{})
+	if !ok || point["x"] != int64(3) {
+		t.Errorf("Expected default export for other classes, got %#v", ExportWith(runner, result))
+	}
+
+	// A throwing constructor getter or Proxy trap falls back to the default
+	// export instead of panicking.
+	for _, expr := range []string{
+		`Object.create({ get constructor() { throw new Error("no constructor"); } }, { x: { value: 4, enumerable: true } })`,
+		`new Proxy({ x: 4 }, { get(target, key) { if (key === "constructor") throw new Error("trap"); return target[key]; } })`,
+	} {
+		result, err = runner.Eval(expr)
+		if err != nil {
+			t.Fatalf("Eval() failed: %v", err)
+		}
+		if _, isMoney := ExportWith(runner, result).(testMoney); isMoney {
+			t.Errorf("Expected %s not to use the Money exporter", expr)
+		}
+	}
+	if result, err := runner.Eval(`new Money(5, "USD").cents`); err != nil || ExportInt(result) != 5 {
+		t.Errorf("Expected the runtime to stay usable, got %v, %v", result, err)
+	}
+}
+
+func TestBytesInterop(t *testing.T) {
+	runner := New()
+	if err := runner.LoadScriptString(`
+		function reverseBytes(input) {
+			var out = new Uint8Array(input.length);
+			for (var i = 0; i < input.length; i++) {
+				out[i] = input[input.length - 1 - i];
+			}
+			return out;
+		}
+	`); err != nil {
+		t.Fatalf("LoadScriptString() failed: %v", err)
+	}
+
+	input, err := runner.NewUint8Array([]byte{1, 2, 3, 250})
+	if err != nil {
+		t.Fatalf("NewUint8Array() failed: %v", err)
+	}
+	result, err := runner.Call("reverseBytes", input)
+	if err != nil {
+		t.Fatalf("Call() failed: %v", err)
+	}
+	data, ok := ExportBytes(result)
+	if !ok {
+		t.Fatalf("ExportBytes() did not detect Uint8Array, got %T", Export(result))
+	}
+	if !bytes.Equal(data, []byte{250, 3, 2, 1}) {
+		t.Errorf("Expected [250 3 2 1], got %v", data)
+	}
+
+	result, err = runner.Eval("new Uint8Array([7, 8]).buffer")
+	if err != nil {
+		t.Fatalf("Eval() failed: %v", err)
+	}
+	data, ok = ExportBytes(result)
+	if !ok || !bytes.Equal(data, []byte{7, 8}) {
+		t.Errorf("Expected ArrayBuffer bytes [7 8], got %v (ok=%v)", data, ok)
+	}
+
+	result, err = runner.Eval("[1, 2]")
+	if err != nil {
+		t.Fatalf("Eval() failed: %v", err)
+	}
+	if _, ok := ExportBytes(result); ok {
+		t.Error("ExportBytes() should reject plain arrays")
+	}
+
+	// Without the Uint8Array constructor there is no typed array to return.
+	codecs := New(WithTextCodecs())
+	if _, err := codecs.Eval(`delete globalThis.Uint8Array`); err != nil {
+		t.Fatalf("Eval() failed: %v", err)
+	}
+	if _, err := codecs.NewUint8Array([]byte{1}); err == nil {
+		t.Error("expected NewUint8Array to fail without the Uint8Array constructor")
+	}
+	if _, err := codecs.Eval(`new TextEncoder().encode("hi")`); err == nil || !strings.Contains(err.Error(), "Uint8Array") {
+		t.Errorf("expected TextEncoder.encode to throw without Uint8Array, got %v", err)
+	}
+}
+
+func TestWithTimeConversion(t *testing.T) {
+	runner := New(WithTimeConversion())
+
+	runner.SetGlobal("timeout", 1500*time.Millisecond)
+	result, err := runner.Eval("typeof timeout === 'number' ? timeout : -1")
+	if err != nil {
+		t.Fatalf("Eval() failed: %v", err)
+	}
+	if got := ExportFloat(result); got != 1500 {
+		t.Errorf("Expected 1500ms, got %v", got)
+	}
+
+	stamp := time.Date(2024, time.March, 5, 10, 30, 0, 0, time.UTC)
+	runner.SetGlobal("stamp", stamp)
+	result, err = runner.Eval("stamp instanceof Date && stamp.toISOString()")
+	if err != nil {
+		t.Fatalf("Eval() failed: %v", err)
+	}
+	if got := ExportString(result); got != "2024-03-05T10:30:00.000Z" {
+		t.Errorf("Expected ISO date, got '%s'", got)
+	}
+
+	if err := runner.LoadScriptString(`function year(d) { return d.getUTCFullYear(); }`); err != nil {
+		t.Fatalf("LoadScriptString() failed: %v", err)
+	}
+	result, err = runner.Call("year", stamp)
+	if err != nil {
+		t.Fatalf("Call() failed: %v", err)
+	}
+	if ExportInt(result) != 2024 {
+		t.Errorf("Expected 2024, got %d", ExportInt(result))
+	}
+
+	plain := New()
+	plain.SetGlobal("timeout", 1500*time.Millisecond)
+	result, err = plain.Eval("timeout")
+	if err != nil {
+		t.Fatalf("Eval() failed: %v", err)
+	}
+	if ExportInt(result) != int64(1500*time.Millisecond) {
+		t.Errorf("Expected default conversion to keep nanoseconds, got %d", ExportInt(result))
+	}
+}
+
+func TestTimeConversionCyclicContainers(t *testing.T) {
+	runner := New(WithTimeConversion())
+
+	// Containers are copied while time conversion is on; a container that
+	// holds itself must become a cyclic object rather than recurse forever.
+	node := map[string]interface{}{"timeout": time.Second}
+	node["self"] = node
+	list := []interface{}{"head", nil}
+	list[1] = list
+	runner.SetGlobal("node", node)
+	runner.SetGlobal("list", list)
+
+	result, err := runner.Eval("node.self === node && node.self.timeout === 1000 && list[1] === list && list[1][0]")
+	if err != nil {
+		t.Fatalf("Eval() failed: %v", err)
+	}
+	if got := ExportString(result); got != "head" {
+		t.Errorf("Expected cyclic containers to refer to themselves, got %v", result)
+	}
+
+	// The copy is a snapshot: script changes do not reach the Go map.
+	if _, err := runner.Eval("node.added = true"); err != nil {
+		t.Fatalf("Eval() failed: %v", err)
+	}
+	if _, ok := node["added"]; ok {
+		t.Error("expected the Go map to be copied, not wrapped")
+	}
+}
+
+func TestConstruct(t *testing.T) {
+	runner := New()
+	err := runner.LoadScriptString(`
+		class Point {
+			constructor(x, y) {
+				this.x = x;
+				this.y = y;
+			}
+			length() {
+				return Math.sqrt(this.x * this.x + this.y * this.y);
+			}
+		}
+	`)
+	if err != nil {
+		t.Fatalf("LoadScriptString() failed: %v", err)
+	}
+
+	point, err := runner.Construct("Point", 3, 4)
+	if err != nil {
+		t.Fatalf("Construct() failed: %v", err)
+	}
+	obj := point.ToObject(runner.GetVM())
+	if got := ExportInt(obj.Get("x")); got != 3 {
+		t.Errorf("Expected x=3, got %d", got)
+	}
+
+	length, err := runner.CallOn(point, obj.Get("length"))
+	if err != nil {
+		t.Fatalf("CallOn() failed: %v", err)
+	}
+	if got := ExportFloat(length); got != 5 {
+		t.Errorf("Expected length 5, got %v", got)
+	}
+
+	if _, err := runner.Construct("Missing"); err == nil {
+		t.Error("expected error constructing undefined class")
+	}
+	if _, err := runner.Construct("Math.max"); err == nil {
+		t.Error("expected error constructing non-constructor")
+	}
+}
+
+func TestFunctionArity(t *testing.T) {
+	runner := New()
+	err := runner.LoadScriptString(`
+		function none() {}
+		function one(a) {}
+		function three(a, b, c) {}
+		function withRest(a, ...rest) {}
+		var notFn = 42;
+		var utils = { pair: function(x, y) {} };
+	`)
+	if err != nil {
+		t.Fatalf("LoadScriptString() failed: %v", err)
+	}
+
+	cases := []struct {
+		name  string
+		arity int
+		ok    bool
+	}{
+		{"none", 0, true},
+		{"one", 1, true},
+		{"three", 3, true},
+		{"withRest", 1, true},
+		{"utils.pair", 2, true},
+		{"notFn", 0, false},
+		{"missing", 0, false},
+	}
+	for _, tc := range cases {
+		arity, ok := runner.FunctionArity(tc.name)
+		if arity != tc.arity || ok != tc.ok {
+			t.Errorf("FunctionArity(%q) = (%d, %v), want (%d, %v)", tc.name, arity, ok, tc.arity, tc.ok)
+		}
+	}
+}
+
+func TestWithIntegerNumbers(t *testing.T) {
+	runner := New(WithIntegerNumbers())
+
+	result, err := runner.Eval(`JSON.parse('{"id": 9007199254740991, "ratio": 0.5, "tags": [1, 2.5]}')`)
+	if err != nil {
+		t.Fatalf("Eval() failed: %v", err)
+	}
+	obj, ok := ExportWith(runner, result).(map[string]interface{})
+	if !ok {
+		t.Fatalf("Expected map, got %T", ExportWith(runner, result))
+	}
+	if id, ok := obj["id"].(int64); !ok || id != 9007199254740991 {
+		t.Errorf("Expected int64 9007199254740991, got %T %v", obj["id"], obj["id"])
+	}
+	if ratio, ok := obj["ratio"].(float64); !ok || ratio != 0.5 {
+		t.Errorf("Expected float64 0.5, got %T %v", obj["ratio"], obj["ratio"])
+	}
+	tags := obj["tags"].([]interface{})
+	if _, ok := tags[0].(int64); !ok {
+		t.Errorf("Expected nested integer to be int64, got %T", tags[0])
+	}
+	if _, ok := tags[1].(float64); !ok {
+		t.Errorf("Expected nested fraction to stay float64, got %T", tags[1])
+	}
+
+	// Maps and slices handed in from Go are exported as themselves; converting
+	// them must not rewrite the caller's data.
+	settings := map[string]interface{}{"limit": float64(10), "steps": []interface{}{float64(1)}}
+	runner.SetGlobal("settings", settings)
+	result, err = runner.Eval("settings")
+	if err != nil {
+		t.Fatalf("Eval() failed: %v", err)
+	}
+	converted := ExportWith(runner, result).(map[string]interface{})
+	if _, ok := converted["limit"].(int64); !ok {
+		t.Errorf("Expected converted limit to be int64, got %T", converted["limit"])
+	}
+	if _, ok := settings["limit"].(float64); !ok {
+		t.Errorf("Expected caller's map to keep float64, got %T", settings["limit"])
+	}
+	if _, ok := settings["steps"].([]interface{})[0].(float64); !ok {
+		t.Errorf("Expected caller's slice to keep float64, got %T", settings["steps"].([]interface{})[0])
+	}
+
+	// A self-referencing object must not recurse forever.
+	result, err = runner.Eval("var o = {n: 1}; o.self = o; o")
+	if err != nil {
+		t.Fatalf("Eval() failed: %v", err)
+	}
+	if _, ok := ExportWith(runner, result).(map[string]interface{}); !ok {
+		t.Errorf("Expected cyclic object to export as a map, got %T", ExportWith(runner, result))
+	}
+	if _, err := runner.EvalExports("o"); err == nil || !strings.Contains(err.Error(), "cycle") {
+		t.Errorf("EvalExports() error = %v, want a cycle error", err)
+	}
+
+	result, err = runner.Eval("2 ** 53")
+	if err != nil {
+		t.Fatalf("Eval() failed: %v", err)
+	}
+	if got, ok := ExportNumber(result).(int64); !ok || got != 1<<53 {
+		t.Errorf("Expected int64 %d, got %T %v", int64(1<<53), ExportNumber(result), ExportNumber(result))
+	}
+
+	result, err = runner.Eval("1e300")
+	if err != nil {
+		t.Fatalf("Eval() failed: %v", err)
+	}
+	if _, ok := ExportNumber(result).(float64); !ok {
+		t.Errorf("Expected out-of-range number to stay float64, got %T", ExportNumber(result))
+	}
+}
+
+func TestDefinedFunctions(t *testing.T) {
+	runner := New()
+	err := runner.LoadScriptString(`
+		function zeta() {}
+		function alpha(a) {}
+		var mid = function() {};
+		var version = 3;
+	`)
+	if err != nil {
+		t.Fatalf("LoadScriptString() failed: %v", err)
+	}
+
+	got := runner.DefinedFunctions()
+	want := []string{"alpha", "mid", "zeta"}
+	if len(got) != len(want) {
+		t.Fatalf("Expected %v, got %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Expected %v, got %v", want, got)
+			break
+		}
+	}
+}
+
+func TestCallNestedArguments(t *testing.T) {
+	describe := `function describe(v) {
+		if (Array.isArray(v)) { return "[" + v.map(describe).join(",") + "]"; }
+		if (v instanceof Date) { return "date"; }
+		if (v !== null && typeof v === "object") {
+			return "{" + Object.keys(v).sort().map(function(k) { return k + ":" + describe(v[k]); }).join(",") + "}";
+		}
+		return typeof v;
+	}`
+	orders := []map[string]interface{}{
+		{"id": 1, "lines": []map[string]interface{}{{"sku": "a", "tags": []string{"x", "y"}}}},
+		{"id": 2, "lines": []interface{}{}},
+	}
+	want := "[{id:number,lines:[{sku:string,tags:[string,string]}]},{id:number,lines:[]}]"
+
+	runner := New()
+	if err := runner.LoadScriptString(describe); err != nil {
+		t.Fatalf("LoadScriptString() failed: %v", err)
+	}
+	result, err := runner.Call("describe", orders)
+	if err != nil {
+		t.Fatalf("Call() failed: %v", err)
+	}
+	if got := ExportString(result); got != want {
+		t.Errorf("describe() = %s, want %s", got, want)
+	}
+
+	// Regression: a top-level object argument must arrive as an object, not
+	// as the string "[object Object]".
+	if err := runner.LoadScriptString(`function inspect(o) { return typeof o + ":" + o.name + ":" + JSON.stringify(o.items); }`); err != nil {
+		t.Fatalf("LoadScriptString() failed: %v", err)
+	}
+	result, err = runner.Call("inspect", map[string]interface{}{"name": "cart", "items": []int{1, 2}})
+	if err != nil {
+		t.Fatalf("Call() failed: %v", err)
+	}
+	if got := ExportString(result); got != "object:cart:[1,2]" {
+		t.Errorf("inspect() = %s, want object:cart:[1,2]", got)
+	}
+
+	// Converters apply at every level, not just to top-level arguments.
+	converted := New(WithTimeConversion())
+	if err := converted.LoadScriptString(describe); err != nil {
+		t.Fatalf("LoadScriptString() failed: %v", err)
+	}
+	stamp := time.Date(2024, time.March, 5, 0, 0, 0, 0, time.UTC)
+	result, err = converted.Call("describe", []map[string]interface{}{
+		{"at": stamp, "history": []interface{}{map[string]interface{}{"at": stamp}}},
+	})
+	if err != nil {
+		t.Fatalf("Call() failed: %v", err)
+	}
+	if got := ExportString(result); got != "[{at:date,history:[{at:date}]}]" {
+		t.Errorf("describe() with time conversion = %s", got)
+	}
+}
@@ -0,0 +1,61 @@
+package jsrunner
+
+import "testing"
+
+func TestWithErrorHandler(t *testing.T) {
+	type report struct {
+		op     string
+		source string
+		err    error
+	}
+	var reports []report
+
+	var runner *Runner
+	runner = New(
+		WithSynchronized(),
+		WithErrorHandler(func(op, source string, err error) {
+			reports = append(reports, report{op, source, err})
+			// The lock is released before the handler runs.
+			if _, evalErr := runner.Eval("1"); evalErr != nil {
+				t.Errorf("handler could not use the runner: %v", evalErr)
+			}
+		}),
+	)
+
+	_, err := runner.Eval("1 +")
+	if err == nil {
+		t.Fatal("expected a syntax error")
+	}
+	if len(reports) != 1 {
+		t.Fatalf("expected 1 report, got %d: %v", len(reports), reports)
+	}
+	if reports[0].op != "Eval" || reports[0].source != "1 +" {
+		t.Errorf("unexpected report: op=%q source=%q", reports[0].op, reports[0].source)
+	}
+	if reports[0].err != err {
+		t.Errorf("handler received %v, caller received %v", reports[0].err, err)
+	}
+
+	if _, err := runner.Call("missing"); err == nil {
+		t.Fatal("expected Call of an undefined function to fail")
+	}
+	if err := runner.LoadScriptString("function ("); err == nil {
+		t.Fatal("expected LoadScriptString to fail")
+	}
+	if len(reports) != 3 {
+		t.Fatalf("expected 3 reports, got %d: %v", len(reports), reports)
+	}
+	if reports[1].op != "Call" || reports[1].source != "missing" {
+		t.Errorf("unexpected Call report: op=%q source=%q", reports[1].op, reports[1].source)
+	}
+	if reports[2].op != "LoadScriptString" {
+		t.Errorf("unexpected LoadScriptString report: op=%q", reports[2].op)
+	}
+
+	if _, err := runner.Eval("2 + 2"); err != nil {
+		t.Fatalf("Eval() failed: %v", err)
+	}
+	if len(reports) != 3 {
+		t.Errorf("expected successful calls not to be reported, got %d reports", len(reports))
+	}
+}
@@ -1,11 +1,18 @@
 package jsrunner
 
 import (
+	"context"
+	"errors"
+	"io"
+	"runtime"
+	"strings"
+	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/dop251/goja"
+	"github.com/dop251/goja_nodejs/eventloop"
 )
 
 func TestNewEventLoopRunner(t *testing.T) {
@@ -89,6 +96,78 @@ func TestEventLoopRunner_SetInterval(t *testing.T) {
 	}
 }
 
+func TestEventLoopRunner_SetIntervalContext(t *testing.T) {
+	runner := NewEventLoopRunner()
+	runner.Start()
+	defer runner.Stop()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var count int32
+	ticked := make(chan struct{}, 1)
+	runner.SetIntervalContext(ctx, func(vm *goja.Runtime) {
+		if atomic.AddInt32(&count, 1) == 2 {
+			ticked <- struct{}{}
+		}
+	}, 20*time.Millisecond)
+
+	select {
+	case <-ticked:
+	case <-time.After(500 * time.Millisecond):
+		t.Fatal("Interval did not fire before cancellation")
+	}
+	cancel()
+
+	deadline := time.Now().Add(500 * time.Millisecond)
+	for runner.PendingTasks() != 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if pending := runner.PendingTasks(); pending != 0 {
+		t.Fatalf("Expected the interval to be cleared, %d tasks pending", pending)
+	}
+
+	stopped := atomic.LoadInt32(&count)
+	time.Sleep(100 * time.Millisecond)
+	if got := atomic.LoadInt32(&count); got != stopped {
+		t.Errorf("Interval kept firing after cancellation: %d calls, then %d", stopped, got)
+	}
+}
+
+// opaqueContext hides the cancelCtx underneath it, so context.AfterFunc has to
+// watch it from a goroutine of its own.
+type opaqueContext struct{ context.Context }
+
+func (opaqueContext) Value(interface{}) interface{} { return nil }
+
+func TestEventLoopRunner_SetIntervalContextClearStopsWatch(t *testing.T) {
+	runner := NewEventLoopRunner()
+	runner.Start()
+	defer runner.Stop()
+
+	parent, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ctx := opaqueContext{parent}
+
+	before := runtime.NumGoroutine()
+	for i := 0; i < 50; i++ {
+		interval := runner.SetIntervalContext(ctx, func(vm *goja.Runtime) {}, time.Hour)
+		runner.ClearInterval(interval)
+	}
+	for i := 0; i < 50; i++ {
+		runner.SetIntervalContext(ctx, func(vm *goja.Runtime) {}, time.Hour)
+	}
+	runner.ClearAllTimers()
+
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > before+5 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if after := runtime.NumGoroutine(); after > before+5 {
+		t.Errorf("Expected cleared intervals to stop watching ctx, goroutines grew from %d to %d", before, after)
+	}
+}
+
 func TestEventLoopRunner_RunAsync(t *testing.T) {
 	runner := NewEventLoopRunner()
 
@@ -191,6 +270,40 @@ func TestEventLoopRunner_AwaitPromiseRejected(t *testing.T) {
 	}
 }
 
+func TestEventLoopRunner_InspectPromise(t *testing.T) {
+	runner := NewEventLoopRunner()
+	runner.Start()
+	defer runner.Stop()
+
+	state, value, err := runner.InspectPromise(`Promise.resolve("ready")`)
+	if err != nil {
+		t.Fatalf("InspectPromise failed: %v", err)
+	}
+	if state != "fulfilled" || value != "ready" {
+		t.Errorf("Expected fulfilled with 'ready', got %s with %v", state, value)
+	}
+
+	state, value, err = runner.InspectPromise(`globalThis.stuck = new Promise(function() {}); stuck`)
+	if err != nil {
+		t.Fatalf("InspectPromise failed: %v", err)
+	}
+	if state != "pending" || value != nil {
+		t.Errorf("Expected pending with no value, got %s with %v", state, value)
+	}
+
+	state, value, err = runner.InspectPromise(`Promise.reject("boom")`)
+	if err != nil {
+		t.Fatalf("InspectPromise failed: %v", err)
+	}
+	if state != "rejected" || value != "boom" {
+		t.Errorf("Expected rejected with 'boom', got %s with %v", state, value)
+	}
+
+	if _, _, err := runner.InspectPromise(`42`); err == nil {
+		t.Error("Expected error for a non-promise value")
+	}
+}
+
 func TestEventLoopRunner_AwaitPromiseNonPromise(t *testing.T) {
 	runner := NewEventLoopRunner()
 	runner.Start()
@@ -477,3 +590,447 @@ func TestEventLoopRunner_MultipleTimeouts(t *testing.T) {
 		}
 	}
 }
+
+func TestEventLoopRunner_RunAsyncDrainsTimers(t *testing.T) {
+	runner := NewEventLoopRunner()
+
+	var mu sync.Mutex
+	var order []int
+	runner.SetGlobal("record", func(n int) {
+		mu.Lock()
+		defer mu.Unlock()
+		order = append(order, n)
+	})
+
+	_, err := runner.RunAsync(`
+		setTimeout(function() {
+			record(1);
+			setTimeout(function() {
+				record(2);
+				Promise.resolve().then(function() {
+					setTimeout(function() { record(3); }, 10);
+				});
+			}, 10);
+		}, 10);
+	`)
+	if err != nil {
+		t.Fatalf("RunAsync failed: %v", err)
+	}
+
+	mu.Lock()
+	got := append([]int(nil), order...)
+	mu.Unlock()
+	if len(got) != 3 || got[0] != 1 || got[1] != 2 || got[2] != 3 {
+		t.Errorf("Expected all chained timeouts to run before return, got %v", got)
+	}
+	if pending := runner.PendingTasks(); pending != 0 {
+		t.Errorf("Expected 0 pending tasks, got %d", pending)
+	}
+}
+
+func TestEventLoopRunner_PendingTasks(t *testing.T) {
+	runner := NewEventLoopRunner()
+
+	timer := runner.SetTimeout(func(vm *goja.Runtime) {}, time.Hour)
+	interval := runner.SetInterval(func(vm *goja.Runtime) {}, time.Hour)
+	if pending := runner.PendingTasks(); pending != 2 {
+		t.Errorf("Expected 2 pending tasks, got %d", pending)
+	}
+
+	runner.ClearTimeout(timer)
+	runner.ClearInterval(interval)
+	if pending := runner.PendingTasks(); pending != 0 {
+		t.Errorf("Expected 0 pending tasks after clearing, got %d", pending)
+	}
+
+	runner.Start()
+	defer runner.Stop()
+
+	done := make(chan struct{})
+	runner.SetTimeout(func(vm *goja.Runtime) { close(done) }, 10*time.Millisecond)
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Timeout did not fire")
+	}
+	if pending := runner.PendingTasks(); pending != 0 {
+		t.Errorf("Expected 0 pending tasks after timer fired, got %d", pending)
+	}
+}
+
+func TestEventLoopRunner_WithGlobalsOption(t *testing.T) {
+	runner := NewEventLoopRunner(WithGlobals(map[string]interface{}{"greeting": "hi"}))
+
+	result, err := runner.RunAsync("greeting + '!'")
+	if err != nil {
+		t.Fatalf("RunAsync failed: %v", err)
+	}
+	if got := ExportString(result); got != "hi!" {
+		t.Errorf("Expected 'hi!', got '%s'", got)
+	}
+}
+
+func TestEventLoopRunner_WarnsAboutRunnerOnlyOptions(t *testing.T) {
+	runnerOnly := []struct {
+		name string
+		opt  Option
+	}{
+		{"WithStrictMode", WithStrictMode()},
+		{"WithProgramCache", WithProgramCache(8)},
+		{"WithValueConverter", WithValueConverter(func(interface{}) (goja.Value, bool) { return nil, false })},
+		{"WithExportConverter", WithExportConverter(func(goja.Value) (interface{}, bool) { return nil, false })},
+		{"WithIntegerNumbers", WithIntegerNumbers()},
+		{"WithTimeConversion", WithTimeConversion()},
+		{"WithFrozenGlobals", WithFrozenGlobals()},
+		{"WithFrozenIntrinsics", WithFrozenIntrinsics()},
+		{"WithRandomSeed", WithRandomSeed(1)},
+		{"WithProfiler", WithProfiler()},
+		{"WithErrorHandler", WithErrorHandler(func(string, string, error) {})},
+		{"WithBoundErrorMode", WithBoundErrorMode(BoundErrorReturn)},
+		{"WithSynchronized", WithSynchronized()},
+	}
+	for _, tc := range runnerOnly {
+		logger := &captureLogger{}
+		NewEventLoopRunner(WithLogger(logger), tc.opt)
+		event, ok := logger.find("option has no effect on EventLoopRunner")
+		if !ok {
+			t.Errorf("%s: expected a warning that the option is ignored", tc.name)
+			continue
+		}
+		if len(event.kv) != 2 || event.kv[1] != tc.name {
+			t.Errorf("%s: warning names %v", tc.name, event.kv)
+		}
+	}
+
+	// Options the loop supports are applied silently.
+	logger := &captureLogger{}
+	NewEventLoopRunner(WithLogger(logger), WithConsole(io.Discard), WithURLGlobals(), WithTextCodecs(),
+		WithAsyncIteration(), WithLoopWatchdog(time.Second), WithGlobals(map[string]interface{}{"a": 1}))
+	if event, ok := logger.find("option has no effect on EventLoopRunner"); ok {
+		t.Errorf("unexpected warning for a supported option: %v", event.kv)
+	}
+}
+
+func TestEventLoopRunner_RunProgramAsync(t *testing.T) {
+	program, err := Compile("counter.js", `
+		var ticks = 0;
+		setTimeout(function() { ticks++; }, 5);
+		Promise.resolve().then(function() { ticks++; });
+		label + ":" + ticks;
+	`)
+	if err != nil {
+		t.Fatalf("Compile failed: %v", err)
+	}
+
+	for _, label := range []string{"first", "second"} {
+		runner := NewEventLoopRunner()
+		runner.SetGlobal("label", label)
+
+		result, err := runner.RunProgramAsync(program)
+		if err != nil {
+			t.Fatalf("RunProgramAsync failed: %v", err)
+		}
+		if got := ExportString(result); got != label+":0" {
+			t.Errorf("Expected '%s:0', got '%s'", label, got)
+		}
+
+		var ticks int64
+		runner.Run(func(vm *goja.Runtime) {
+			ticks = vm.Get("ticks").ToInteger()
+		})
+		if ticks != 2 {
+			t.Errorf("Expected timer and microtask to run on %s runner, got %d ticks", label, ticks)
+		}
+	}
+
+	if _, err := Compile("broken.js", "function ("); err == nil {
+		t.Error("expected Compile to reject invalid syntax")
+	}
+}
+
+func TestEventLoopRunner_Stats(t *testing.T) {
+	runner := NewEventLoopRunner()
+
+	if _, err := runner.RunAsync(`
+		Promise.reject(new Error("ignored"));
+		Promise.reject(new Error("handled")).catch(function() {});
+	`); err != nil {
+		t.Fatalf("RunAsync failed: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(5)
+	runner.SetTimeout(func(vm *goja.Runtime) { wg.Done() }, 5*time.Millisecond)
+	runner.SetTimeout(func(vm *goja.Runtime) { wg.Done() }, 10*time.Millisecond)
+
+	var ticks int
+	var interval *eventloop.Interval
+	interval = runner.SetInterval(func(vm *goja.Runtime) {
+		ticks++
+		if ticks == 3 {
+			runner.ClearInterval(interval)
+		}
+		wg.Done()
+	}, 5*time.Millisecond)
+
+	runner.Start()
+	defer runner.Stop()
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Timers did not fire")
+	}
+
+	want := LoopStats{
+		TimeoutsScheduled:   2,
+		IntervalsScheduled:  1,
+		TimersFired:         5,
+		AsyncRuns:           1,
+		UnhandledRejections: 1,
+	}
+	if got := runner.Stats(); got != want {
+		t.Errorf("Stats() = %+v, want %+v", got, want)
+	}
+}
+
+func TestEventLoopRunner_WithPromiseRejectionTracker(t *testing.T) {
+	var mu sync.Mutex
+	var ops []goja.PromiseRejectionOperation
+	runner := NewEventLoopRunner(WithPromiseRejectionTracker(func(_ *goja.Promise, op goja.PromiseRejectionOperation) {
+		mu.Lock()
+		ops = append(ops, op)
+		mu.Unlock()
+	}))
+
+	if _, err := runner.RunAsync(`
+		var late = Promise.reject(new Error("late"));
+		Promise.reject(new Error("never handled"));
+		setTimeout(function() { late.catch(function() {}); }, 0);
+		"ok"
+	`); err != nil {
+		t.Fatalf("RunAsync() failed: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	want := []goja.PromiseRejectionOperation{goja.PromiseRejectionReject, goja.PromiseRejectionReject, goja.PromiseRejectionHandle}
+	if len(ops) != len(want) {
+		t.Fatalf("tracker saw %v, want %v", ops, want)
+	}
+	for i := range want {
+		if ops[i] != want[i] {
+			t.Errorf("tracker saw %v, want %v", ops, want)
+			break
+		}
+	}
+	if got := runner.Stats().UnhandledRejections; got != 1 {
+		t.Errorf("UnhandledRejections = %d, want 1 alongside the user tracker", got)
+	}
+
+	var rejected int
+	plain := New(WithPromiseRejectionTracker(func(_ *goja.Promise, op goja.PromiseRejectionOperation) {
+		if op == goja.PromiseRejectionReject {
+			rejected++
+		}
+	}))
+	if _, err := plain.Eval(`Promise.reject(1); 0`); err != nil {
+		t.Fatalf("Eval() failed: %v", err)
+	}
+	if rejected != 1 {
+		t.Errorf("Runner tracker saw %d rejections, want 1", rejected)
+	}
+}
+
+func TestEventLoopRunner_AwaitValue(t *testing.T) {
+	runner := NewEventLoopRunner()
+	runner.Start()
+	defer runner.Stop()
+
+	value, err := runner.AwaitValue(`
+		class Profile { get label() { return this.city + "!"; } }
+		new Promise(function(resolve) {
+			var profile = new Profile();
+			profile.city = "Oslo";
+			setTimeout(function() { resolve({ user: { name: "Ada", profile: profile } }); }, 5);
+		})
+	`)
+	if err != nil {
+		t.Fatalf("AwaitValue failed: %v", err)
+	}
+
+	done := make(chan string)
+	runner.RunOnLoop(func(vm *goja.Runtime) {
+		profile := value.ToObject(vm).Get("user").ToObject(vm).Get("profile").ToObject(vm)
+		done <- profile.Get("label").String() + ":" + profile.Get("constructor").ToObject(vm).Get("name").String()
+	})
+	if got := <-done; got != "Oslo!:Profile" {
+		t.Errorf("Expected getter and prototype to survive, got %s", got)
+	}
+
+	if _, err := runner.AwaitValue(`Promise.reject(new Error("nope"))`); err == nil {
+		t.Error("Expected rejection error")
+	}
+
+	value, err = runner.AwaitValue(`41 + 1`)
+	if err != nil {
+		t.Fatalf("AwaitValue with plain value failed: %v", err)
+	}
+	if got := value.ToInteger(); got != 42 {
+		t.Errorf("Expected 42, got %d", got)
+	}
+}
+
+func TestEventLoopRunner_AwaitPromiseTimeout(t *testing.T) {
+	runner := NewEventLoopRunner()
+	runner.Start()
+	defer runner.Stop()
+
+	start := time.Now()
+	_, err := runner.AwaitPromiseTimeout(`new Promise(function() {})`, 50*time.Millisecond)
+	if err == nil || !strings.Contains(err.Error(), "did not settle") {
+		t.Fatalf("Expected timeout error, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Timeout took too long: %v", elapsed)
+	}
+
+	result, err := runner.AwaitPromiseTimeout(`
+		new Promise(function(resolve) { setTimeout(function() { resolve("ok"); }, 5); })
+	`, time.Second)
+	if err != nil {
+		t.Fatalf("Expected loop to remain usable after a timeout, got %v", err)
+	}
+	if result != "ok" {
+		t.Errorf("Expected 'ok', got %v", result)
+	}
+}
+
+func TestEventLoopRunner_ClearAllTimers(t *testing.T) {
+	runner := NewEventLoopRunner()
+	runner.Start()
+	defer runner.Stop()
+
+	var fired int64
+	for i := 0; i < 3; i++ {
+		runner.SetInterval(func(vm *goja.Runtime) { atomic.AddInt64(&fired, 1) }, 20*time.Millisecond)
+	}
+	runner.SetTimeout(func(vm *goja.Runtime) { atomic.AddInt64(&fired, 1) }, 20*time.Millisecond)
+
+	runner.ClearAllTimers()
+	if pending := runner.PendingTasks(); pending != 0 {
+		t.Errorf("Expected 0 pending tasks after ClearAllTimers, got %d", pending)
+	}
+
+	time.Sleep(80 * time.Millisecond)
+	if got := atomic.LoadInt64(&fired); got != 0 {
+		t.Errorf("Expected no timers to fire after ClearAllTimers, got %d", got)
+	}
+}
+
+func TestEventLoopRunner_AsyncIteration(t *testing.T) {
+	runner := NewEventLoopRunner(WithAsyncIteration())
+	runner.Start()
+	defer runner.Stop()
+
+	result, err := runner.AwaitPromise(`(async () => {
+		const delay = (value) => new Promise(resolve => setTimeout(() => resolve(value), 1));
+
+		async function* numbers() {
+			for (let i = 1; i <= 3; i++) {
+				yield await delay(i);
+			}
+		}
+
+		const collected = [];
+		for await (const n of numbers()) {
+			collected.push(n);
+		}
+
+		const custom = {
+			[Symbol.asyncIterator]() {
+				let i = 0;
+				return { next: () => Promise.resolve({ value: "c" + i, done: i++ >= 2 }) };
+			},
+		};
+		for await (const item of custom) {
+			collected.push(item);
+		}
+
+		for await (const item of [Promise.resolve("p"), "v"]) {
+			collected.push(item);
+		}
+		return collected.join(",");
+	})()`)
+	if err != nil {
+		t.Fatalf("AwaitPromise() failed: %v", err)
+	}
+	if result != "1,2,3,c0,c1,p,v" {
+		t.Errorf("expected all async values to be collected, got %v", result)
+	}
+
+	plain := NewEventLoopRunner()
+	plain.Start()
+	defer plain.Stop()
+	if _, err := plain.AwaitPromise(`(async () => { for await (const x of []) {} })()`); err == nil {
+		t.Error("expected for await to be rejected without WithAsyncIteration")
+	}
+}
+
+func TestEventLoopRunner_LoopWatchdog(t *testing.T) {
+	logger := &captureLogger{}
+	runner := NewEventLoopRunner(WithLoopWatchdog(50*time.Millisecond), WithLogger(logger))
+
+	// A timer callback that never returns is interrupted and the loop drains.
+	done := make(chan struct{})
+	var result goja.Value
+	var err error
+	go func() {
+		result, err = runner.RunAsync(`
+			var after = "pending";
+			setTimeout(function() { while (true) {} }, 0);
+			setTimeout(function() { after = "ran"; }, 10);
+			"started";
+		`)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("watchdog did not interrupt the stuck timer callback")
+	}
+	if err != nil {
+		t.Fatalf("RunAsync() failed: %v", err)
+	}
+	if got := ExportString(result); got != "started" {
+		t.Errorf("Expected 'started', got %q", got)
+	}
+	if got := runner.Stats().WatchdogInterrupts; got != 1 {
+		t.Errorf("Expected 1 watchdog interrupt, got %d", got)
+	}
+	if _, ok := logger.find("loop watchdog fired"); !ok {
+		t.Error("expected a 'loop watchdog fired' event")
+	}
+
+	after, err := runner.RunAsync(`after`)
+	if err != nil {
+		t.Fatalf("RunAsync() failed after the watchdog fired: %v", err)
+	}
+	if got := ExportString(after); got != "ran" {
+		t.Errorf("Expected later timers to keep running, got %q", got)
+	}
+
+	// A stuck tick started from Go returns the watchdog error.
+	_, err = runner.RunAsync(`while (true) {}`)
+	if !errors.Is(err, ErrLoopStalled) {
+		t.Errorf("Expected ErrLoopStalled, got %v", err)
+	}
+	if got := runner.Stats().WatchdogInterrupts; got != 2 {
+		t.Errorf("Expected 2 watchdog interrupts, got %d", got)
+	}
+}
@@ -0,0 +1,122 @@
+package jsrunner
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/dop251/goja"
+)
+
+func TestWithFrozenGlobals(t *testing.T) {
+	runner := New(
+		WithGlobals(map[string]interface{}{
+			"helper": func() string { return "host" },
+			"config": map[string]interface{}{"mode": "safe"},
+		}),
+		WithWebAccess(nil),
+		WithFrozenGlobals(),
+	)
+
+	// Sloppy-mode reassignment silently fails.
+	if err := runner.LoadScriptString(`
+		helper = function() { return "evil"; };
+		fetchText = function() { return "evil"; };
+	`); err != nil {
+		t.Fatalf("LoadScriptString() failed: %v", err)
+	}
+
+	result, err := runner.Call("helper")
+	if err != nil {
+		t.Fatalf("Call() failed: %v", err)
+	}
+	if got := ExportString(result); got != "host" {
+		t.Errorf("Expected frozen helper to return 'host', got '%s'", got)
+	}
+
+	result, err = runner.Eval("fetchText.toString().indexOf('evil') === -1")
+	if err != nil {
+		t.Fatalf("Eval() failed: %v", err)
+	}
+	if !ExportBool(result) {
+		t.Error("Expected fetchText to remain the host implementation")
+	}
+
+	// Strict-mode reassignment throws.
+	if _, err := runner.Eval(`(function() { "use strict"; helper = 1; })()`); err == nil {
+		t.Error("Expected strict-mode reassignment of a frozen global to throw")
+	}
+	if _, err := runner.Eval(`(function() { "use strict"; delete globalThis.helper; })()`); err == nil {
+		t.Error("Expected deleting a frozen global to throw")
+	}
+
+	// Globals set after construction stay writable.
+	runner.SetGlobal("perCall", 1)
+	runner.SetGlobal("perCall", 2)
+	result, err = runner.Eval("perCall")
+	if err != nil {
+		t.Fatalf("Eval() failed: %v", err)
+	}
+	if ExportInt(result) != 2 {
+		t.Errorf("Expected perCall to be 2, got %d", ExportInt(result))
+	}
+}
+
+func TestWithFrozenIntrinsics(t *testing.T) {
+	runner := New(WithFrozenIntrinsics())
+
+	// Sloppy-mode pollution silently fails.
+	result, err := runner.Eval(`Object.prototype.evil = true; Array.prototype.evil = true; [({}).evil, [].evil]`)
+	if err != nil {
+		t.Fatalf("Eval() failed: %v", err)
+	}
+	if got, _ := json.Marshal(result.Export()); string(got) != "[null,null]" {
+		t.Errorf("Expected prototypes to stay unpolluted, got %s", got)
+	}
+
+	// Strict-mode pollution throws.
+	if _, err := runner.Eval(`(function() { "use strict"; Object.prototype.evil = true; })()`); err == nil {
+		t.Error("Expected strict-mode assignment to Object.prototype to throw")
+	}
+	// Replacing a shadowable member on the intrinsic throws even in sloppy mode.
+	if _, err := runner.Eval(`Object.prototype.toString = function() { return "evil"; }`); err == nil {
+		t.Error("Expected replacing Object.prototype.toString to throw")
+	}
+	if _, err := runner.Eval(`Object.defineProperty(Object.prototype, "evil", { value: 1 })`); err == nil {
+		t.Error("Expected defineProperty on Object.prototype to throw")
+	}
+
+	result, err = runner.Eval(`Object.isFrozen(Object.prototype) && Object.isFrozen(Function.prototype) &&
+		Object.isFrozen(Object.getPrototypeOf([][Symbol.iterator]())) && Object.isFrozen(JSON)`)
+	if err != nil {
+		t.Fatalf("Eval() failed: %v", err)
+	}
+	if !ExportBool(result) {
+		t.Error("Expected intrinsics to be frozen")
+	}
+
+	// Shadowing inherited members on ordinary objects keeps working.
+	result, err = runner.Eval(`
+		class ValidationError extends Error {
+			constructor(msg) { super(msg); this.name = "ValidationError"; }
+		}
+		function Point() {}
+		Point.prototype.toString = function() { return "point"; };
+		var o = {};
+		o.toString = function() { return "own"; };
+		[new ValidationError("bad").name, String(new Point()), String(o), String({})].join(",")
+	`)
+	if err != nil {
+		t.Fatalf("Eval() failed: %v", err)
+	}
+	if got := ExportString(result); got != "ValidationError,point,own,[object Object]" {
+		t.Errorf("Expected overrides on own objects to work, got %q", got)
+	}
+
+	result, err = runner.EvalIsolated(`Object.prototype.evil = true; ({}).evil`)
+	if err != nil {
+		t.Fatalf("EvalIsolated() failed: %v", err)
+	}
+	if !goja.IsUndefined(result) {
+		t.Errorf("Expected isolated runtime to freeze intrinsics, got %v", result)
+	}
+}
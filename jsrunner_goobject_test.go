@@ -0,0 +1,81 @@
+package jsrunner
+
+import (
+	"errors"
+	"testing"
+)
+
+type testGreeter struct {
+	Prefix string
+	calls  int
+}
+
+func (g *testGreeter) Greet(name string) (string, error) {
+	g.calls++
+	if name == "" {
+		return "", errors.New("name is required")
+	}
+	return g.Prefix + name, nil
+}
+
+func (g testGreeter) Calls() int {
+	return g.calls
+}
+
+func TestSetGlobalObject(t *testing.T) {
+	runner := New()
+	greeter := &testGreeter{Prefix: "Hello, "}
+	runner.SetGlobalObject("greeter", greeter)
+
+	result, err := runner.Eval(`greeter.Greet("Ada")`)
+	if err != nil {
+		t.Fatalf("Eval() failed: %v", err)
+	}
+	if got := ExportString(result); got != "Hello, Ada" {
+		t.Errorf("Expected 'Hello, Ada', got %q", got)
+	}
+
+	result, err = runner.Eval(`try { greeter.Greet(""); "no error" } catch (e) { e.message }`)
+	if err != nil {
+		t.Fatalf("Eval() failed: %v", err)
+	}
+	if got := ExportString(result); got != "name is required" {
+		t.Errorf("Expected the Go error as the exception message, got %q", got)
+	}
+
+	if _, err := runner.Eval(`greeter.Greet("")`); err == nil {
+		t.Error("Expected an uncaught method error to fail Eval")
+	}
+
+	if _, err := runner.Eval(`greeter.Prefix = "Hi, "`); err != nil {
+		t.Fatalf("Eval() failed: %v", err)
+	}
+	if greeter.Prefix != "Hi, " {
+		t.Errorf("Expected field assignment to write through, got %q", greeter.Prefix)
+	}
+
+	result, err = runner.Eval(`greeter.Calls()`)
+	if err != nil {
+		t.Fatalf("Eval() failed: %v", err)
+	}
+	if got := ExportInt(result); got != 3 {
+		t.Errorf("Expected 3 calls, got %d", got)
+	}
+}
+
+func TestSetGlobalObjectValueReceiver(t *testing.T) {
+	runner := New()
+	greeter := testGreeter{Prefix: "Hey, "}
+	runner.SetGlobalObject("greeter", greeter)
+
+	result, err := runner.Eval(`greeter.Greet("Bob")`)
+	if err != nil {
+		t.Fatalf("Eval() failed: %v", err)
+	}
+	if got := ExportString(result); got != "Hey, Bob" {
+		t.Errorf("Expected 'Hey, Bob', got %q", got)
+	}
+	if greeter.calls != 0 {
+		t.Error("Expected a non-pointer value to be copied")
+	}
+}
@@ -0,0 +1,62 @@
+package jsrunner
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestInspect(t *testing.T) {
+	runner := New()
+	if err := runner.LoadScriptString(`
+		class User { constructor(name) { this.name = name; } }
+		function greet() {}
+		var cyclic = { id: 7 };
+		cyclic.self = cyclic;
+	`); err != nil {
+		t.Fatalf("LoadScriptString failed: %v", err)
+	}
+
+	tests := []struct {
+		expr string
+		want string
+	}{
+		{`({ id: 1, name: "Ada", tags: ["x", "y"] })`, `object { id: 1, name: 'Ada', tags: array(2) [ 'x', 'y' ] }`},
+		{`[1, 2, 3]`, `array(3) [ 1, 2, 3 ]`},
+		{`new User("Ada")`, `object User { name: 'Ada' }`},
+		{`greet`, `[Function: greet]`},
+		{`cyclic`, `object { id: 7, self: [Circular] }`},
+		{`({ a: { b: { c: { d: 1 } } } })`, `object { a: object { b: object {...} } }`},
+		{`[undefined, null, true, 10n, "it's"]`, `array(5) [ undefined, null, true, 10n, 'it\'s' ]`},
+		{`({})`, `object {}`},
+		{`Object.create({ get constructor() { throw new Error("no") } }, { a: { value: 1, enumerable: true } })`, `object { a: 1 }`},
+	}
+	for _, tt := range tests {
+		val, err := runner.Eval(tt.expr)
+		if err != nil {
+			t.Fatalf("Eval(%s) failed: %v", tt.expr, err)
+		}
+		if got := Inspect(val); got != tt.want {
+			t.Errorf("Inspect(%s) = %s, want %s", tt.expr, got, tt.want)
+		}
+	}
+
+	val, err := runner.Eval(`"x".repeat(200)`)
+	if err != nil {
+		t.Fatalf("Eval failed: %v", err)
+	}
+	if got := Inspect(val); !strings.HasSuffix(got, "…' (+120 chars)") {
+		t.Errorf("Expected truncated string, got %s", got)
+	}
+
+	val, err = runner.Eval(`Array.from({ length: 25 }, function(_, i) { return i; })`)
+	if err != nil {
+		t.Fatalf("Eval failed: %v", err)
+	}
+	if got := Inspect(val); !strings.HasPrefix(got, "array(25) [ 0, 1") || !strings.HasSuffix(got, "... 5 more items ]") {
+		t.Errorf("Expected truncated array, got %s", got)
+	}
+
+	if got := Inspect(nil); got != "undefined" {
+		t.Errorf("Inspect(nil) = %s, want undefined", got)
+	}
+}
@@ -0,0 +1,115 @@
+package jsrunner
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dop251/goja"
+)
+
+func TestEvalIsolated(t *testing.T) {
+	runner := New(WithBase64Globals())
+	runner.SetGlobal("shared", "runner value")
+
+	result, err := runner.EvalIsolated(`globalThis.leak = "first"; btoa("hi")`)
+	if err != nil {
+		t.Fatalf("EvalIsolated() failed: %v", err)
+	}
+	if got := ExportString(result); got != "aGk=" {
+		t.Errorf("Expected feature globals to be installed, got %q", got)
+	}
+
+	result, err = runner.EvalIsolated(`typeof leak + "," + typeof shared`)
+	if err != nil {
+		t.Fatalf("EvalIsolated() failed: %v", err)
+	}
+	if got := ExportString(result); got != "undefined,undefined" {
+		t.Errorf("Expected a fresh global scope, got %q", got)
+	}
+
+	result, err = runner.Eval(`typeof leak`)
+	if err != nil {
+		t.Fatalf("Eval() failed: %v", err)
+	}
+	if got := ExportString(result); got != "undefined" {
+		t.Errorf("Expected isolated globals not to reach the runner, got %q", got)
+	}
+
+	if _, err := runner.EvalIsolated(`throw new Error("boom")`); err == nil {
+		t.Error("expected thrown error to be returned")
+	}
+}
+
+func TestEvalIsolatedContext(t *testing.T) {
+	runner := New()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	_, err := runner.EvalIsolatedContext(ctx, "while (true) {}")
+	if err == nil {
+		t.Fatal("expected EvalIsolatedContext to be interrupted")
+	}
+	var interrupted *goja.InterruptedError
+	if !errors.As(err, &interrupted) {
+		t.Errorf("Expected *goja.InterruptedError, got %T: %v", err, err)
+	}
+
+	result, err := runner.EvalIsolatedContext(context.Background(), "6 * 7")
+	if err != nil {
+		t.Fatalf("EvalIsolatedContext() failed: %v", err)
+	}
+	if got := ExportInt(result); got != 42 {
+		t.Errorf("Expected 42, got %d", got)
+	}
+}
+
+// overlapWriter records whether two writes were ever in progress at once.
+type overlapWriter struct {
+	writing  atomic.Bool
+	overlaps atomic.Int32
+}
+
+func (w *overlapWriter) Write(p []byte) (int, error) {
+	if !w.writing.CompareAndSwap(false, true) {
+		w.overlaps.Add(1)
+		return len(p), nil
+	}
+	time.Sleep(100 * time.Microsecond)
+	w.writing.Store(false)
+	return len(p), nil
+}
+
+func TestEvalIsolatedSerializesConsoleWrites(t *testing.T) {
+	out := &overlapWriter{}
+	runner := New(WithConsole(out))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := runner.EvalIsolated(`for (var i = 0; i < 20; i++) console.log(i)`); err != nil {
+				t.Errorf("EvalIsolated() failed: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if n := out.overlaps.Load(); n != 0 {
+		t.Errorf("Expected console writes to be serialized, saw %d overlapping writes", n)
+	}
+}
+
+func BenchmarkEvalIsolated(b *testing.B) {
+	runner := New(WithProgramCache(16))
+	for i := 0; i < b.N; i++ {
+		if _, err := runner.EvalIsolated("var factor = 7;" + benchmarkExpression); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package jsrunner
+
+import (
+	"testing"
+)
+
+func TestWithProfiler(t *testing.T) {
+	runner := New(WithProfiler())
+	runner.SetGlobal("hostHelper", func(n int) int { return n * 2 })
+
+	if err := runner.LoadScriptString(`
+		function square(n) { return n * n; }
+		function sumSquares(n) {
+			var total = 0;
+			for (var i = 1; i <= n; i++) { total += square(i); }
+			return total;
+		}
+		function Point(x) { this.x = x; }
+		var notAFunction = 1;
+	`); err != nil {
+		t.Fatalf("LoadScriptString() failed: %v", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		if _, err := runner.Call("sumSquares", 4); err != nil {
+			t.Fatalf("Call() failed: %v", err)
+		}
+	}
+	result, err := runner.Eval(`var p = new Point(hostHelper(2)); (p instanceof Point) + "," + p.x`)
+	if err != nil {
+		t.Fatalf("Eval() failed: %v", err)
+	}
+	if got := ExportString(result); got != "true,4" {
+		t.Errorf("Expected wrapped constructor to behave normally, got %q", got)
+	}
+
+	profile := runner.Profile()
+	if got := profile["sumSquares"].Calls; got != 3 {
+		t.Errorf("Expected sumSquares to report 3 calls, got %d", got)
+	}
+	if got := profile["square"].Calls; got != 12 {
+		t.Errorf("Expected square to report 12 calls, got %d", got)
+	}
+	if got := profile["Point"].Calls; got != 1 {
+		t.Errorf("Expected Point to report 1 call, got %d", got)
+	}
+	if profile["sumSquares"].Total <= 0 {
+		t.Error("Expected sumSquares to report a positive total time")
+	}
+	for _, name := range []string{"hostHelper", "notAFunction", "Object"} {
+		if _, ok := profile[name]; ok {
+			t.Errorf("Expected %s not to be profiled", name)
+		}
+	}
+
+	if New().Profile() != nil {
+		t.Error("Expected Profile() to return nil without WithProfiler")
+	}
+}
+
+func TestProfilerSkipsGlobalGetters(t *testing.T) {
+	runner := New(WithProfiler(), WithSynchronized())
+
+	// Instrumenting must not run getters: a counting getter stays at zero and
+	// a throwing one neither fails the Eval nor leaves the runner locked.
+	if err := runner.LoadScriptString(`
+		var getterCalls = 0;
+		Object.defineProperty(globalThis, "counted", { get: function() { getterCalls++; return function() {}; } });
+		Object.defineProperty(globalThis, "broken", { get: function() { throw new Error("getter ran"); } });
+		function work() { return 1; }
+	`); err != nil {
+		t.Fatalf("LoadScriptString() failed: %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		result, err := runner.Eval("work() + getterCalls")
+		if err != nil {
+			t.Fatalf("Eval() failed: %v", err)
+		}
+		if got := ExportInt(result); got != 1 {
+			t.Errorf("Expected getters not to run, got getterCalls = %d", got-1)
+		}
+	}
+	if stat := runner.Profile()["work"]; stat.Calls != 2 {
+		t.Errorf("Expected work to be profiled with 2 calls, got %d", stat.Calls)
+	}
+}
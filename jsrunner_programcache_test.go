@@ -0,0 +1,91 @@
+package jsrunner
+
+import (
+	"testing"
+)
+
+func TestWithProgramCache(t *testing.T) {
+	runner := New(WithProgramCache(2))
+
+	for i := 0; i < 3; i++ {
+		runner.SetGlobal("x", i)
+		result, err := runner.Eval("x * 2")
+		if err != nil {
+			t.Fatalf("Eval() failed: %v", err)
+		}
+		if got := ExportInt(result); got != int64(i*2) {
+			t.Errorf("Expected %d, got %d", i*2, got)
+		}
+	}
+	if got := runner.programCache.order.Len(); got != 1 {
+		t.Errorf("Expected repeated expression to be cached once, got %d entries", got)
+	}
+
+	runner.Eval("1")
+	runner.Eval("2")
+	if got := runner.programCache.order.Len(); got != 2 {
+		t.Errorf("Expected cache to be bounded to 2 entries, got %d", got)
+	}
+	if _, ok := runner.programCache.get(programCacheKey{code: "x * 2"}); ok {
+		t.Error("expected least recently used program to be evicted")
+	}
+
+	if _, err := runner.Eval("syntax error here"); err == nil {
+		t.Error("expected syntax error to be reported")
+	}
+}
+
+func TestProgramCacheEvalBytesReusedBuffer(t *testing.T) {
+	runner := New(WithProgramCache(8))
+
+	// The cache keeps its own copy of the source, so overwriting the buffer
+	// once the program is cached leaves the cached entry intact.
+	buf := []byte("1 + 1")
+	if _, err := runner.EvalBytes(buf); err != nil {
+		t.Fatalf("EvalBytes() failed: %v", err)
+	}
+	copy(buf, "2 + 2")
+
+	if _, ok := runner.programCache.get(programCacheKey{code: "1 + 1"}); !ok {
+		t.Error("expected the program to stay cached under its original source")
+	}
+	if _, ok := runner.programCache.get(programCacheKey{code: "2 + 2"}); ok {
+		t.Error("expected overwriting the buffer not to rekey the cached program")
+	}
+	result, err := runner.Eval("1 + 1")
+	if err != nil {
+		t.Fatalf("Eval() failed: %v", err)
+	}
+	if got := ExportInt(result); got != 2 {
+		t.Errorf("cached program returned %d, want 2", got)
+	}
+}
+
+const benchmarkExpression = `
+	[1, 2, 3, 4, 5, 6, 7, 8, 9, 10]
+		.map(function(n) { return n * factor; })
+		.filter(function(n) { return n % 3 !== 0; })
+		.reduce(function(sum, n) { return sum + n; }, 0)
+`
+
+func BenchmarkEvalUncached(b *testing.B) {
+	runner := New()
+	runner.SetGlobal("factor", 7)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := runner.Eval(benchmarkExpression); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkEvalProgramCache(b *testing.B) {
+	runner := New(WithProgramCache(16))
+	runner.SetGlobal("factor", 7)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := runner.Eval(benchmarkExpression); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package jsrunner
+
+import (
+	"reflect"
+	"testing"
+)
+
+func randomSequence(t *testing.T, runner *Runner) []interface{} {
+	t.Helper()
+	result, err := runner.Eval(`[Math.random(), Math.random(), Math.random(), Math.random()]`)
+	if err != nil {
+		t.Fatalf("Eval() failed: %v", err)
+	}
+	return result.Export().([]interface{})
+}
+
+func TestWithRandomSeed(t *testing.T) {
+	first := randomSequence(t, New(WithRandomSeed(42)))
+	second := randomSequence(t, New(WithRandomSeed(42)))
+	if !reflect.DeepEqual(first, second) {
+		t.Errorf("Expected identical sequences for the same seed, got %v and %v", first, second)
+	}
+
+	other := randomSequence(t, New(WithRandomSeed(7)))
+	if reflect.DeepEqual(first, other) {
+		t.Errorf("Expected different seeds to diverge, both produced %v", first)
+	}
+
+	for _, v := range first {
+		if f := v.(float64); f < 0 || f >= 1 {
+			t.Errorf("Expected values in [0, 1), got %v", f)
+		}
+	}
+
+	runner := New(WithRandomSeed(42))
+	isolated, err := runner.EvalIsolated(`Math.random()`)
+	if err != nil {
+		t.Fatalf("EvalIsolated() failed: %v", err)
+	}
+	if got := ExportFloat(isolated); got != first[0].(float64) {
+		t.Errorf("Expected EvalIsolated to start the sequence from the seed, got %v", got)
+	}
+}
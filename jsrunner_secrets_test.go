@@ -0,0 +1,46 @@
+package jsrunner
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestSetSecretGlobal(t *testing.T) {
+	var handled, handledSource string
+	runner := New(WithErrorHandler(func(op, source string, err error) {
+		handled = err.Error()
+		handledSource = source
+	}))
+	runner.SetSecretGlobal("apiKey", "sk-live-123")
+
+	result, err := runner.Eval(`apiKey.length`)
+	if err != nil {
+		t.Fatalf("Eval() failed: %v", err)
+	}
+	if ExportInt(result) != 11 {
+		t.Errorf("Expected scripts to see the secret, got length %d", ExportInt(result))
+	}
+
+	_, err = runner.Eval(`throw new Error("rejected key " + apiKey) // sk-live-123`)
+	if err == nil {
+		t.Fatal("Expected an error")
+	}
+	if strings.Contains(err.Error(), "sk-live-123") || !strings.Contains(err.Error(), "rejected key ***") {
+		t.Errorf("Expected the secret to be redacted, got %q", err.Error())
+	}
+	if strings.Contains(handled, "sk-live-123") || strings.Contains(handledSource, "sk-live-123") {
+		t.Errorf("Expected the error handler to see redacted values, got %q and %q", handled, handledSource)
+	}
+
+	errLeak := errors.New("upstream refused sk-live-123")
+	runner.SetGlobal("login", func() error { return errLeak })
+	runner.LoadScriptString(`function signIn() { login(); }`)
+	_, err = runner.Call("signIn")
+	if err == nil || strings.Contains(err.Error(), "sk-live-123") {
+		t.Errorf("Expected a redacted Call error, got %v", err)
+	}
+	if !errors.Is(err, errLeak) {
+		t.Error("Expected errors.Is to reach the original error")
+	}
+}
@@ -0,0 +1,104 @@
+package jsrunner
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/evanw/esbuild/pkg/api"
+)
+
+func TestLoadScriptWithSourceMap(t *testing.T) {
+	original := `function formatPrice(cents) {
+  if (cents < 0) {
+    throw new Error("negative price");
+  }
+  return "$" + (cents / 100).toFixed(2);
+}
+globalThis.formatPrice = formatPrice;
+`
+	result := api.Transform(original, api.TransformOptions{
+		Sourcefile:        "src/price.ts",
+		Loader:            api.LoaderTS,
+		MinifyWhitespace:  true,
+		MinifyIdentifiers: true,
+		KeepNames:         true,
+		Sourcemap:         api.SourceMapExternal,
+	})
+	if len(result.Errors) > 0 {
+		t.Fatalf("Transform() failed: %v", result.Errors[0].Text)
+	}
+
+	runner := New()
+	if err := runner.LoadScriptWithSourceMap(string(result.Code), result.Map); err != nil {
+		t.Fatalf("LoadScriptWithSourceMap() failed: %v", err)
+	}
+
+	value, err := runner.Call("formatPrice", 1999)
+	if err != nil {
+		t.Fatalf("Call() failed: %v", err)
+	}
+	if got := ExportString(value); got != "$19.99" {
+		t.Errorf("Expected '$19.99', got %q", got)
+	}
+
+	_, err = runner.Call("formatPrice", -1)
+	if err == nil {
+		t.Fatal("expected thrown error")
+	}
+	stack := errorStack(err)
+	if !strings.Contains(stack, "src/price.ts:3:") {
+		t.Errorf("Expected stack to reference the original source, got %q", stack)
+	}
+	if !strings.Contains(stack, "formatPrice") {
+		t.Errorf("Expected stack to name formatPrice, got %q", stack)
+	}
+
+	if err := runner.LoadScriptWithSourceMap("1", []byte("not json")); err == nil {
+		t.Error("expected invalid source map to be rejected")
+	}
+}
+
+func TestLoadScriptWithSourceMapRewrittenScript(t *testing.T) {
+	original := `async function* letters() { yield "a"; }
+globalThis.collect = async function () {
+  let out = "";
+  for await (const l of letters()) out += l;
+  return out;
+};
+`
+	result := api.Transform(original, api.TransformOptions{
+		Sourcefile: "src/letters.js",
+		Loader:     api.LoaderJS,
+		Sourcemap:  api.SourceMapExternal,
+	})
+	if len(result.Errors) > 0 {
+		t.Fatalf("Transform() failed: %v", result.Errors[0].Text)
+	}
+
+	logger := &captureLogger{}
+	runner := New(WithAsyncIteration(), WithLogger(logger))
+	if err := runner.LoadScriptWithSourceMap(string(result.Code), result.Map); err != nil {
+		t.Fatalf("LoadScriptWithSourceMap() failed: %v", err)
+	}
+	if _, ok := logger.find("source map ignored because the script was rewritten"); !ok {
+		t.Error("expected a warning that the source map was ignored")
+	}
+
+	if _, err := runner.Eval(`typeof collect`); err != nil {
+		t.Fatalf("Eval() failed: %v", err)
+	}
+
+	plain := api.Transform("globalThis.x = 1;\n", api.TransformOptions{
+		Sourcefile: "src/plain.js",
+		Loader:     api.LoaderJS,
+		Sourcemap:  api.SourceMapExternal,
+	})
+	logger = &captureLogger{}
+	runner = New(WithAsyncIteration(), WithLogger(logger))
+	if err := runner.LoadScriptWithSourceMap(string(plain.Code), plain.Map); err != nil {
+		t.Fatalf("LoadScriptWithSourceMap() failed: %v", err)
+	}
+	if _, ok := logger.find("source map ignored because the script was rewritten"); ok {
+		t.Error("expected no warning when the script is not rewritten")
+	}
+}
@@ -0,0 +1,44 @@
+package jsrunner
+
+import (
+	"testing"
+)
+
+func TestWithStrictMode(t *testing.T) {
+	runner := New(WithStrictMode())
+
+	if err := runner.LoadScriptString(`var declared = 1; function leak() { undeclared = 2; }`); err != nil {
+		t.Fatalf("LoadScriptString() failed: %v", err)
+	}
+
+	result, err := runner.Eval("declared")
+	if err != nil {
+		t.Fatalf("Eval() failed: %v", err)
+	}
+	if ExportInt(result) != 1 {
+		t.Errorf("Expected top-level var to register as a global, got %d", ExportInt(result))
+	}
+
+	if _, err := runner.Call("leak"); err == nil {
+		t.Error("expected undeclared assignment in a loaded function to throw")
+	}
+	if _, err := runner.Eval("other = 3"); err == nil {
+		t.Error("expected undeclared assignment in Eval to throw")
+	}
+	if _, err := runner.EvalWith("(missing = a)", map[string]interface{}{"a": 1}); err == nil {
+		t.Error("expected undeclared assignment in EvalWith to throw")
+	}
+
+	result, err = runner.Eval("typeof undeclared + ',' + typeof other")
+	if err != nil {
+		t.Fatalf("Eval() failed: %v", err)
+	}
+	if got := ExportString(result); got != "undefined,undefined" {
+		t.Errorf("Expected no accidental globals, got %s", got)
+	}
+
+	sloppy := New()
+	if _, err := sloppy.Eval("other = 3"); err != nil {
+		t.Errorf("expected sloppy mode by default, got %v", err)
+	}
+}
@@ -0,0 +1,70 @@
+package jsrunner
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestWithSynchronized(t *testing.T) {
+	runner := New(WithSynchronized())
+	if err := runner.LoadScriptString(`var total = 0; function add(n) { total += n; return total; }`); err != nil {
+		t.Fatalf("LoadScriptString() failed: %v", err)
+	}
+
+	const workers = 16
+	const iterations = 200
+
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func(id int) {
+			defer wg.Done()
+			for i := 0; i < iterations; i++ {
+				if _, err := runner.Call("add", 1); err != nil {
+					t.Errorf("Call() failed: %v", err)
+					return
+				}
+				if _, err := runner.Eval("total"); err != nil {
+					t.Errorf("Eval() failed: %v", err)
+					return
+				}
+				runner.SetGlobal("lastWorker", id)
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	result, err := runner.Eval("total")
+	if err != nil {
+		t.Fatalf("Eval() failed: %v", err)
+	}
+	if got := ExportInt(result); got != workers*iterations {
+		t.Errorf("Expected total %d, got %d", workers*iterations, got)
+	}
+}
+
+func TestRunnerLock(t *testing.T) {
+	runner := New()
+	runner.SetGlobal("counter", 0)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				runner.Lock()
+				if _, err := runner.Eval("counter++"); err != nil {
+					t.Errorf("Eval() failed: %v", err)
+				}
+				runner.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	result, _ := runner.Eval("counter")
+	if got := ExportInt(result); got != 400 {
+		t.Errorf("Expected counter 400, got %d", got)
+	}
+}
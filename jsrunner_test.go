@@ -1,13 +1,23 @@
 package jsrunner
 
 import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"math/big"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
 	"sync"
 	"testing"
+	"testing/fstest"
 	"time"
+
+	"github.com/dop251/goja"
 )
 
 func TestNew(t *testing.T) {
@@ -154,6 +164,89 @@ func TestLoadScript(t *testing.T) {
 	}
 }
 
+func TestLoadScriptStackUsesFilename(t *testing.T) {
+	tmpDir := t.TempDir()
+	file := filepath.Join(tmpDir, "thrower.js")
+	code := "function boom() {\n  throw new Error('kaboom');\n}\nboom();\n"
+	if err := os.WriteFile(file, []byte(code), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	runner := New()
+	err := runner.LoadScript(file)
+	if err == nil {
+		t.Fatal("expected LoadScript to return the thrown error")
+	}
+
+	var jsErr *goja.Exception
+	if !errors.As(err, &jsErr) {
+		t.Fatalf("expected *goja.Exception, got %T", err)
+	}
+	if !strings.Contains(jsErr.String(), file+":2") {
+		t.Errorf("expected stack to reference %s:2, got %s", file, jsErr.String())
+	}
+}
+
+func TestLoadScriptFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"scripts/lib.js": &fstest.MapFile{Data: []byte("function embedded() { return 'from fs'; }")},
+	}
+
+	runner := New()
+	if err := runner.LoadScriptFS(fsys, "scripts/lib.js"); err != nil {
+		t.Fatalf("LoadScriptFS() failed: %v", err)
+	}
+
+	result, err := runner.Call("embedded")
+	if err != nil {
+		t.Fatalf("Call() failed: %v", err)
+	}
+	if got := ExportString(result); got != "from fs" {
+		t.Errorf("Expected 'from fs', got '%s'", got)
+	}
+
+	if err := runner.LoadScriptFS(fsys, "scripts/missing.js"); err == nil {
+		t.Error("expected error loading missing file from fs")
+	}
+}
+
+func TestLoadScriptReader(t *testing.T) {
+	runner := New()
+
+	if err := runner.LoadScriptReader(bytes.NewReader([]byte("var plain = 'bytes';"))); err != nil {
+		t.Fatalf("LoadScriptReader() failed: %v", err)
+	}
+
+	var compressed bytes.Buffer
+	zw := gzip.NewWriter(&compressed)
+	if _, err := zw.Write([]byte("var zipped = 'gzip';")); err != nil {
+		t.Fatalf("Failed to write gzip data: %v", err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("Failed to close gzip writer: %v", err)
+	}
+
+	zr, err := gzip.NewReader(&compressed)
+	if err != nil {
+		t.Fatalf("Failed to create gzip reader: %v", err)
+	}
+	if err := runner.LoadScriptReader(zr); err != nil {
+		t.Fatalf("LoadScriptReader() with gzip failed: %v", err)
+	}
+
+	result, err := runner.Eval("plain + ',' + zipped")
+	if err != nil {
+		t.Fatalf("Eval() failed: %v", err)
+	}
+	if got := ExportString(result); got != "bytes,gzip" {
+		t.Errorf("Expected 'bytes,gzip', got '%s'", got)
+	}
+
+	if err := runner.LoadScriptReader(bytes.NewReader([]byte("var x = ;"))); err == nil {
+		t.Error("expected syntax error from LoadScriptReader")
+	}
+}
+
 func TestLoadScriptAndAccess(t *testing.T) {
 	tmpDir := t.TempDir()
 	testFile := filepath.Join(tmpDir, "test.js")
@@ -269,6 +362,52 @@ func TestCall(t *testing.T) {
 	}
 }
 
+func TestExportFunc(t *testing.T) {
+	runner := New(WithSynchronized())
+	err := runner.LoadScriptString(`
+		var calls = 0;
+		function add(a, b) { calls++; return a + b; }
+		var counter = { step: 5, next: function(n) { return n + this.step; } };
+	`)
+	if err != nil {
+		t.Fatalf("LoadScriptString() failed: %v", err)
+	}
+
+	add, err := runner.ExportFunc("add")
+	if err != nil {
+		t.Fatalf("ExportFunc() failed: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		result, err := add(i, 10)
+		if err != nil {
+			t.Fatalf("add(%d, 10) failed: %v", i, err)
+		}
+		if got := ExportInt(result); got != int64(i+10) {
+			t.Errorf("add(%d, 10) = %d, want %d", i, got, i+10)
+		}
+	}
+	calls, _ := runner.Eval("calls")
+	if ExportInt(calls) != 3 {
+		t.Errorf("Expected 3 calls, got %d", ExportInt(calls))
+	}
+
+	next, err := runner.ExportFunc("counter.next")
+	if err != nil {
+		t.Fatalf("ExportFunc() failed: %v", err)
+	}
+	result, err := next(1)
+	if err != nil {
+		t.Fatalf("counter.next failed: %v", err)
+	}
+	if ExportInt(result) != 6 {
+		t.Errorf("Expected counter.next to keep its receiver, got %d", ExportInt(result))
+	}
+
+	if _, err := runner.ExportFunc("missing"); err == nil {
+		t.Error("Expected error for an undefined function")
+	}
+}
+
 func TestCallWithDifferentTypes(t *testing.T) {
 	runner := New()
 	code := `
@@ -641,6 +780,43 @@ func TestExport(t *testing.T) {
 	}
 }
 
+func TestExportBigInt(t *testing.T) {
+	runner := New()
+
+	result, err := runner.Eval("2n ** 80n")
+	if err != nil {
+		t.Fatalf("Eval() failed: %v", err)
+	}
+
+	want := new(big.Int).Lsh(big.NewInt(1), 80)
+	n, ok := ExportBigInt(result)
+	if !ok {
+		t.Fatal("ExportBigInt() did not detect BigInt")
+	}
+	if n.Cmp(want) != 0 {
+		t.Errorf("Expected %s, got %s", want, n)
+	}
+
+	exported, ok := Export(result).(*big.Int)
+	if !ok {
+		t.Fatalf("Export() returned %T, want *big.Int", Export(result))
+	}
+	if exported.Cmp(want) != 0 {
+		t.Errorf("Expected %s, got %s", want, exported)
+	}
+
+	result, err = runner.Eval("42")
+	if err != nil {
+		t.Fatalf("Eval() failed: %v", err)
+	}
+	if _, ok := ExportBigInt(result); ok {
+		t.Error("ExportBigInt() should reject plain numbers")
+	}
+	if _, ok := ExportBigInt(nil); ok {
+		t.Error("ExportBigInt(nil) should return false")
+	}
+}
+
 func TestComplexScenario(t *testing.T) {
 	runner := New()
 
@@ -735,6 +911,24 @@ func TestNewWithGlobals(t *testing.T) {
 	}
 }
 
+func TestWithGlobalsOption(t *testing.T) {
+	runner := New(
+		WithGlobals(map[string]interface{}{"apiUrl": "https://api.example.com", "retries": 1}),
+		WithGlobals(map[string]interface{}{"retries": 3}),
+	)
+
+	result, err := runner.Eval("apiUrl + ' ' + retries")
+	if err != nil {
+		t.Fatalf("Eval() failed: %v", err)
+	}
+	if got := ExportString(result); got != "https://api.example.com 3" {
+		t.Errorf("Expected 'https://api.example.com 3', got '%s'", got)
+	}
+	if runner.globals["retries"] != 3 {
+		t.Errorf("Expected globals map to track retries=3, got %v", runner.globals["retries"])
+	}
+}
+
 func TestNewWithGlobalsEmptyMap(t *testing.T) {
 	runner := NewWithGlobals(map[string]interface{}{})
 	if runner == nil {
@@ -1104,3 +1298,318 @@ func TestEnableWebAccessAfterInit(t *testing.T) {
 		t.Errorf("Expected 'late', got '%s'", ExportString(textResult))
 	}
 }
+
+func TestResetGlobals(t *testing.T) {
+	runner := NewWithGlobals(map[string]interface{}{"appName": "demo"})
+	if err := runner.LoadScriptString(`function greet() { return "hi " + appName; }`); err != nil {
+		t.Fatalf("LoadScriptString() failed: %v", err)
+	}
+
+	runner.SetGlobal("SERVER_PROPS", map[string]interface{}{"user": "ada"})
+	runner.SetGlobal("locale", "en")
+
+	runner.ResetGlobals("locale")
+
+	result, err := runner.Eval("typeof SERVER_PROPS")
+	if err != nil {
+		t.Fatalf("Eval() failed: %v", err)
+	}
+	if got := ExportString(result); got != "undefined" {
+		t.Errorf("Expected SERVER_PROPS to be removed, got typeof %s", got)
+	}
+
+	result, err = runner.Eval("locale")
+	if err != nil {
+		t.Fatalf("Eval() failed: %v", err)
+	}
+	if got := ExportString(result); got != "en" {
+		t.Errorf("Expected kept global 'en', got '%s'", got)
+	}
+
+	result, err = runner.Call("greet")
+	if err != nil {
+		t.Fatalf("Call() failed after reset: %v", err)
+	}
+	if got := ExportString(result); got != "hi demo" {
+		t.Errorf("Expected 'hi demo', got '%s'", got)
+	}
+}
+
+func TestRunProgram(t *testing.T) {
+	program, err := Compile("math.js", `function square(x) { return x * x; } square(base);`)
+	if err != nil {
+		t.Fatalf("Compile failed: %v", err)
+	}
+
+	for _, base := range []int{3, 4} {
+		runner := NewWithGlobals(map[string]interface{}{"base": base})
+		result, err := runner.RunProgram(program)
+		if err != nil {
+			t.Fatalf("RunProgram failed: %v", err)
+		}
+		if got := ExportInt(result); got != int64(base*base) {
+			t.Errorf("Expected %d, got %d", base*base, got)
+		}
+	}
+}
+
+func TestSetGlobalGetter(t *testing.T) {
+	runner := New()
+
+	counter := 0
+	runner.SetGlobalGetter("nextID", func() interface{} {
+		counter++
+		return counter
+	})
+
+	result, err := runner.Eval("[nextID, nextID]")
+	if err != nil {
+		t.Fatalf("Eval() failed: %v", err)
+	}
+	values, ok := Export(result).([]interface{})
+	if !ok || len(values) != 2 {
+		t.Fatalf("Expected two values, got %v", Export(result))
+	}
+	if values[0] == values[1] {
+		t.Errorf("Expected distinct values on each read, got %v", values)
+	}
+	if counter != 2 {
+		t.Errorf("Expected getter to run twice, ran %d times", counter)
+	}
+
+	runner.ResetGlobals()
+	result, err = runner.Eval("typeof nextID")
+	if err != nil {
+		t.Fatalf("Eval() failed: %v", err)
+	}
+	if got := ExportString(result); got != "undefined" {
+		t.Errorf("Expected ResetGlobals to remove the getter, got typeof %s", got)
+	}
+}
+
+func TestSetGlobalOverwrites(t *testing.T) {
+	runner := New()
+
+	runner.SetGlobal("mode", "first")
+	runner.SetGlobal("mode", "second")
+
+	result, err := runner.Eval("mode")
+	if err != nil {
+		t.Fatalf("Eval() failed: %v", err)
+	}
+	if got := ExportString(result); got != "second" {
+		t.Errorf("Expected the last SetGlobal to win, got '%s'", got)
+	}
+	if got := runner.globals["mode"]; got != "second" {
+		t.Errorf("Expected the tracked value to be overwritten, got %v", got)
+	}
+
+	if err := runner.LoadScriptString(`var fromScript = "script";`); err != nil {
+		t.Fatalf("LoadScriptString() failed: %v", err)
+	}
+	runner.SetGlobal("fromScript", "go")
+	result, err = runner.Eval("fromScript")
+	if err != nil {
+		t.Fatalf("Eval() failed: %v", err)
+	}
+	if got := ExportString(result); got != "go" {
+		t.Errorf("Expected SetGlobal to replace a script global, got '%s'", got)
+	}
+}
+
+func TestSetGlobalOnce(t *testing.T) {
+	runner := New()
+
+	if !runner.SetGlobalOnce("locale", "en-US") {
+		t.Error("Expected SetGlobalOnce to set a new global")
+	}
+	if runner.SetGlobalOnce("locale", "nl-NL") {
+		t.Error("Expected SetGlobalOnce to refuse an existing global")
+	}
+
+	if err := runner.LoadScriptString(`var config = { env: "test" }; var declared;`); err != nil {
+		t.Fatalf("LoadScriptString() failed: %v", err)
+	}
+	if runner.SetGlobalOnce("config", map[string]interface{}{"env": "prod"}) {
+		t.Error("Expected SetGlobalOnce to keep a script-defined global")
+	}
+	if runner.SetGlobalOnce("declared", 1) {
+		t.Error("Expected a declared but undefined global to count as existing")
+	}
+	if runner.SetGlobalOnce("JSON", nil) {
+		t.Error("Expected SetGlobalOnce to keep built-ins")
+	}
+
+	result, err := runner.Eval(`locale + "," + config.env + "," + typeof JSON.parse`)
+	if err != nil {
+		t.Fatalf("Eval() failed: %v", err)
+	}
+	if got := ExportString(result); got != "en-US,test,function" {
+		t.Errorf("Expected existing values to be untouched, got '%s'", got)
+	}
+}
+
+func TestEvalWith(t *testing.T) {
+	runner := New()
+
+	result, err := runner.EvalWith("a + b", map[string]interface{}{"a": 2, "b": 3})
+	if err != nil {
+		t.Fatalf("EvalWith() failed: %v", err)
+	}
+	if got := ExportInt(result); got != 5 {
+		t.Errorf("Expected 5, got %d", got)
+	}
+
+	result, err = runner.Eval("typeof a + ',' + typeof b")
+	if err != nil {
+		t.Fatalf("Eval() failed: %v", err)
+	}
+	if got := ExportString(result); got != "undefined,undefined" {
+		t.Errorf("Expected locals not to leak into globals, got %s", got)
+	}
+
+	runner.SetGlobal("a", 100)
+	result, err = runner.EvalWith("a", map[string]interface{}{"a": 1})
+	if err != nil {
+		t.Fatalf("EvalWith() failed: %v", err)
+	}
+	if got := ExportInt(result); got != 1 {
+		t.Errorf("Expected local to shadow global, got %d", got)
+	}
+
+	if _, err := runner.EvalWith("1", map[string]interface{}{"not valid": 1}); err == nil {
+		t.Error("expected error for invalid local name")
+	}
+	for _, name := range []string{"this", "new", "class", "eval"} {
+		if _, err := runner.EvalWith("1", map[string]interface{}{name: 1}); err == nil || !strings.Contains(err.Error(), "invalid local name") {
+			t.Errorf("expected reserved word %q to be rejected, got %v", name, err)
+		}
+	}
+}
+
+func TestEvalExports(t *testing.T) {
+	runner := New()
+	err := runner.LoadScriptString(`
+		const total = [1, 2, 3].reduce((a, b) => a + b, 0);
+		let label = "sum";
+		var summary = { total: total, label: label };
+		var unset;
+	`)
+	if err != nil {
+		t.Fatalf("LoadScriptString failed: %v", err)
+	}
+
+	exports, err := runner.EvalExports("total", "label", "summary", "unset")
+	if err != nil {
+		t.Fatalf("EvalExports() failed: %v", err)
+	}
+	want := map[string]interface{}{
+		"total":   int64(6),
+		"label":   "sum",
+		"summary": map[string]interface{}{"total": int64(6), "label": "sum"},
+		"unset":   nil,
+	}
+	if !reflect.DeepEqual(exports, want) {
+		t.Errorf("EvalExports() = %#v, want %#v", exports, want)
+	}
+
+	if _, err := runner.EvalExports("total", "missing"); err == nil || !strings.Contains(err.Error(), "missing is not defined") {
+		t.Errorf("expected an error naming the missing global, got %v", err)
+	}
+	if _, err := runner.EvalExports("total; sideEffect()"); err == nil || !strings.Contains(err.Error(), "invalid name") {
+		t.Errorf("expected an error for a name that is not an identifier, got %v", err)
+	}
+}
+
+func TestLoadScriptBytes(t *testing.T) {
+	var bundle bytes.Buffer
+	for i := 0; i < 1000; i++ {
+		fmt.Fprintf(&bundle, "function helper%d(x) { return x + %d; }\n", i, i)
+	}
+	bundle.WriteString("function total() { return helper999(1); }\n")
+
+	runner := New()
+	if err := runner.LoadScriptBytes(bundle.Bytes()); err != nil {
+		t.Fatalf("LoadScriptBytes failed: %v", err)
+	}
+	result, err := runner.Call("total")
+	if err != nil {
+		t.Fatalf("Call failed: %v", err)
+	}
+	if got := ExportInt(result); got != 1000 {
+		t.Errorf("Expected 1000, got %d", got)
+	}
+
+	source, err := runner.EvalBytes([]byte("helper7.toString()"))
+	if err != nil {
+		t.Fatalf("EvalBytes failed: %v", err)
+	}
+	if got := ExportString(source); got != "function helper7(x) { return x + 7; }" {
+		t.Errorf("Unexpected function source: %s", got)
+	}
+
+	if _, err := runner.EvalBytes(nil); err != nil {
+		t.Errorf("EvalBytes(nil) failed: %v", err)
+	}
+	if err := runner.LoadScriptBytes([]byte("function (")); err == nil {
+		t.Error("Expected syntax error from LoadScriptBytes")
+	}
+}
+
+func TestSnapshotRestore(t *testing.T) {
+	runner := New()
+	runner.SetGlobal("apiKey", "secret")
+	if err := runner.LoadScriptString(`
+		var counter = 0;
+		var config = { retries: 3, tags: ["a", "b"], nested: { enabled: true } };
+		config.self = config;
+		function bump() { counter++; config.nested.enabled = false; config.tags.push("c"); }
+	`); err != nil {
+		t.Fatalf("LoadScriptString failed: %v", err)
+	}
+
+	if err := runner.RestoreSnapshot(); err == nil {
+		t.Error("Expected error when restoring without a snapshot")
+	}
+	if err := runner.Snapshot(); err != nil {
+		t.Fatalf("Snapshot failed: %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := runner.Eval(`bump(); apiKey = "leaked"; extra = 1; config.retries = 0;`); err != nil {
+			t.Fatalf("Eval failed: %v", err)
+		}
+		if err := runner.RestoreSnapshot(); err != nil {
+			t.Fatalf("RestoreSnapshot failed: %v", err)
+		}
+
+		result, err := runner.Eval(`[counter, config.retries, config.tags.join(""), config.nested.enabled,
+			config.self === config, apiKey, typeof extra].join(",")`)
+		if err != nil {
+			t.Fatalf("Eval failed: %v", err)
+		}
+		if got := ExportString(result); got != "0,3,ab,true,true,secret,undefined" {
+			t.Errorf("Restore %d: unexpected state %s", i+1, got)
+		}
+	}
+}
+
+func TestWithAsyncIteration(t *testing.T) {
+	runner := New(WithAsyncIteration())
+
+	if err := runner.LoadScriptString(`
+		var collected = [];
+		async function* letters() { yield "a"; yield await Promise.resolve("b"); }
+		(async () => { for await (const l of letters()) collected.push(l); })();
+	`); err != nil {
+		t.Fatalf("LoadScriptString() failed: %v", err)
+	}
+
+	result, err := runner.Eval(`typeof Symbol.asyncIterator + ":" + collected.join("")`)
+	if err != nil {
+		t.Fatalf("Eval() failed: %v", err)
+	}
+	if got := ExportString(result); got != "symbol:ab" {
+		t.Errorf("Expected 'symbol:ab', got '%s'", got)
+	}
+}
@@ -0,0 +1,50 @@
+package jsrunner
+
+import "testing"
+
+func TestWithTextCodecs(t *testing.T) {
+	script := `
+		const encoded = new TextEncoder().encode("héllo €😀");
+		const decoded = new TextDecoder().decode(encoded);
+		[encoded.length, encoded[1], decoded, decoded === "héllo €😀"].join("|");
+	`
+
+	runner := New(WithTextCodecs())
+	result, err := runner.Eval(script)
+	if err != nil {
+		t.Fatalf("Eval() failed: %v", err)
+	}
+	loop := NewEventLoopRunner(WithTextCodecs())
+	looped, err := loop.RunAsync(script)
+	if err != nil {
+		t.Fatalf("RunAsync() failed: %v", err)
+	}
+
+	want := "14|195|héllo €😀|true"
+	if result.String() != want || looped.String() != want {
+		t.Errorf("results = %q and %q, want %q", result.String(), looped.String(), want)
+	}
+}
+
+func TestTextCodecsEdgeCases(t *testing.T) {
+	runner := New(WithTextCodecs())
+
+	result, err := runner.Eval(`
+		const bytes = new Uint8Array([0xEF, 0xBB, 0xBF, 0x68, 0x69, 0xFF]);
+		const lenient = new TextDecoder("utf-8").decode(bytes);
+		let fatal = "";
+		try { new TextDecoder("utf-8", { fatal: true }).decode(bytes); } catch (e) { fatal = e.name; }
+		let label = "";
+		try { new TextDecoder("latin1"); } catch (e) { label = e.name; }
+		const view = new TextDecoder().decode(bytes.subarray(3, 5));
+		const target = new Uint8Array(4);
+		const into = new TextEncoder().encodeInto("aé€", target);
+		[lenient, fatal, label, view, into.read, into.written, target.join(",")].join("|");
+	`)
+	if err != nil {
+		t.Fatalf("Eval() failed: %v", err)
+	}
+	if want := "hi�|TypeError|RangeError|hi|2|3|97,195,169,0"; result.String() != want {
+		t.Errorf("result = %q, want %q", result.String(), want)
+	}
+}
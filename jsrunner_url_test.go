@@ -0,0 +1,83 @@
+package jsrunner
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestWithURLGlobals(t *testing.T) {
+	runner := New(WithURLGlobals())
+
+	result, err := runner.Eval(`
+		const u = new URL("/search?q=go&page=1#results", "https://Example.com:8443");
+		const before = [u.host, u.hostname, u.port, u.pathname, u.search, u.hash, u.origin].join("|");
+		u.searchParams.set("page", "2");
+		u.searchParams.append("tag", "a b");
+		JSON.stringify({ before, href: u.href, search: u.search, page: u.searchParams.get("page"), json: JSON.stringify({ u }) });
+	`)
+	if err != nil {
+		t.Fatalf("Eval() failed: %v", err)
+	}
+
+	var got struct {
+		Before string `json:"before"`
+		Href   string `json:"href"`
+		Search string `json:"search"`
+		Page   string `json:"page"`
+		JSON   string `json:"json"`
+	}
+	if err := json.Unmarshal([]byte(ExportString(result)), &got); err != nil {
+		t.Fatalf("failed to decode result: %v", err)
+	}
+
+	if want := "example.com:8443|example.com|8443|/search|?q=go&page=1|#results|https://example.com:8443"; got.Before != want {
+		t.Errorf("properties = %q, want %q", got.Before, want)
+	}
+	if want := "https://example.com:8443/search?q=go&page=2&tag=a+b#results"; got.Href != want {
+		t.Errorf("href = %q, want %q", got.Href, want)
+	}
+	if got.Search != "?q=go&page=2&tag=a+b" || got.Page != "2" {
+		t.Errorf("search = %q, page = %q", got.Search, got.Page)
+	}
+	if want := `{"u":"https://example.com:8443/search?q=go&page=2&tag=a+b#results"}`; got.JSON != want {
+		t.Errorf("JSON = %q, want %q", got.JSON, want)
+	}
+}
+
+func TestURLSearchParams(t *testing.T) {
+	runner := New(WithURLGlobals())
+
+	result, err := runner.Eval(`
+		const params = new URLSearchParams("?b=2&a=1&b=3");
+		const fromRecord = new URLSearchParams({ x: "1", y: "two words" });
+		const fromPairs = new URLSearchParams([["k", "v"]]);
+		const copy = new URLSearchParams(params);
+		params.delete("a");
+		const seen = [];
+		for (const [key, value] of fromRecord) seen.push(key + "=" + value);
+		copy.sort();
+		[params.getAll("b").join(","), params.has("a"), params.toString(), fromPairs.get("k"), fromPairs.get("missing"), seen.join("&"), copy.toString(), params instanceof URLSearchParams].join("|");
+	`)
+	if err != nil {
+		t.Fatalf("Eval() failed: %v", err)
+	}
+	if want := "2,3|false|b=2&b=3|v||x=1&y=two words|a=1&b=2&b=3|true"; result.String() != want {
+		t.Errorf("result = %q, want %q", result.String(), want)
+	}
+}
+
+func TestWithURLGlobalsInvalidURL(t *testing.T) {
+	loop := NewEventLoopRunner(WithURLGlobals())
+
+	result, err := loop.RunAsync(`
+		let message = "";
+		try { new URL("not a url"); } catch (e) { message = e.name + ": " + e.message; }
+		message;
+	`)
+	if err != nil {
+		t.Fatalf("RunAsync() failed: %v", err)
+	}
+	if want := "TypeError: Invalid URL: not a url"; result.String() != want {
+		t.Errorf("result = %q, want %q", result.String(), want)
+	}
+}
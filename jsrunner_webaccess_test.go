@@ -1,11 +1,24 @@
 package jsrunner
 
 import (
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"encoding/json"
 	"fmt"
+	"io"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
+
+	"github.com/andybalholm/brotli"
 )
 
 type spyTransport struct {
@@ -76,3 +89,668 @@ func TestCustomHTTPClientIsUsed(t *testing.T) {
 		t.Fatalf("custom transport was never called")
 	}
 }
+
+func TestFetchBlocksPrivateNetworks(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "internal")
+	}))
+	defer server.Close()
+
+	runner := New(WithWebAccess(&WebAccessConfig{Timeout: time.Second, BlockPrivateNetworks: true}))
+
+	_, err := runner.Call("fetchText", server.URL)
+	if err == nil {
+		t.Fatal("expected fetch to a loopback address to be blocked")
+	}
+	if !strings.Contains(err.Error(), "private address") {
+		t.Errorf("expected descriptive private network error, got %v", err)
+	}
+
+	result, err := runner.Eval(`
+		(function() {
+			try {
+				fetchText("file:///etc/passwd");
+				return "allowed";
+			} catch (e) {
+				return String(e);
+			}
+		})()
+	`)
+	if err != nil {
+		t.Fatalf("Eval() failed: %v", err)
+	}
+	if !strings.Contains(ExportString(result), "unsupported scheme") {
+		t.Errorf("expected JS to observe scheme error, got %s", ExportString(result))
+	}
+}
+
+// redirectTransport answers requests for host with a redirect to location and
+// sends everything else to the network.
+type redirectTransport struct {
+	host     string
+	location string
+}
+
+func (rt redirectTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Hostname() != rt.host {
+		return http.DefaultTransport.RoundTrip(req)
+	}
+	return &http.Response{
+		StatusCode: http.StatusFound,
+		Header:     http.Header{"Location": []string{rt.location}},
+		Body:       io.NopCloser(strings.NewReader("")),
+		Request:    req,
+	}, nil
+}
+
+func TestFetchBlocksPrivateRedirects(t *testing.T) {
+	var hits atomic.Int32
+	internal := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		fmt.Fprint(w, "metadata")
+	}))
+	defer internal.Close()
+
+	runner := New(
+		WithWebAccess(&WebAccessConfig{Timeout: time.Second, BlockPrivateNetworks: true}),
+		WithHTTPClientFunc(func(string) *http.Client {
+			return &http.Client{Transport: redirectTransport{host: "public.example", location: internal.URL}}
+		}),
+	)
+	// Pretend public.example resolves to a public address.
+	runner.fetchPolicy.resolve = func(ctx context.Context, host string) ([]net.IPAddr, error) {
+		if host == "public.example" {
+			return []net.IPAddr{{IP: net.ParseIP("93.184.216.34")}}, nil
+		}
+		return net.DefaultResolver.LookupIPAddr(ctx, host)
+	}
+
+	_, err := runner.Call("fetchText", "http://public.example/")
+	if err == nil || !strings.Contains(err.Error(), "private address") {
+		t.Errorf("expected the redirect to loopback to be blocked, got %v", err)
+	}
+
+	// A name that passed the lookup but connects to a private address, as
+	// with DNS rebinding, is stopped when dialing.
+	client := runner.fetchPolicy.client(&http.Client{Timeout: time.Second})
+	if _, err := client.Get(internal.URL); err == nil || !strings.Contains(err.Error(), "private address") {
+		t.Errorf("expected the dial to a loopback address to be blocked, got %v", err)
+	}
+
+	if n := hits.Load(); n != 0 {
+		t.Errorf("internal server was reached %d times", n)
+	}
+	if !isPrivateIP(net.ParseIP("100.64.1.1")) {
+		t.Error("expected the carrier-grade NAT range to be private")
+	}
+}
+
+func TestFetchAllowedHosts(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "ok")
+	}))
+	defer server.Close()
+
+	runner := New(WithWebAccess(&WebAccessConfig{
+		Timeout:      time.Second,
+		AllowedHosts: []string{"127.0.0.1", "*.example.com"},
+	}))
+
+	result, err := runner.Call("fetchText", server.URL)
+	if err != nil {
+		t.Fatalf("fetchText to allowed host failed: %v", err)
+	}
+	if ExportString(result) != "ok" {
+		t.Errorf("Expected 'ok', got '%s'", ExportString(result))
+	}
+
+	_, err = runner.Call("fetchText", "http://internal.local/secrets")
+	if err == nil || !strings.Contains(err.Error(), "not in the allowed hosts list") {
+		t.Errorf("expected disallowed host error, got %v", err)
+	}
+
+	elr := NewEventLoopRunner(WithWebAccess(&WebAccessConfig{
+		Timeout:      time.Second,
+		AllowedHosts: []string{"api.example.com"},
+	}))
+	result, err = elr.RunAsync(`
+		(function() {
+			try {
+				fetchText("` + server.URL + `");
+				return "allowed";
+			} catch (e) {
+				return "blocked";
+			}
+		})()
+	`)
+	if err != nil {
+		t.Fatalf("RunAsync failed: %v", err)
+	}
+	if ExportString(result) != "blocked" {
+		t.Errorf("expected event loop runner to enforce allowed hosts, got %s", ExportString(result))
+	}
+}
+
+func TestFetchMaxResponseBytes(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		chunk := strings.Repeat("x", 1024)
+		for i := 0; i < 64; i++ {
+			fmt.Fprint(w, chunk)
+			if f, ok := w.(http.Flusher); ok {
+				f.Flush()
+			}
+		}
+	}))
+	defer server.Close()
+
+	runner := New(WithWebAccess(&WebAccessConfig{Timeout: time.Second, MaxResponseBytes: 4096}))
+
+	_, err := runner.Call("fetchText", server.URL)
+	if err == nil {
+		t.Fatal("expected oversized response to fail")
+	}
+	if !strings.Contains(err.Error(), "exceeds limit of 4096 bytes") {
+		t.Errorf("expected size limit error, got %v", err)
+	}
+
+	roomy := New(WithWebAccess(&WebAccessConfig{Timeout: time.Second, MaxResponseBytes: 64 * 1024}))
+	result, err := roomy.Call("fetchText", server.URL)
+	if err != nil {
+		t.Fatalf("fetchText within limit failed: %v", err)
+	}
+	if len(ExportString(result)) != 64*1024 {
+		t.Errorf("Expected %d bytes, got %d", 64*1024, len(ExportString(result)))
+	}
+}
+
+type countingTransport struct {
+	mu    sync.Mutex
+	calls int
+}
+
+func (c *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	c.mu.Lock()
+	c.calls++
+	c.mu.Unlock()
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func (c *countingTransport) count() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.calls
+}
+
+func TestFetchCache(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/volatile" {
+			w.Header().Set("Cache-Control", "no-store")
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"region":"eu"}`)
+	}))
+	defer server.Close()
+
+	spy := &countingTransport{}
+	runner := New(
+		WithWebAccess(&WebAccessConfig{Client: &http.Client{Transport: spy}, Timeout: time.Second}),
+		WithFetchCache(time.Minute, 10),
+	)
+	now := time.Now()
+	runner.fetchCache.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		if _, err := runner.Call("fetchJSON", server.URL+"/config"); err != nil {
+			t.Fatalf("fetchJSON failed: %v", err)
+		}
+	}
+	if got := spy.count(); got != 1 {
+		t.Errorf("Expected 1 request within TTL, got %d", got)
+	}
+
+	now = now.Add(2 * time.Minute)
+	if _, err := runner.Call("fetchJSON", server.URL+"/config"); err != nil {
+		t.Fatalf("fetchJSON failed: %v", err)
+	}
+	if got := spy.count(); got != 2 {
+		t.Errorf("Expected a new request after expiry, got %d total", got)
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := runner.Call("fetchText", server.URL+"/volatile"); err != nil {
+			t.Fatalf("fetchText failed: %v", err)
+		}
+	}
+	if got := spy.count(); got != 4 {
+		t.Errorf("Expected no-store responses to bypass the cache, got %d total requests", got)
+	}
+}
+
+func TestFetchCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	cache := newFetchCache(time.Minute, 2)
+	cache.put("a", []byte("1"))
+	cache.put("b", []byte("2"))
+	cache.get("a")
+	cache.put("c", []byte("3"))
+
+	if _, ok := cache.get("b"); ok {
+		t.Error("Expected least recently used entry to be evicted")
+	}
+	if _, ok := cache.get("a"); !ok {
+		t.Error("Expected recently used entry to remain cached")
+	}
+}
+
+func TestFetchCacheInvalidation(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, r.URL.Path)
+	}))
+	defer server.Close()
+
+	spy := &countingTransport{}
+	runner := New(
+		WithWebAccess(&WebAccessConfig{Client: &http.Client{Transport: spy}, Timeout: time.Second}),
+		WithFetchCache(time.Minute, 10),
+	)
+	fetch := func(path string) {
+		t.Helper()
+		if _, err := runner.Call("fetchText", server.URL+path); err != nil {
+			t.Fatalf("fetchText failed: %v", err)
+		}
+	}
+
+	fetch("/a")
+	fetch("/b")
+	if got := runner.FetchCacheKeys(); !reflect.DeepEqual(got, []string{server.URL + "/b", server.URL + "/a"}) {
+		t.Errorf("Expected both URLs cached, most recent first, got %v", got)
+	}
+
+	runner.InvalidateFetchCache(server.URL + "/a")
+	fetch("/a")
+	fetch("/b")
+	if got := spy.count(); got != 3 {
+		t.Errorf("Expected only the invalidated URL to be refetched, got %d requests", got)
+	}
+
+	runner.ClearFetchCache()
+	if got := runner.FetchCacheKeys(); len(got) != 0 {
+		t.Errorf("Expected cache to be empty after ClearFetchCache, got %v", got)
+	}
+	fetch("/b")
+	if got := spy.count(); got != 4 {
+		t.Errorf("Expected a request after ClearFetchCache, got %d total", got)
+	}
+
+	if keys := New().FetchCacheKeys(); keys != nil {
+		t.Errorf("Expected nil keys without WithFetchCache, got %v", keys)
+	}
+}
+
+func TestFetchCacheHitIsLogged(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "cached")
+	}))
+	defer server.Close()
+
+	logger := &captureLogger{}
+	runner := New(WithWebAccess(nil), WithFetchCache(time.Minute, 4), WithLogger(logger))
+	for i := 0; i < 2; i++ {
+		if _, err := runner.Call("fetchText", server.URL); err != nil {
+			t.Fatalf("fetchText failed: %v", err)
+		}
+	}
+
+	if _, ok := logger.find("fetch cache hit"); !ok {
+		t.Error("expected a 'fetch cache hit' event")
+	}
+}
+
+func TestFetchHelperSelectionAndPrefix(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"ok":true}`)
+	}))
+	defer server.Close()
+
+	runner := New(WithWebAccess(&WebAccessConfig{
+		Timeout:     time.Second,
+		InstallJSON: true,
+		Prefix:      "go",
+	}))
+
+	result, err := runner.Eval("typeof goFetchJSON + ',' + typeof goFetchText + ',' + typeof fetchJSON + ',' + typeof fetchText")
+	if err != nil {
+		t.Fatalf("Eval() failed: %v", err)
+	}
+	if got := ExportString(result); got != "function,undefined,undefined,undefined" {
+		t.Errorf("Expected only goFetchJSON to be installed, got %s", got)
+	}
+
+	result, err = runner.Call("goFetchJSON", server.URL)
+	if err != nil {
+		t.Fatalf("goFetchJSON failed: %v", err)
+	}
+	obj, ok := Export(result).(map[string]interface{})
+	if !ok || obj["ok"] != true {
+		t.Errorf("Expected {ok:true}, got %v", Export(result))
+	}
+
+	defaults := New(WithWebAccess(&WebAccessConfig{Timeout: time.Second}))
+	result, err = defaults.Eval("typeof fetchJSON + ',' + typeof fetchText")
+	if err != nil {
+		t.Fatalf("Eval() failed: %v", err)
+	}
+	if got := ExportString(result); got != "function,function" {
+		t.Errorf("Expected both helpers by default, got %s", got)
+	}
+}
+
+func TestWithHTTPClientFunc(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, r.URL.Path)
+	}))
+	defer server.Close()
+
+	tenantA := &spyTransport{}
+	tenantB := &spyTransport{}
+	fallback := &spyTransport{}
+	clients := map[string]*http.Client{
+		"/tenant-a": {Transport: tenantA},
+		"/tenant-b": {Transport: tenantB},
+	}
+
+	runner := New(
+		WithWebAccess(&WebAccessConfig{Client: &http.Client{Transport: fallback}}),
+		WithHTTPClientFunc(func(url string) *http.Client {
+			return clients[strings.TrimPrefix(url, server.URL)]
+		}),
+	)
+
+	if _, err := runner.Call("fetchText", server.URL+"/tenant-a"); err != nil {
+		t.Fatalf("fetchText failed: %v", err)
+	}
+	if !tenantA.called || tenantB.called || fallback.called {
+		t.Fatalf("expected only tenant A transport, got a=%v b=%v fallback=%v", tenantA.called, tenantB.called, fallback.called)
+	}
+
+	if _, err := runner.Call("fetchText", server.URL+"/tenant-b"); err != nil {
+		t.Fatalf("fetchText failed: %v", err)
+	}
+	if !tenantB.called || fallback.called {
+		t.Fatalf("expected tenant B transport, got b=%v fallback=%v", tenantB.called, fallback.called)
+	}
+
+	if _, err := runner.Call("fetchText", server.URL+"/other"); err != nil {
+		t.Fatalf("fetchText failed: %v", err)
+	}
+	if !fallback.called {
+		t.Fatal("expected fallback client when the func returns nil")
+	}
+
+	spy := &spyTransport{}
+	loop := NewEventLoopRunner(
+		WithWebAccess(nil),
+		WithHTTPClientFunc(func(string) *http.Client { return &http.Client{Transport: spy} }),
+	)
+	if _, err := loop.RunAsync(fmt.Sprintf("fetchText(%q)", server.URL+"/loop")); err != nil {
+		t.Fatalf("RunAsync failed: %v", err)
+	}
+	if !spy.called {
+		t.Fatal("expected EventLoopRunner to use the client from the func")
+	}
+}
+
+func TestFetchDecodesContentEncoding(t *testing.T) {
+	const payload = `{"name":"goja","ok":true}`
+	encoders := map[string]func(io.Writer) io.WriteCloser{
+		"gzip":    func(w io.Writer) io.WriteCloser { return gzip.NewWriter(w) },
+		"deflate": func(w io.Writer) io.WriteCloser { return zlib.NewWriter(w) },
+		"br":      func(w io.Writer) io.WriteCloser { return brotli.NewWriter(w) },
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		encoding := strings.TrimPrefix(r.URL.Path, "/")
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Encoding", encoding)
+		enc := encoders[encoding](w)
+		io.WriteString(enc, payload)
+		enc.Close()
+	}))
+	defer server.Close()
+
+	// Disabling compression stops the transport from decoding gzip itself.
+	client := &http.Client{Transport: &http.Transport{DisableCompression: true}}
+	runner := New(WithWebAccess(&WebAccessConfig{Client: client, Timeout: time.Second}))
+
+	for encoding := range encoders {
+		result, err := runner.Call("fetchJSON", server.URL+"/"+encoding)
+		if err != nil {
+			t.Fatalf("fetchJSON with %s failed: %v", encoding, err)
+		}
+		data, ok := result.Export().(map[string]interface{})
+		if !ok || data["name"] != "goja" || data["ok"] != true {
+			t.Errorf("unexpected %s result: %#v", encoding, result.Export())
+		}
+	}
+}
+
+func TestWithLoopbackHandler(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/data", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"items":[1,2,3],"path":%q}`, r.URL.Path)
+	})
+
+	runner := New(
+		WithWebAccess(&WebAccessConfig{BlockPrivateNetworks: true, AllowedHosts: []string{"example.com"}}),
+		WithLoopbackHandler("/api/", mux),
+	)
+
+	result, err := runner.Eval(`var data = fetchJSON("/api/data"); data.path + ":" + data.items.length`)
+	if err != nil {
+		t.Fatalf("fetchJSON via loopback failed: %v", err)
+	}
+	if got := ExportString(result); got != "/api/data:3" {
+		t.Errorf("Expected '/api/data:3', got '%s'", got)
+	}
+
+	if _, err := runner.Call("fetchText", "/api/missing"); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("Expected 404 error for unknown loopback path, got %v", err)
+	}
+	if _, err := runner.Call("fetchText", "/other"); err == nil {
+		t.Error("Expected non-loopback relative URL to be rejected")
+	}
+
+	loop := NewEventLoopRunner(WithWebAccess(nil), WithLoopbackHandler("/api/", mux))
+	result, err = loop.RunAsync(`fetchJSON("/api/data").items[2]`)
+	if err != nil {
+		t.Fatalf("RunAsync failed: %v", err)
+	}
+	if got := ExportInt(result); got != 3 {
+		t.Errorf("Expected 3, got %d", got)
+	}
+}
+
+func TestFetchParityAcrossRunners(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ok":
+			fmt.Fprint(w, `{"ok":true}`)
+		case "/broken":
+			fmt.Fprint(w, `{not json`)
+		case "/error":
+			http.Error(w, "boom", http.StatusInternalServerError)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	cfg := &WebAccessConfig{Timeout: time.Second, MaxResponseBytes: 64}
+	runner := New(WithWebAccess(cfg))
+	loop := NewEventLoopRunner(WithWebAccess(cfg))
+
+	script := `(function() {
+		var out = [];
+		["/ok", "/broken", "/error", "/missing", "ftp://example.com/"].forEach(function(path) {
+			var url = path.indexOf("://") > 0 ? path : base + path;
+			try {
+				out.push(path + " => " + JSON.stringify(fetchJSON(url)));
+			} catch (e) {
+				out.push(path + " => " + String(e).replace(base, "BASE"));
+			}
+		});
+		return out.join("\n");
+	})()`
+
+	runner.SetGlobal("base", server.URL)
+	loop.SetGlobal("base", server.URL)
+
+	fromRunner, err := runner.Eval(script)
+	if err != nil {
+		t.Fatalf("Runner Eval failed: %v", err)
+	}
+	fromLoop, err := loop.RunAsync(script)
+	if err != nil {
+		t.Fatalf("EventLoopRunner RunAsync failed: %v", err)
+	}
+
+	if fromRunner.String() != fromLoop.String() {
+		t.Errorf("fetch behavior differs:\nRunner:\n%s\nEventLoopRunner:\n%s", fromRunner, fromLoop)
+	}
+	for _, want := range []string{`/ok => {"ok":true}`, "status 500", "status 404", "unsupported scheme"} {
+		if !strings.Contains(fromRunner.String(), want) {
+			t.Errorf("expected output to contain %q, got:\n%s", want, fromRunner)
+		}
+	}
+}
+
+func TestFetchMaxConcurrentFetches(t *testing.T) {
+	const limit = 2
+	var inFlight, peak int32
+	var mu sync.Mutex
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		inFlight++
+		if inFlight > peak {
+			peak = inFlight
+		}
+		mu.Unlock()
+
+		time.Sleep(20 * time.Millisecond)
+
+		mu.Lock()
+		inFlight--
+		mu.Unlock()
+		fmt.Fprint(w, "ok")
+	}))
+	defer server.Close()
+
+	opt := WithWebAccess(&WebAccessConfig{Timeout: 5 * time.Second, MaxConcurrentFetches: limit})
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 24)
+	for i := 0; i < 6; i++ {
+		runner := New(opt)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 4; j++ {
+				if _, err := runner.Call("fetchText", server.URL); err != nil {
+					errs <- err
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Errorf("fetchText failed: %v", err)
+	}
+	if peak > limit {
+		t.Errorf("observed %d concurrent fetches, limit is %d", peak, limit)
+	}
+}
+
+func TestFetchJSONCustomParser(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"features": ["a", "b",], "enabled": true,}`)
+	}))
+	defer server.Close()
+
+	strict := New(WithWebAccess(nil))
+	if _, err := strict.Call("fetchJSON", server.URL); err == nil {
+		t.Fatal("expected the default parser to reject trailing commas")
+	}
+
+	trailingCommas := regexp.MustCompile(`,\s*([}\]])`)
+	relaxed := New(WithWebAccess(&WebAccessConfig{
+		JSONParser: func(data []byte) (interface{}, error) {
+			var payload interface{}
+			err := json.Unmarshal(trailingCommas.ReplaceAll(data, []byte("$1")), &payload)
+			return payload, err
+		},
+	}))
+	relaxed.SetGlobal("url", server.URL)
+
+	result, err := relaxed.Eval(`const cfg = fetchJSON(url); cfg.features.length + ":" + cfg.enabled`)
+	if err != nil {
+		t.Fatalf("fetchJSON with a custom parser failed: %v", err)
+	}
+	if got := ExportString(result); got != "2:true" {
+		t.Errorf("expected 2:true, got %q", got)
+	}
+}
+
+func TestFetchBytes(t *testing.T) {
+	payload := []byte{0x89, 'P', 'N', 'G', 0x00, 0xff, 0x10}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		w.Write(payload)
+	}))
+	defer server.Close()
+
+	runner := New(WithWebAccess(&WebAccessConfig{Timeout: time.Second}))
+	runner.SetGlobal("url", server.URL)
+
+	result, err := runner.Eval(`
+		const body = fetchBytes(url);
+		[body instanceof Uint8Array, body.length, body[0], body[1], body[5]].join(",");
+	`)
+	if err != nil {
+		t.Fatalf("fetchBytes failed: %v", err)
+	}
+	if got, want := ExportString(result), fmt.Sprintf("true,%d,137,80,255", len(payload)); got != want {
+		t.Errorf("expected %s, got %s", want, got)
+	}
+
+	if _, err := runner.Call("fetchBytes", server.URL+"/\x00bad"); err == nil {
+		t.Error("expected an invalid URL to throw")
+	}
+
+	// Writing to the array must not corrupt the cached response.
+	cached := New(WithWebAccess(&WebAccessConfig{Timeout: time.Second}), WithFetchCache(time.Minute, 8))
+	cached.SetGlobal("url", server.URL)
+	result, err = cached.Eval(`
+		const first = fetchBytes(url);
+		first[1] = 74;
+		[fetchBytes(url)[1], fetchText(url).charCodeAt(1)].join(",");
+	`)
+	if err != nil {
+		t.Fatalf("fetchBytes with cache failed: %v", err)
+	}
+	if got := ExportString(result); got != "80,80" {
+		t.Errorf("expected the cached response to be unchanged, got %s", got)
+	}
+
+	textOnly := New(WithWebAccess(&WebAccessConfig{InstallText: true}))
+	result, err = textOnly.Eval("typeof fetchBytes")
+	if err != nil {
+		t.Fatalf("Eval() failed: %v", err)
+	}
+	if got := ExportString(result); got != "undefined" {
+		t.Errorf("expected fetchBytes to be opt-in when helpers are selected, got %s", got)
+	}
+}
@@ -0,0 +1,39 @@
+package jsrunner
+
+// Logger receives structured diagnostic events emitted by the package, such as
+// bundle builds, fetch cache hits, and script loads. keysAndValues alternate
+// between a string key and its value, matching the log/slog convention, so a
+// *slog.Logger satisfies the interface directly.
+type Logger interface {
+	Debug(msg string, keysAndValues ...interface{})
+	Info(msg string, keysAndValues ...interface{})
+	Warn(msg string, keysAndValues ...interface{})
+	Error(msg string, keysAndValues ...interface{})
+}
+
+// WithLogger routes internal diagnostic events to logger. By default events are
+// discarded.
+//
+// Example:
+//
+//	runner := jsrunner.New(jsrunner.WithLogger(slog.Default()))
+func WithLogger(logger Logger) Option {
+	return func(r *Runner) {
+		r.logger = logger
+	}
+}
+
+type noopLogger struct{}
+
+func (noopLogger) Debug(string, ...interface{}) {}
+func (noopLogger) Info(string, ...interface{})  {}
+func (noopLogger) Warn(string, ...interface{})  {}
+func (noopLogger) Error(string, ...interface{}) {}
+
+// loggerOrNoop returns logger, or a no-op logger when it is nil.
+func loggerOrNoop(logger Logger) Logger {
+	if logger == nil {
+		return noopLogger{}
+	}
+	return logger
+}
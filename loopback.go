@@ -0,0 +1,73 @@
+package jsrunner
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+)
+
+// WithLoopbackHandler serves fetches whose URL starts with prefix from h in
+// memory instead of going over the network. It lets server-side rendering code
+// load data from the application's own handlers, and lets tests exercise
+// fetch-based scripts against in-process handlers.
+//
+// The prefix may be a path ("/api/") so scripts can use relative URLs, or an
+// absolute URL ("https://api.example.com/"). Loopback requests skip the
+// AllowedHosts and BlockPrivateNetworks checks because they never leave the
+// process; MaxResponseBytes and the fetch cache still apply. When several
+// prefixes match, the longest wins. Web access must be enabled with
+// WithWebAccess.
+//
+// Example:
+//
+//	mux := http.NewServeMux()
+//	mux.HandleFunc("/api/data", dataHandler)
+//
+//	runner := jsrunner.New(
+//	    jsrunner.WithWebAccess(nil),
+//	    jsrunner.WithLoopbackHandler("/api/", mux),
+//	)
+//	result, err := runner.Eval(`fetchJSON("/api/data").items.length`)
+func WithLoopbackHandler(prefix string, h http.Handler) Option {
+	return func(r *Runner) {
+		r.loopback = append(r.loopback, loopbackRoute{prefix: prefix, handler: h})
+	}
+}
+
+type loopbackRoute struct {
+	prefix  string
+	handler http.Handler
+}
+
+type loopbackRoutes []loopbackRoute
+
+// match returns the handler registered for the longest prefix of url, or nil.
+func (routes loopbackRoutes) match(url string) http.Handler {
+	var best *loopbackRoute
+	for i := range routes {
+		route := &routes[i]
+		if strings.HasPrefix(url, route.prefix) && (best == nil || len(route.prefix) > len(best.prefix)) {
+			best = route
+		}
+	}
+	if best == nil {
+		return nil
+	}
+	return best.handler
+}
+
+// serveLoopback runs a GET request for url through h and returns the recorded
+// response.
+func serveLoopback(ctx context.Context, h http.Handler, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid fetch url %q: %w", url, err)
+	}
+	req.RemoteAddr = "127.0.0.1:0"
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec.Result(), nil
+}
@@ -0,0 +1,110 @@
+package jsrunner
+
+import (
+	"sync/atomic"
+
+	"github.com/dop251/goja"
+)
+
+// LoopStats is a snapshot of the activity counters of an EventLoopRunner.
+// Counters are cumulative over the lifetime of the runner.
+type LoopStats struct {
+	// TimeoutsScheduled is the number of SetTimeout calls.
+	TimeoutsScheduled int64
+
+	// IntervalsScheduled is the number of SetInterval calls.
+	IntervalsScheduled int64
+
+	// TimersFired is the number of times a callback scheduled through
+	// SetTimeout or SetInterval ran. Every tick of an interval counts.
+	TimersFired int64
+
+	// AsyncRuns is the number of RunAsync, RunAsyncWithTimeout, and
+	// RunProgramAsync calls.
+	AsyncRuns int64
+
+	// UnhandledRejections is the number of promises rejected without a
+	// handler attached. A rejection that gets a handler later is subtracted
+	// again, mirroring how engines retract unhandled rejection reports. To
+	// observe the rejections themselves, use WithPromiseRejectionTracker.
+	UnhandledRejections int64
+
+	// WatchdogInterrupts is the number of ticks interrupted by
+	// WithLoopWatchdog.
+	WatchdogInterrupts int64
+}
+
+// loopCounters holds the atomic counters behind LoopStats.
+type loopCounters struct {
+	timeoutsScheduled   int64
+	intervalsScheduled  int64
+	timersFired         int64
+	asyncRuns           int64
+	unhandledRejections int64
+	watchdogInterrupts  int64
+}
+
+// Stats returns a snapshot of the runner's activity counters. It is safe to
+// call from any goroutine, including while the loop is running.
+//
+// Timers created from JavaScript with setTimeout or setInterval are not
+// counted; only the Go wrappers are instrumented.
+//
+// Example:
+//
+//	stats := runner.Stats()
+//	log.Printf("timers fired: %d, unhandled rejections: %d",
+//	    stats.TimersFired, stats.UnhandledRejections)
+func (r *EventLoopRunner) Stats() LoopStats {
+	return LoopStats{
+		TimeoutsScheduled:   atomic.LoadInt64(&r.stats.timeoutsScheduled),
+		IntervalsScheduled:  atomic.LoadInt64(&r.stats.intervalsScheduled),
+		TimersFired:         atomic.LoadInt64(&r.stats.timersFired),
+		AsyncRuns:           atomic.LoadInt64(&r.stats.asyncRuns),
+		UnhandledRejections: atomic.LoadInt64(&r.stats.unhandledRejections),
+		WatchdogInterrupts:  atomic.LoadInt64(&r.stats.watchdogInterrupts),
+	}
+}
+
+// WithPromiseRejectionTracker registers fn to be told when a promise is
+// rejected without a handler and when a handler is attached to it later, as
+// goja.Runtime.SetPromiseRejectionTracker does. Use it to log or fail on
+// unhandled rejections.
+//
+// An EventLoopRunner installs its own tracker on the loop's runtime before
+// every tick to count LoopStats.UnhandledRejections, replacing any tracker set
+// directly with SetPromiseRejectionTracker; the tracker given here is called
+// after the counter is updated. On a Runner the tracker is installed once
+// during construction.
+//
+// Example:
+//
+//	runner := jsrunner.NewEventLoopRunner(jsrunner.WithPromiseRejectionTracker(
+//	    func(p *goja.Promise, op goja.PromiseRejectionOperation) {
+//	        if op == goja.PromiseRejectionReject {
+//	            log.Printf("unhandled rejection: %v", p.Result())
+//	        }
+//	    }))
+func WithPromiseRejectionTracker(fn goja.PromiseRejectionTracker) Option {
+	return func(r *Runner) {
+		r.rejectionTracker = fn
+	}
+}
+
+// trackRejections installs a promise rejection tracker that keeps the
+// UnhandledRejections counter up to date and then calls the tracker from
+// WithPromiseRejectionTracker, if any.
+func (r *EventLoopRunner) trackRejections(vm *goja.Runtime) {
+	next := r.rejectionTracker
+	vm.SetPromiseRejectionTracker(func(p *goja.Promise, op goja.PromiseRejectionOperation) {
+		switch op {
+		case goja.PromiseRejectionReject:
+			atomic.AddInt64(&r.stats.unhandledRejections, 1)
+		case goja.PromiseRejectionHandle:
+			atomic.AddInt64(&r.stats.unhandledRejections, -1)
+		}
+		if next != nil {
+			next(p, op)
+		}
+	})
+}
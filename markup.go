@@ -0,0 +1,130 @@
+package jsrunner
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// NormalizeMarkup parses an HTML fragment and re-serializes it in a canonical
+// form so SSR snapshot comparisons are stable across React versions:
+//   - attributes are sorted by name
+//   - comments (such as React's <!-- --> text separators) are dropped and the
+//     text around them merged
+//   - runs of whitespace collapse to a single space, text is trimmed at the
+//     start and end of its parent, and whitespace-only text nodes are dropped
+//
+// Content of pre, textarea, script, and style elements is left untouched. The
+// result is meant for comparison, not for serving to browsers.
+//
+// Example:
+//
+//	html, _ := app.Render(props)
+//	golden, _ := os.ReadFile("testdata/home.golden.html")
+//	if jsrunner.NormalizeMarkup(html) != jsrunner.NormalizeMarkup(string(golden)) {
+//	    t.Error("rendered markup does not match golden file")
+//	}
+func NormalizeMarkup(markup string) string {
+	context := &html.Node{Type: html.ElementNode, Data: "body", DataAtom: atom.Body}
+	nodes, err := html.ParseFragment(strings.NewReader(markup), context)
+	if err != nil {
+		return markup
+	}
+
+	root := &html.Node{Type: html.DocumentNode}
+	for _, node := range nodes {
+		root.AppendChild(node)
+	}
+	normalizeNode(root, false)
+
+	var sb strings.Builder
+	for node := root.FirstChild; node != nil; node = node.NextSibling {
+		if err := html.Render(&sb, node); err != nil {
+			return markup
+		}
+	}
+	return sb.String()
+}
+
+// normalizeNode canonicalizes node in place and reports whether it should be
+// kept.
+func normalizeNode(node *html.Node, preserve bool) bool {
+	switch node.Type {
+	case html.CommentNode:
+		return false
+	case html.TextNode:
+		return node.Data != ""
+	case html.ElementNode:
+		sort.SliceStable(node.Attr, func(i, j int) bool {
+			if node.Attr[i].Namespace != node.Attr[j].Namespace {
+				return node.Attr[i].Namespace < node.Attr[j].Namespace
+			}
+			return node.Attr[i].Key < node.Attr[j].Key
+		})
+		switch node.DataAtom {
+		case atom.Pre, atom.Textarea, atom.Script, atom.Style:
+			preserve = true
+		}
+	}
+
+	for child := node.FirstChild; child != nil; {
+		next := child.NextSibling
+		if !normalizeNode(child, preserve) {
+			node.RemoveChild(child)
+		}
+		child = next
+	}
+
+	if !preserve {
+		mergeText(node)
+	}
+	return true
+}
+
+// mergeText joins adjacent text children of node, collapses their whitespace,
+// and trims text at the boundaries of node.
+func mergeText(node *html.Node) {
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		for child.Type == html.TextNode && child.NextSibling != nil && child.NextSibling.Type == html.TextNode {
+			child.Data += child.NextSibling.Data
+			node.RemoveChild(child.NextSibling)
+		}
+	}
+
+	for child := node.FirstChild; child != nil; {
+		next := child.NextSibling
+		if child.Type == html.TextNode {
+			text := collapseWhitespace(child.Data)
+			if child == node.FirstChild {
+				text = strings.TrimLeft(text, " ")
+			}
+			if child == node.LastChild {
+				text = strings.TrimRight(text, " ")
+			}
+			if strings.TrimSpace(text) == "" {
+				node.RemoveChild(child)
+			} else {
+				child.Data = text
+			}
+		}
+		child = next
+	}
+}
+
+func collapseWhitespace(text string) string {
+	fields := strings.Fields(text)
+	if len(fields) == 0 {
+		return ""
+	}
+	collapsed := strings.Join(fields, " ")
+	if strings.TrimLeftFunc(text, unicode.IsSpace) != text {
+		collapsed = " " + collapsed
+	}
+	if strings.TrimRightFunc(text, unicode.IsSpace) != text {
+		collapsed += " "
+	}
+	return collapsed
+}
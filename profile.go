@@ -0,0 +1,225 @@
+package jsrunner
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/dop251/goja"
+)
+
+// FuncStat holds the profiling counters of one JavaScript function.
+type FuncStat struct {
+	// Calls is the number of completed calls, including calls that threw.
+	Calls int64
+
+	// Total is the cumulative wall time spent in the function, including the
+	// functions it called. Recursive calls are counted at every level.
+	Total time.Duration
+}
+
+// WithProfiler counts calls and measures the cumulative time of every
+// function a script defines as a global (with a function declaration or var
+// at the top level), whether it is called from Go via Call or from other
+// JavaScript code. Read the counters with Profile.
+//
+// goja has no function entry hooks, so the profiler replaces each such global
+// with a wrapper after every Eval, Call, or LoadScript call that defines it.
+// Functions installed by the host (SetGlobal, WithGlobals, feature options)
+// and the JavaScript built-ins are not instrumented, and methods or inner
+// functions are attributed to the global function that calls them. Wrapping adds
+// two native calls to every call, so enable the option while investigating
+// hot paths rather than in production. It applies to Runner only;
+// NewEventLoopRunner ignores it and logs a warning.
+//
+// Example:
+//
+//	runner := jsrunner.New(jsrunner.WithProfiler())
+//	runner.LoadScriptString(bundle)
+//	runner.Call("renderPage", props)
+//	for name, stat := range runner.Profile() {
+//	    fmt.Printf("%s: %d calls, %v\n", name, stat.Calls, stat.Total)
+//	}
+func WithProfiler() Option {
+	return func(r *Runner) {
+		r.profiler = &profiler{}
+	}
+}
+
+// Profile returns a snapshot of the counters collected by WithProfiler,
+// keyed by global function name. It returns nil when the runner was created
+// without WithProfiler. Profile may be called while the runner is busy.
+func (r *Runner) Profile() map[string]FuncStat {
+	if r.profiler == nil {
+		return nil
+	}
+	return r.profiler.snapshot()
+}
+
+// profileWrapperSource builds the wrapper installed in place of a profiled
+// global. It preserves this, new.target, the prototype, name, and length.
+const profileWrapperSource = `(function(name, fn, start, stop) {
+	var wrapper = function() {
+		var began = start();
+		try {
+			return new.target ? Reflect.construct(fn, arguments, new.target) : fn.apply(this, arguments);
+		} finally {
+			stop(name, began);
+		}
+	};
+	wrapper.prototype = fn.prototype;
+	Object.defineProperty(wrapper, "name", { value: fn.name });
+	Object.defineProperty(wrapper, "length", { value: fn.length });
+	return wrapper;
+})`
+
+var profileWrapperProgram = goja.MustCompile("profiler.js", profileWrapperSource, true)
+
+// profileGlobalsSource builds the function instrument uses to list global
+// functions. It reads property descriptors instead of values, so getters a
+// script defined on the global object are never run, and captures the
+// built-ins it needs before any script can replace them.
+const profileGlobalsSource = `(function() {
+	var g = globalThis;
+	var names = Object.getOwnPropertyNames;
+	var describe = Object.getOwnPropertyDescriptor;
+	var apply = Reflect.apply;
+	var forEach = Array.prototype.forEach;
+	var push = Array.prototype.push;
+	return function() {
+		var found = [];
+		apply(forEach, names(g), [function(name) {
+			var desc = describe(g, name);
+			if (desc && typeof desc.value === "function") {
+				apply(push, found, [name, desc.value]);
+			}
+		}]);
+		return found;
+	};
+})()`
+
+var profileGlobalsProgram = goja.MustCompile("profiler-globals.js", profileGlobalsSource, true)
+
+// profiler accumulates per-function statistics for WithProfiler.
+type profiler struct {
+	mu    sync.Mutex
+	stats map[string]*FuncStat
+	epoch time.Time
+
+	// Fields below are only used by the runtime's goroutine.
+	builtin  map[string]struct{}
+	wrapped  map[*goja.Object]struct{}
+	wrapFunc goja.Callable
+	globals  goja.Callable
+	vm       *goja.Runtime
+}
+
+// attach binds the profiler to vm. The globals present at that point (the
+// built-ins and the host's globals) are never instrumented.
+func (p *profiler) attach(vm *goja.Runtime) {
+	p.vm = vm
+	p.epoch = time.Now()
+	p.builtin = make(map[string]struct{})
+	p.wrapped = make(map[*goja.Object]struct{})
+	p.wrapFunc = nil
+	p.globals = nil
+	for _, name := range vm.GlobalObject().GetOwnPropertyNames() {
+		p.builtin[name] = struct{}{}
+	}
+	if lister, err := vm.RunProgram(profileGlobalsProgram); err == nil {
+		p.globals, _ = goja.AssertFunction(lister)
+	}
+}
+
+// instrument wraps every global function defined since the last call. Names
+// in host are globals set by the host and are left alone. Only data
+// properties are considered: accessors are skipped without calling them, and
+// any exception thrown while instrumenting is dropped rather than surfacing
+// from the operation that triggered it.
+func (p *profiler) instrument(host map[string]interface{}) {
+	if p.globals == nil {
+		return
+	}
+	listed, err := p.globals(goja.Undefined())
+	if err != nil {
+		return
+	}
+	found, ok := listed.(*goja.Object)
+	if !ok {
+		return
+	}
+	global := p.vm.GlobalObject()
+	length := found.Get("length").ToInteger()
+	for i := int64(0); i+1 < length; i += 2 {
+		name := found.Get(strconv.FormatInt(i, 10)).String()
+		if _, ok := p.builtin[name]; ok {
+			continue
+		}
+		if _, ok := host[name]; ok {
+			continue
+		}
+		fn, ok := found.Get(strconv.FormatInt(i+1, 10)).(*goja.Object)
+		if !ok {
+			continue
+		}
+		if _, ok := p.wrapped[fn]; ok {
+			continue
+		}
+		wrapper, err := p.wrap(name, fn)
+		if err != nil {
+			continue
+		}
+		if err := global.Set(name, wrapper); err != nil {
+			continue
+		}
+		p.wrapped[wrapper] = struct{}{}
+	}
+}
+
+// wrap returns a function that records each call of fn under name.
+func (p *profiler) wrap(name string, fn *goja.Object) (*goja.Object, error) {
+	if p.wrapFunc == nil {
+		factory, err := p.vm.RunProgram(profileWrapperProgram)
+		if err != nil {
+			return nil, err
+		}
+		p.wrapFunc, _ = goja.AssertFunction(factory)
+	}
+
+	start := func() float64 {
+		return float64(time.Since(p.epoch))
+	}
+	stop := func(name string, began float64) {
+		p.record(name, time.Since(p.epoch)-time.Duration(began))
+	}
+	wrapper, err := p.wrapFunc(goja.Undefined(), p.vm.ToValue(name), fn, p.vm.ToValue(start), p.vm.ToValue(stop))
+	if err != nil {
+		return nil, err
+	}
+	return wrapper.ToObject(p.vm), nil
+}
+
+func (p *profiler) record(name string, elapsed time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	stat, ok := p.stats[name]
+	if !ok {
+		if p.stats == nil {
+			p.stats = make(map[string]*FuncStat)
+		}
+		stat = &FuncStat{}
+		p.stats[name] = stat
+	}
+	stat.Calls++
+	stat.Total += elapsed
+}
+
+func (p *profiler) snapshot() map[string]FuncStat {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	stats := make(map[string]FuncStat, len(p.stats))
+	for name, stat := range p.stats {
+		stats[name] = *stat
+	}
+	return stats
+}
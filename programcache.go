@@ -0,0 +1,114 @@
+package jsrunner
+
+import (
+	"container/list"
+	"strings"
+	"sync"
+
+	"github.com/dop251/goja"
+)
+
+// WithProgramCache keeps up to maxEntries compiled programs keyed by their
+// source, so Eval, EvalWith, and LoadScript* skip parsing when the same code is
+// executed again. This speeds up servers that evaluate a bounded set of
+// templated expressions repeatedly. The least recently used program is evicted
+// first. EventLoopRunner has no program cache; NewEventLoopRunner ignores the
+// option and logs a warning.
+//
+// Example:
+//
+//	runner := jsrunner.New(jsrunner.WithProgramCache(256))
+//	for _, row := range rows {
+//	    runner.SetGlobal("row", row)
+//	    runner.Eval("row.price * row.quantity") // parsed once
+//	}
+func WithProgramCache(maxEntries int) Option {
+	return func(r *Runner) {
+		r.programCache = newProgramCache(maxEntries)
+	}
+}
+
+type programCacheKey struct {
+	name string
+	code string
+}
+
+type programCacheEntry struct {
+	key     programCacheKey
+	program *goja.Program
+}
+
+// programCache is a size-bounded LRU of compiled programs keyed by source.
+type programCache struct {
+	mu         sync.Mutex
+	maxEntries int
+	entries    map[programCacheKey]*list.Element
+	order      *list.List
+}
+
+func newProgramCache(maxEntries int) *programCache {
+	return &programCache{
+		maxEntries: maxEntries,
+		entries:    make(map[programCacheKey]*list.Element),
+		order:      list.New(),
+	}
+}
+
+func (c *programCache) get(key programCacheKey) (*goja.Program, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(elem)
+	return elem.Value.(*programCacheEntry).program, true
+}
+
+func (c *programCache) put(key programCacheKey, program *goja.Program) {
+	if c.maxEntries <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.entries[key]; ok {
+		elem.Value.(*programCacheEntry).program = program
+		c.order.MoveToFront(elem)
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(&programCacheEntry{key: key, program: program})
+	for c.order.Len() > c.maxEntries {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*programCacheEntry).key)
+	}
+}
+
+// compile compiles code under name honoring the runner's strict mode, reusing
+// a cached program when WithProgramCache is enabled.
+func (r *Runner) compile(name, code string) (*goja.Program, error) {
+	if r.programCache == nil {
+		return goja.Compile(name, r.prepareSource(code), r.strictMode)
+	}
+
+	key := programCacheKey{name: name, code: code}
+	if program, ok := r.programCache.get(key); ok {
+		return program, nil
+	}
+	// code may share memory with a caller's byte slice (see EvalBytes). The
+	// cache outlives the call, so the key and the program must own a copy:
+	// otherwise a reused buffer would rewrite the key and map the new source
+	// to the old program.
+	code = strings.Clone(code)
+	key.code = code
+	program, err := goja.Compile(name, r.prepareSource(code), r.strictMode)
+	if err != nil {
+		return nil, err
+	}
+	r.programCache.put(key, program)
+	return program, nil
+}
@@ -0,0 +1,36 @@
+package jsrunner
+
+import (
+	"math/rand/v2"
+
+	"github.com/dop251/goja"
+)
+
+// WithRandomSeed replaces the source behind Math.random with a deterministic
+// PRNG seeded by seed, so two runners created with the same seed produce the
+// same sequence on every run. Use it for snapshot tests of components that
+// render random ids or shuffled content; leave it off in production, since
+// the sequence is predictable.
+//
+// The sequence continues across calls on the same runner, while each
+// EvalIsolated call starts it afresh from seed. It applies to Runner only;
+// NewEventLoopRunner ignores it and logs a warning.
+//
+// Example:
+//
+//	a := jsrunner.New(jsrunner.WithRandomSeed(42))
+//	b := jsrunner.New(jsrunner.WithRandomSeed(42))
+//	x, _ := a.Eval("Math.random()")
+//	y, _ := b.Eval("Math.random()")
+//	// jsrunner.ExportFloat(x) == jsrunner.ExportFloat(y)
+func WithRandomSeed(seed int64) Option {
+	return func(r *Runner) {
+		r.randomSeed = &seed
+	}
+}
+
+// seededRandSource returns a Math.random source producing the sequence
+// determined by seed.
+func seededRandSource(seed int64) goja.RandSource {
+	return rand.New(rand.NewPCG(uint64(seed), 0)).Float64
+}
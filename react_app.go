@@ -1,14 +1,64 @@
 package jsrunner
 
 import (
+	"bytes"
+	"encoding"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/boomhut/goja-runner/internal/bundler"
+	"github.com/dop251/goja"
+	"github.com/santhosh-tekuri/jsonschema/v6"
 )
 
+// ErrRenderAppNotFunction is returned by NewReactApp when the SSR entry
+// defines renderApp (or ReactAppOptions.RenderFunctionName) as something
+// other than a function.
+var ErrRenderAppNotFunction = errors.New("renderApp is not a function")
+
+// BundleError is returned when esbuild cannot compile an entry point, for
+// example because of a TypeScript syntax error or an unresolvable import. No
+// script has run in the runner when it is returned.
+type BundleError struct {
+	Err error
+}
+
+func (e *BundleError) Error() string { return e.Err.Error() }
+
+func (e *BundleError) Unwrap() error { return e.Err }
+
+// RuntimeLoadError is returned when a polyfill or the compiled SSR bundle
+// throws while being executed in the runner, such as an exception at module
+// scope. Use errors.As to tell it apart from a BundleError.
+//
+// Example:
+//
+//	var loadErr *jsrunner.RuntimeLoadError
+//	if errors.As(err, &loadErr) {
+//	    log.Printf("%s threw during boot: %v", loadErr.Script, loadErr.Err)
+//	}
+type RuntimeLoadError struct {
+	// Script names what was being loaded: the SSR bundle file name,
+	// "polyfill[i]" for inline polyfills, or "polyfill <file>" for files
+	// from PolyfillDir.
+	Script string
+	Err    error
+}
+
+func (e *RuntimeLoadError) Error() string { return "load " + e.Script + ": " + e.Err.Error() }
+
+func (e *RuntimeLoadError) Unwrap() error { return e.Err }
+
 // ReactAppOptions configures the creation of a ReactApp helper.
 type ReactAppOptions struct {
 	// Runner allows supplying an existing Runner. When nil, a new runner is
@@ -19,37 +69,215 @@ type ReactAppOptions struct {
 	RunnerOptions []Option
 
 	// Polyfills are executed prior to loading the bundled React code. Use
-	// this to install globals like TextEncoder/TextDecoder.
+	// this to install globals the runtime lacks; TextEncoder/TextDecoder can
+	// instead be installed natively with RunnerOptions: WithTextCodecs().
 	Polyfills []string
 
+	// PolyfillDir names a directory whose .js files are executed, in lexical
+	// order, after Polyfills and before the SSR bundle. It keeps large
+	// polyfill sets out of Go source. Subdirectories are not traversed.
+	PolyfillDir string
+
 	// SSREntry and ClientEntry contain the TypeScript/JSX source fed to
 	// esbuild. These must define the renderApp function (server) and the
 	// hydrateRoot bootstrap (client).
 	SSREntry    string
 	ClientEntry string
 
+	// PrecompiledSSR and PrecompiledClient supply bundles built ahead of
+	// time, for example at deploy time from ReactApp.SSRBundle and
+	// ReactApp.ClientBundle. When PrecompiledSSR is set, esbuild is skipped:
+	// the program is run instead of bundling SSREntry, ClientBundle returns
+	// PrecompiledClient, and the entries and build options (ReactVersion,
+	// SourceMap, Metafile, JSX, CSS, and the like) are ignored.
+	// UpdateClientEntry and UpdateSSREntry are unavailable on such an app.
+	PrecompiledSSR    *goja.Program
+	PrecompiledClient string
+
 	// ReactVersion controls which React release is fetched from esm.sh.
 	// Defaults to a sensible version when empty.
 	ReactVersion string
+
+	// SourceMap attaches the esbuild-produced source map of the SSR bundle
+	// so RewriteStack can map errors back to the original sources.
+	SourceMap bool
+
+	// Metafile records bundle sizes and the remote modules pulled in by
+	// esbuild, exposed via ReactApp.BundleMeta.
+	Metafile bool
+
+	// JSXMode selects the JSX transform: JSXAutomatic (default), JSXClassic
+	// for React.createElement or `/** @jsx h */` pragma codebases, or
+	// JSXPreserve.
+	JSXMode JSXMode
+
+	// JSXImportSource selects the package providing the JSX runtime.
+	// Defaults to "react"; use "preact" to bundle a Preact app, rendering
+	// on the server with preact-render-to-string.
+	JSXImportSource string
+
+	// DevMode trades bundle size for debuggability: output is not minified,
+	// function names are kept, NODE_ENV is "development", and an inline
+	// source map lets goja report original positions in stack traces.
+	DevMode bool
+
+	// ResolveDir is the directory relative imports in the entries, such as
+	// "./styles.css", are resolved from. Defaults to the working directory.
+	ResolveDir string
+
+	// CSSMode selects how CSS imports are compiled. CSSExtract (default)
+	// turns them into modules exporting the stylesheet text; CSSInject also
+	// makes the client bundle add a <style> element to the page. In both
+	// modes the collected CSS is available from ReactApp.CSS.
+	CSSMode CSSMode
+
+	// InlineModules maps bare import specifiers to module sources that are
+	// bundled in place of CDN or file-system lookups, for hermetic builds.
+	InlineModules map[string]string
+
+	// Define substitutes build-time constants in both bundles, mapping
+	// identifiers to JavaScript expressions (quote string values). It is
+	// merged over process.env.NODE_ENV, which is only replaced when named.
+	Define map[string]string
+
+	// CheckHydration warns through the runner's logger when none of the
+	// app's own client modules calls hydrateRoot, hydrate, or createRoot, a
+	// common mistake that otherwise leaves the page silently non-interactive.
+	// Library code fetched from the CDN is not inspected.
+	CheckHydration bool
+
+	// PropsSchema is an optional JSON Schema document. When set, Render
+	// validates props against it before invoking renderApp and returns a
+	// descriptive error on mismatch.
+	PropsSchema []byte
+
+	// PropsFieldNameMapper renames Go struct fields found in render props,
+	// for example goja.TagFieldNameMapper("js", true) or
+	// goja.UncapFieldNameMapper() for camelCase props from PascalCase
+	// structs. It applies only to the props built by Render, RenderWith,
+	// RenderCached, and RenderStruct; the rest of the runtime keeps its
+	// default mapping. Props are copied into plain objects and arrays, so
+	// renderApp sees data rather than wrapped Go values.
+	PropsFieldNameMapper goja.FieldNameMapper
+
+	// ShareBundles looks the bundles up in a process-wide cache keyed by a
+	// hash of the entries and every build option, so apps created from
+	// identical sources (per-tenant apps running the same code, say) run
+	// esbuild only once. Concurrent NewReactApp calls wait for a single
+	// build, and failed builds are not cached. A cache hit is rebuilt when
+	// a file imported from ResolveDir has a different modification time or
+	// size than when the cached bundles were built. The cache is opt-in
+	// because it keeps bundles alive for the life of the process; release
+	// them with ClearSharedBundleCache.
+	ShareBundles bool
+
+	// RenderCacheSize bounds how many entries RenderCached keeps; the least
+	// recently used entry is evicted first. Defaults to 256.
+	RenderCacheSize int
+
+	// PropsGlobalName is the global the render methods store props in
+	// before calling the render function. Defaults to "SERVER_PROPS"; change
+	// it when the SSR entry or a polyfill already uses that name.
+	PropsGlobalName string
+
+	// RenderFunctionName is the global function the SSR entry defines and
+	// the render methods call with the props. Defaults to "renderApp", for
+	// entries that follow another framework's convention. Both names must
+	// be plain JavaScript identifiers, not reserved words.
+	RenderFunctionName string
 }
 
+const (
+	defaultRenderCacheSize    = 256
+	defaultPropsGlobalName    = "SERVER_PROPS"
+	defaultRenderFunctionName = "renderApp"
+)
+
+// CSSMode selects how CSS imports in the entry points are compiled.
+type CSSMode = bundler.CSSMode
+
+// Supported CSS modes.
+const (
+	CSSExtract = bundler.CSSExtract
+	CSSInject  = bundler.CSSInject
+)
+
+// JSXMode selects how JSX in the entry points is compiled.
+type JSXMode = bundler.JSXMode
+
+// Supported JSX modes.
+const (
+	JSXAutomatic = bundler.JSXAutomatic
+	JSXClassic   = bundler.JSXClassic
+	JSXPreserve  = bundler.JSXPreserve
+)
+
+// BundleMeta reports the size of each bundle and the remote dependencies that
+// were resolved while building them.
+type BundleMeta = bundler.BundleMeta
+
 // ReactApp wires a Runner together with a bundled React application so it can
 // render HTML on the server while exposing a hydration bundle for browsers.
 type ReactApp struct {
-	runner       *Runner
-	clientBundle string
-	mu           sync.Mutex
+	runner      *Runner
+	bundles     atomic.Pointer[bundler.ReactBundles]
+	propsSchema *jsonschema.Schema
+	propsMapper goja.FieldNameMapper
+	propsGlobal string
+	renderFunc  string
+	renderCall  string
+	renderCache *fetchCache
+	renderGen   atomic.Uint64
+	bundleTime  time.Duration
+	loadTime    time.Duration
+	mu          sync.Mutex
 }
 
 // NewReactApp bundles the supplied entry points and installs them into the
 // provided (or newly created) Runner. The resulting ReactApp can render props
 // via renderApp(props) and expose the compiled client bundle.
+//
+// Compilation failures are returned as *BundleError and exceptions thrown
+// while executing polyfills or the SSR bundle as *RuntimeLoadError, so callers
+// can tell a build problem from a script that fails at boot.
+//
+// When opts.PrecompiledSSR is set, no bundling takes place and the program is
+// loaded instead.
 func NewReactApp(opts ReactAppOptions) (*ReactApp, error) {
-	if strings.TrimSpace(opts.SSREntry) == "" {
-		return nil, errors.New("react ssr entry is required")
+	precompiled := opts.PrecompiledSSR != nil
+	if !precompiled {
+		if opts.PrecompiledClient != "" {
+			return nil, errors.New("react precompiled client bundle requires PrecompiledSSR")
+		}
+		if strings.TrimSpace(opts.SSREntry) == "" {
+			return nil, errors.New("react ssr entry is required")
+		}
+		if strings.TrimSpace(opts.ClientEntry) == "" {
+			return nil, errors.New("react client entry is required")
+		}
+	}
+
+	propsGlobal := opts.PropsGlobalName
+	if propsGlobal == "" {
+		propsGlobal = defaultPropsGlobalName
 	}
-	if strings.TrimSpace(opts.ClientEntry) == "" {
-		return nil, errors.New("react client entry is required")
+	renderFunc := opts.RenderFunctionName
+	if renderFunc == "" {
+		renderFunc = defaultRenderFunctionName
+	}
+	for _, name := range []string{propsGlobal, renderFunc} {
+		if !isIdentifier(name) {
+			return nil, fmt.Errorf("react global name %q is not a valid identifier", name)
+		}
+	}
+
+	var propsSchema *jsonschema.Schema
+	if len(opts.PropsSchema) > 0 {
+		schema, err := compilePropsSchema(opts.PropsSchema)
+		if err != nil {
+			return nil, fmt.Errorf("compile props schema: %w", err)
+		}
+		propsSchema = schema
 	}
 
 	r := opts.Runner
@@ -57,54 +285,672 @@ func NewReactApp(opts ReactAppOptions) (*ReactApp, error) {
 		r = New(opts.RunnerOptions...)
 	}
 
+	polyfillStart := time.Now()
 	for idx, script := range opts.Polyfills {
 		if strings.TrimSpace(script) == "" {
 			continue
 		}
 		if err := r.LoadScriptString(script); err != nil {
-			return nil, fmt.Errorf("load polyfill[%d]: %w", idx, err)
+			return nil, &RuntimeLoadError{Script: fmt.Sprintf("polyfill[%d]", idx), Err: err}
 		}
 	}
+	if opts.PolyfillDir != "" {
+		if err := loadPolyfillDir(r, opts.PolyfillDir); err != nil {
+			return nil, err
+		}
+	}
+	loadTime := time.Since(polyfillStart)
 
-	bundles, err := bundler.BuildReactBundles(bundler.ReactOptions{
-		ReactVersion: opts.ReactVersion,
-		SSREntry:     opts.SSREntry,
-		ClientEntry:  opts.ClientEntry,
-	})
+	var bundles *bundler.ReactBundles
+	var bundleTime time.Duration
+	var err error
+	if precompiled {
+		bundles = bundler.PrecompiledBundles(opts.PrecompiledClient)
+	} else if bundles, bundleTime, err = buildReactBundles(r, opts); err != nil {
+		return nil, err
+	}
+
+	loadStart := time.Now()
+	if precompiled {
+		_, err = r.RunProgram(opts.PrecompiledSSR)
+	} else {
+		err = r.runNamedScript(bundler.SSRBundleName, bundles.SSR)
+	}
 	if err != nil {
+		return nil, &RuntimeLoadError{Script: bundler.SSRBundleName, Err: err}
+	}
+	loadTime += time.Since(loadStart)
+
+	if err := checkRenderApp(r, renderFunc); err != nil {
 		return nil, err
 	}
 
-	if err := r.LoadScriptString(bundles.SSR); err != nil {
-		return nil, fmt.Errorf("load SSR bundle: %w", err)
+	cacheSize := opts.RenderCacheSize
+	if cacheSize <= 0 {
+		cacheSize = defaultRenderCacheSize
+	}
+
+	app := &ReactApp{
+		runner:      r,
+		propsSchema: propsSchema,
+		propsMapper: opts.PropsFieldNameMapper,
+		propsGlobal: propsGlobal,
+		renderFunc:  renderFunc,
+		renderCall:  renderFunc + "(" + propsGlobal + ")",
+		renderCache: newFetchCache(0, cacheSize),
+		bundleTime:  bundleTime,
+		loadTime:    loadTime,
+	}
+	app.bundles.Store(bundles)
+	return app, nil
+}
+
+// buildReactBundles bundles the entries of opts with esbuild, or takes the
+// bundles from the shared cache when opts.ShareBundles is set, and logs the
+// result through r's logger.
+func buildReactBundles(r *Runner, opts ReactAppOptions) (*bundler.ReactBundles, time.Duration, error) {
+	buildOpts := bundler.ReactOptions{
+		ReactVersion:    opts.ReactVersion,
+		SSREntry:        opts.SSREntry,
+		ClientEntry:     opts.ClientEntry,
+		SourceMap:       opts.SourceMap,
+		Metafile:        opts.Metafile,
+		JSXMode:         opts.JSXMode,
+		JSXImportSource: opts.JSXImportSource,
+		DevMode:         opts.DevMode,
+		ResolveDir:      opts.ResolveDir,
+		CSSMode:         opts.CSSMode,
+		InlineModules:   opts.InlineModules,
+		Define:          opts.Define,
+		CheckHydration:  opts.CheckHydration,
+	}
+
+	buildStart := time.Now()
+	var bundles *bundler.ReactBundles
+	var hit bool
+	var err error
+	if opts.ShareBundles {
+		bundles, hit, err = sharedBundles.get(buildOpts, func() (*bundler.ReactBundles, error) {
+			return bundler.BuildReactBundles(buildOpts)
+		})
+	} else {
+		bundles, err = bundler.BuildReactBundles(buildOpts)
+	}
+	if err != nil {
+		return nil, 0, &BundleError{Err: err}
+	}
+	bundleTime := time.Since(buildStart)
+	if hit {
+		r.logger.Debug("bundle cache hit", "duration", bundleTime)
+	} else {
+		r.logger.Info("bundle built",
+			"duration", bundleTime,
+			"ssrBytes", len(bundles.SSR),
+			"clientBytes", len(bundles.Client),
+		)
+	}
+	logBundleWarnings(r.logger, bundles)
+	return bundles, bundleTime, nil
+}
+
+// checkRenderApp verifies that the loaded SSR bundle defined the render
+// function name (renderApp by default) as a function.
+//
+// It reads the runtime directly, so the caller must hold the runner's lock or
+// not have shared the runner yet.
+func checkRenderApp(r *Runner, name string) error {
+	var value goja.Value
+	var arity int64
+	if ex := r.vm.Try(func() {
+		value = r.vm.GlobalObject().Get(name)
+		if _, ok := goja.AssertFunction(value); ok {
+			arity = value.ToObject(r.vm).Get("length").ToInteger()
+		}
+	}); ex != nil {
+		return fmt.Errorf("%s not defined: %w", name, ex)
+	}
+	if value == nil || goja.IsUndefined(value) {
+		return fmt.Errorf("%s not defined: global %s is undefined", name, name)
+	}
+	if _, ok := goja.AssertFunction(value); !ok {
+		kind, _ := r.vm.RunString("typeof " + name)
+		return fmt.Errorf("%w: the SSR entry defines it as a %s; assign a function such as (globalThis as any).%s = (props) => ...",
+			ErrRenderAppNotFunction, ExportString(kind), name)
+	}
+	if arity != 1 {
+		r.logger.Warn("render function should accept a single props argument", "name", name, "arity", arity)
 	}
+	return nil
+}
 
-	if err := assertGlobalExists(r, "renderApp"); err != nil {
-		return nil, fmt.Errorf("renderApp not defined: %w", err)
+// UpdateClientEntry rebuilds only the client bundle from src, reusing the
+// remote modules downloaded at construction, and swaps it in for subsequent
+// ClientBundle calls. The SSR bundle and the runner are left untouched. It is
+// meant for the edit-refresh loop in development.
+//
+// On error the previous client bundle stays in place. Calls to
+// UpdateClientEntry and UpdateSSREntry must not overlap.
+//
+// Example:
+//
+//	if err := app.UpdateClientEntry(newClientSource); err != nil {
+//	    log.Printf("client rebuild failed: %v", err)
+//	}
+func (ra *ReactApp) UpdateClientEntry(src string) error {
+	start := time.Now()
+	bundles, err := ra.bundles.Load().WithClientEntry(src)
+	if err != nil {
+		return &BundleError{Err: err}
 	}
+	ra.bundles.Store(bundles)
+	ra.resetRenderCache()
 
-	return &ReactApp{runner: r, clientBundle: bundles.Client}, nil
+	ra.runner.logger.Info("client bundle rebuilt",
+		"duration", time.Since(start),
+		"clientBytes", len(bundles.Client),
+	)
+	logBundleWarnings(ra.runner.logger, bundles)
+	return nil
+}
+
+// UpdateSSREntry rebuilds only the SSR bundle from src and loads it into the
+// runner, replacing renderApp for subsequent renders. In-flight renders finish
+// against the previous bundle. The client bundle is left untouched.
+//
+// When bundling fails nothing changes. When the new bundle throws while
+// loading, or does not define renderApp as a function, the error is returned,
+// the globals it assigned are rolled back as in LoadScriptsAtomic, and the
+// previous bundle keeps rendering. The runner stays locked from loading to
+// rollback, so renders and, on a WithSynchronized runner, other calls never
+// observe a rejected bundle. Calls to UpdateClientEntry and UpdateSSREntry
+// must not overlap.
+//
+// Example:
+//
+//	if err := app.UpdateSSREntry(newSSRSource); err != nil {
+//	    log.Printf("ssr rebuild failed: %v", err)
+//	}
+func (ra *ReactApp) UpdateSSREntry(src string) error {
+	start := time.Now()
+	bundles, err := ra.bundles.Load().WithSSREntry(src)
+	if err != nil {
+		return &BundleError{Err: err}
+	}
+
+	// Hold both locks from the snapshot to the rollback, so no render or
+	// other call on a synchronized runner observes a half-loaded or
+	// half-restored global object.
+	ra.mu.Lock()
+	defer ra.mu.Unlock()
+	ra.runner.syncLock()
+	defer ra.runner.syncUnlock()
+
+	rollback, err := ra.runner.snapshotGlobalObject()
+	if err != nil {
+		return fmt.Errorf("failed to snapshot globals: %w", err)
+	}
+	err = ra.runner.runNamedScriptLocked(bundler.SSRBundleName, bundles.SSR)
+	if err != nil {
+		err = &RuntimeLoadError{Script: bundler.SSRBundleName, Err: err}
+	} else {
+		err = checkRenderApp(ra.runner, ra.renderFunc)
+	}
+	if err != nil {
+		if rollbackErr := rollback(); rollbackErr != nil {
+			return fmt.Errorf("%w (rollback failed: %v)", err, rollbackErr)
+		}
+		return err
+	}
+	ra.bundles.Store(bundles)
+	ra.resetRenderCache()
+
+	ra.runner.logger.Info("ssr bundle rebuilt",
+		"duration", time.Since(start),
+		"ssrBytes", len(bundles.SSR),
+	)
+	return nil
 }
 
 // Render executes renderApp inside the underlying Runner with the supplied
-// props and returns the HTML markup.
+// props and returns the HTML markup. When the app was created with
+// ReactAppOptions.PropsSchema, props are validated first and renderApp is not
+// invoked for invalid input.
+//
+// nil props are passed to renderApp as an empty object rather than null, so
+// components that destructure their props do not throw a TypeError. The same
+// applies to every render method that takes a props map.
 func (ra *ReactApp) Render(props map[string]interface{}) (string, error) {
+	return ra.RenderWith(RenderOptions{}, props)
+}
+
+// RenderCached returns the markup cached under key if it was rendered less than
+// ttl ago, and otherwise renders props like Render and caches the result for
+// ttl. Use it for pages whose output depends only on inputs the caller can
+// summarize in key, such as a route plus a content version; props are ignored
+// on a cache hit. Failed renders are not cached, and a ttl of zero or less
+// disables caching for the call.
+//
+// The cache holds at most ReactAppOptions.RenderCacheSize entries and evicts
+// the least recently used first. A successful UpdateSSREntry or
+// UpdateClientEntry empties it, so markup from a replaced bundle is never
+// served.
+//
+// Example:
+//
+//	key := "product:" + id + ":" + version
+//	html, err := app.RenderCached(key, props, time.Minute)
+func (ra *ReactApp) RenderCached(key string, props map[string]interface{}, ttl time.Duration) (string, error) {
+	// Keys carry the bundle generation, so a render that started before an
+	// update cannot store old markup where later calls would find it.
+	key = strconv.FormatUint(ra.renderGen.Load(), 10) + ":" + key
+	if ttl > 0 {
+		if html, ok := ra.renderCache.get(key); ok {
+			return string(html), nil
+		}
+	}
+
+	html, err := ra.Render(props)
+	if err != nil {
+		return "", err
+	}
+	ra.renderCache.putFor(key, []byte(html), ttl)
+	return html, nil
+}
+
+// resetRenderCache drops the markup cached by RenderCached after a bundle was
+// replaced.
+func (ra *ReactApp) resetRenderCache() {
+	ra.renderGen.Add(1)
+	ra.renderCache.clear()
+}
+
+// RenderBatch renders each props set in propsList like Render and returns the
+// markup and error for each item at the same index, so one failing page does
+// not abort the rest. It is meant for static-site generation: the app's lock
+// is taken once for the whole batch and __REQUEST__ is cleared once, so the
+// batch renders back to back without interleaving with other renders (which
+// wait until it finishes) and always against the same SSR bundle.
+//
+// Example:
+//
+//	pages, errs := app.RenderBatch(propsForEveryPost)
+//	for i, html := range pages {
+//	    if errs[i] != nil {
+//	        log.Printf("post %d: %v", i, errs[i])
+//	        continue
+//	    }
+//	    os.WriteFile(fmt.Sprintf("out/post-%d.html", i), []byte(html), 0o644)
+//	}
+func (ra *ReactApp) RenderBatch(propsList []map[string]interface{}) ([]string, []error) {
+	markup := make([]string, len(propsList))
+	errs := make([]error, len(propsList))
+	mapped := make([]map[string]interface{}, len(propsList))
+	for i, props := range propsList {
+		mapped[i], errs[i] = ra.mapProps(props)
+		if errs[i] == nil && ra.propsSchema != nil {
+			if err := validateProps(ra.propsSchema, mapped[i]); err != nil {
+				errs[i] = fmt.Errorf("invalid props: %w", err)
+			}
+		}
+	}
+
 	ra.mu.Lock()
 	defer ra.mu.Unlock()
 
-	ra.runner.SetGlobal("SERVER_PROPS", props)
+	if err := ra.setRequestContext(nil); err != nil {
+		for i := range errs {
+			errs[i] = err
+		}
+		return markup, errs
+	}
+	for i, props := range mapped {
+		if errs[i] != nil {
+			continue
+		}
+		ra.runner.SetGlobal(ra.propsGlobal, props)
+		markup[i], errs[i] = ra.renderLocked()
+	}
+	return markup, errs
+}
+
+// RenderOptions carries per-render settings for RenderWith.
+type RenderOptions struct {
+	// RequestContext describes the incoming request. It is exposed to the SSR
+	// entry as the __REQUEST__ global; when nil, __REQUEST__ is null.
+	RequestContext *RequestContext
+}
 
-	markup, err := ra.runner.Eval("renderApp(SERVER_PROPS)")
+// RequestContext describes the HTTP request being rendered, so the SSR entry
+// can personalize markup by cookie, locale, or user agent. RenderWith exposes
+// it as the __REQUEST__ global with this shape:
+//
+//	{
+//	  method:  "GET",
+//	  url:     "/products?page=2",
+//	  headers: { "accept-language": "nl-NL,nl;q=0.9", "user-agent": "..." },
+//	  cookies: { session: "abc123", theme: "dark" }
+//	}
+//
+// Header names are lower-cased and repeated headers are joined with ", ".
+// headers and cookies are always objects, possibly empty.
+type RequestContext struct {
+	Method  string            `json:"method"`
+	URL     string            `json:"url"`
+	Headers map[string]string `json:"headers"`
+	Cookies map[string]string `json:"cookies"`
+}
+
+// NewRequestContext captures the method, URL, headers, and cookies of req.
+//
+// Example:
+//
+//	html, err := app.RenderWith(jsrunner.RenderOptions{
+//	    RequestContext: jsrunner.NewRequestContext(req),
+//	}, props)
+func NewRequestContext(req *http.Request) *RequestContext {
+	rc := &RequestContext{
+		Method:  req.Method,
+		URL:     req.URL.RequestURI(),
+		Headers: make(map[string]string, len(req.Header)),
+		Cookies: make(map[string]string),
+	}
+	for name, values := range req.Header {
+		rc.Headers[strings.ToLower(name)] = strings.Join(values, ", ")
+	}
+	for _, cookie := range req.Cookies() {
+		rc.Cookies[cookie.Name] = cookie.Value
+	}
+	return rc
+}
+
+// RenderWith is like Render but also applies per-render options, such as the
+// request context the SSR entry reads from __REQUEST__. The global is replaced
+// on every render (and set to null by Render), so one request's data never
+// leaks into another's markup.
+//
+// Example:
+//
+//	// SSR entry:
+//	// (globalThis as any).renderApp = (props: any) =>
+//	//     renderToString(<App {...props} theme={__REQUEST__?.cookies.theme ?? "light"} />);
+//
+//	html, err := app.RenderWith(jsrunner.RenderOptions{
+//	    RequestContext: jsrunner.NewRequestContext(req),
+//	}, props)
+func (ra *ReactApp) RenderWith(opts RenderOptions, props map[string]interface{}) (string, error) {
+	mapped, err := ra.mapProps(props)
 	if err != nil {
-		return "", fmt.Errorf("renderApp failed: %w", err)
+		return "", err
 	}
+	parts, err := ra.render(opts, mapped)
+	return parts.HTML, err
+}
 
-	return ExportString(markup), nil
+// mapProps applies ReactAppOptions.PropsFieldNameMapper to props, if set. nil
+// props become an empty object, so renderApp never receives null.
+func (ra *ReactApp) mapProps(props map[string]interface{}) (map[string]interface{}, error) {
+	if props == nil {
+		return map[string]interface{}{}, nil
+	}
+	if ra.propsMapper == nil {
+		return props, nil
+	}
+	mapped, err := mapFieldNames(ra.propsMapper, reflect.ValueOf(props))
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode props: %w", err)
+	}
+	return mapped.(map[string]interface{}), nil
+}
+
+// render validates props and invokes renderApp with them. props must already
+// be mapped by mapProps.
+func (ra *ReactApp) render(opts RenderOptions, props map[string]interface{}) (Parts, error) {
+	if ra.propsSchema != nil {
+		if err := validateProps(ra.propsSchema, props); err != nil {
+			return Parts{}, fmt.Errorf("invalid props: %w", err)
+		}
+	}
+
+	ra.mu.Lock()
+	defer ra.mu.Unlock()
+
+	if err := ra.setRequestContext(opts.RequestContext); err != nil {
+		return Parts{}, err
+	}
+	ra.runner.SetGlobal(ra.propsGlobal, props)
+	return ra.renderPartsLocked()
+}
+
+// setRequestContext installs rc as the __REQUEST__ global, or null when rc is
+// nil. ra.mu must be held.
+func (ra *ReactApp) setRequestContext(rc *RequestContext) error {
+	if rc == nil {
+		ra.runner.SetGlobal("__REQUEST__", nil)
+		return nil
+	}
+
+	normalized := RequestContext{
+		Method:  rc.Method,
+		URL:     rc.URL,
+		Headers: make(map[string]string, len(rc.Headers)),
+		Cookies: make(map[string]string, len(rc.Cookies)),
+	}
+	for name, value := range rc.Headers {
+		normalized.Headers[strings.ToLower(name)] = value
+	}
+	for name, value := range rc.Cookies {
+		normalized.Cookies[name] = value
+	}
+
+	encoded, err := json.Marshal(normalized)
+	if err != nil {
+		return fmt.Errorf("failed to encode request context: %w", err)
+	}
+	decoded, err := ra.runner.Call("JSON.parse", string(encoded))
+	if err != nil {
+		return fmt.Errorf("failed to decode request context: %w", err)
+	}
+	ra.runner.SetGlobal("__REQUEST__", decoded)
+	return nil
+}
+
+// RenderStruct is like Render but accepts typed props such as a struct. props
+// is JSON-encoded (honoring json tags) and decoded into plain JavaScript
+// values, so renderApp sees exactly the shape a browser would receive from the
+// same props serialized into the page. When ReactAppOptions.PropsFieldNameMapper
+// is set, field names come from the mapper instead of json tags.
+//
+// Example:
+//
+//	type PageProps struct {
+//	    Title string   `json:"title"`
+//	    Items []string `json:"items,omitempty"`
+//	}
+//	html, err := app.RenderStruct(PageProps{Title: "Home"})
+func (ra *ReactApp) RenderStruct(props interface{}) (string, error) {
+	if ra.propsMapper != nil {
+		mapped, err := mapFieldNames(ra.propsMapper, reflect.ValueOf(props))
+		if err != nil {
+			return "", fmt.Errorf("failed to encode props: %w", err)
+		}
+		props = mapped
+	}
+	encoded, err := json.Marshal(props)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode props: %w", err)
+	}
+	if string(encoded) == "null" {
+		// nil props (or a nil pointer) render with an empty object, as in Render.
+		encoded = []byte("{}")
+	}
+	if ra.propsSchema != nil {
+		if err := validatePropsJSON(ra.propsSchema, encoded); err != nil {
+			return "", fmt.Errorf("invalid props: %w", err)
+		}
+	}
+
+	ra.mu.Lock()
+	defer ra.mu.Unlock()
+
+	if err := ra.setRequestContext(nil); err != nil {
+		return "", err
+	}
+	decoded, err := ra.runner.Call("JSON.parse", string(encoded))
+	if err != nil {
+		return "", fmt.Errorf("failed to decode props: %w", err)
+	}
+	ra.runner.SetGlobal(ra.propsGlobal, decoded)
+	return ra.renderLocked()
+}
+
+// renderLocked invokes the render function with the props global and returns
+// the body markup. ra.mu must be held.
+func (ra *ReactApp) renderLocked() (string, error) {
+	parts, err := ra.renderPartsLocked()
+	return parts.HTML, err
+}
+
+// renderPartsLocked invokes the render function with the props global and
+// splits its result into Parts. ra.mu must be held.
+func (ra *ReactApp) renderPartsLocked() (Parts, error) {
+	result, err := ra.runner.Eval(ra.renderCall)
+	if err != nil {
+		return Parts{}, fmt.Errorf("%s failed: %w", ra.renderFunc, err)
+	}
+
+	return exportParts(result), nil
+}
+
+// Warmup performs a throwaway render with sampleProps so goja compiles the
+// render path before real traffic arrives. Call it after NewReactApp and
+// before the server starts accepting requests; a returned error lets boot fail
+// fast instead of surfacing on the first request.
+func (ra *ReactApp) Warmup(sampleProps map[string]interface{}) error {
+	if _, err := ra.Render(sampleProps); err != nil {
+		return fmt.Errorf("warmup failed: %w", err)
+	}
+	return nil
+}
+
+// loadPolyfillDir executes every .js file in dir in lexical order. Scripts are
+// loaded by path so errors and stack traces name the offending file.
+func loadPolyfillDir(r *Runner, dir string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("read polyfill dir: %w", err)
+	}
+
+	// os.ReadDir returns entries sorted by filename.
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".js" {
+			continue
+		}
+		if err := r.LoadScript(filepath.Join(dir, entry.Name())); err != nil {
+			return &RuntimeLoadError{Script: "polyfill " + entry.Name(), Err: err}
+		}
+	}
+	return nil
+}
+
+// BuildStats reports how NewReactApp spent its time: bundle is the esbuild
+// duration for both entries (including fetching remote modules), and load is
+// the time spent executing the polyfills and the SSR bundle in the VM. Use it
+// to tell whether a slow boot comes from bundling or from script evaluation.
+//
+// Example:
+//
+//	bundle, load := app.BuildStats()
+//	log.Printf("bundled in %s, loaded in %s", bundle, load)
+func (ra *ReactApp) BuildStats() (bundle, load time.Duration) {
+	return ra.bundleTime, ra.loadTime
+}
+
+// SSRBundle returns the compiled server bundle loaded into the runner, or ""
+// when the app was created from ReactAppOptions.PrecompiledSSR. Save it with
+// ClientBundle at deploy time, then pass it through Compile and into
+// ReactAppOptions.PrecompiledSSR to create apps without running esbuild.
+//
+// Example:
+//
+//	program, _ := jsrunner.Compile("app-ssr.js", buildApp.SSRBundle())
+//	app, _ := jsrunner.NewReactApp(jsrunner.ReactAppOptions{
+//	    PrecompiledSSR:    program,
+//	    PrecompiledClient: buildApp.ClientBundle(),
+//	})
+func (ra *ReactApp) SSRBundle() string {
+	return ra.bundles.Load().SSR
 }
 
 // ClientBundle returns the compiled browser bundle that hydrates the app.
 func (ra *ReactApp) ClientBundle() string {
-	return ra.clientBundle
+	return ra.bundles.Load().Client
+}
+
+// ClientBundleHash returns a short hex digest of ClientBundle. It is stable for
+// identical bundles and changes whenever the bundle does, so serving the bundle
+// under a name containing it lets browsers cache it indefinitely.
+// RenderDocument references "/static/client.<hash>.js" by default.
+//
+// Example:
+//
+//	app.Get("/static/client."+reactApp.ClientBundleHash()+".js", func(c *fiber.Ctx) error {
+//	    c.Set("Cache-Control", "public, max-age=31536000, immutable")
+//	    c.Type("js")
+//	    return c.SendString(reactApp.ClientBundle())
+//	})
+func (ra *ReactApp) ClientBundleHash() string {
+	return ra.bundles.Load().ClientHash
+}
+
+// CSS returns the text of the stylesheets imported by the entries, in import
+// order, or "" when they import none. Serve it as a stylesheet or inline it in
+// a <style> tag so server-rendered markup is styled before hydration.
+//
+// Example:
+//
+//	app.Get("/static/app.css", func(c *fiber.Ctx) error {
+//	    c.Type("css")
+//	    return c.SendString(reactApp.CSS())
+//	})
+func (ra *ReactApp) CSS() string {
+	return ra.bundles.Load().CSS
+}
+
+// BundleMeta returns the bundle sizes and dependency list, or nil when the app
+// was created without ReactAppOptions.Metafile.
+func (ra *ReactApp) BundleMeta() *BundleMeta {
+	return ra.bundles.Load().Meta
+}
+
+// SSRSourceMap returns the source map of the SSR bundle, or nil when the app
+// was created without ReactAppOptions.SourceMap.
+func (ra *ReactApp) SSRSourceMap() []byte {
+	return ra.bundles.Load().SSRSourceMap
+}
+
+// RewriteStack returns the JavaScript stack trace carried by err with bundle
+// positions mapped back to the original TS/JSX sources. When no source map is
+// attached, the stack is returned unchanged.
+//
+// Example:
+//
+//	if _, err := app.Render(props); err != nil {
+//	    log.Println(app.RewriteStack(err))
+//	}
+func (ra *ReactApp) RewriteStack(err error) string {
+	if err == nil {
+		return ""
+	}
+	stack := errorStack(err)
+	sourceMap := ra.SSRSourceMap()
+	if len(sourceMap) == 0 {
+		return stack
+	}
+	rewritten, rewriteErr := RewriteStack(stack, bundler.SSRBundleName, sourceMap)
+	if rewriteErr != nil {
+		return stack
+	}
+	return rewritten
 }
 
 // Runner exposes the underlying jsrunner.Runner for advanced customization.
@@ -112,13 +958,198 @@ func (ra *ReactApp) Runner() *Runner {
 	return ra.runner
 }
 
-func assertGlobalExists(r *Runner, name string) error {
-	result, err := r.Eval(fmt.Sprintf("typeof this['%s'] !== 'undefined'", name))
+const propsSchemaURL = "props-schema.json"
+
+func compilePropsSchema(doc []byte) (*jsonschema.Schema, error) {
+	parsed, err := jsonschema.UnmarshalJSON(bytes.NewReader(doc))
 	if err != nil {
-		return err
+		return nil, err
 	}
-	if !ExportBool(result) {
-		return fmt.Errorf("global %s is undefined", name)
+	compiler := jsonschema.NewCompiler()
+	if err := compiler.AddResource(propsSchemaURL, parsed); err != nil {
+		return nil, err
+	}
+	return compiler.Compile(propsSchemaURL)
+}
+
+// mapFieldNames copies v into plain maps and slices, naming struct fields with
+// mapper. Fields the mapper names "" and unexported fields are skipped, and
+// the fields of embedded structs are promoted. Values that marshal themselves,
+// such as time.Time, are kept as is. It returns an error when v refers back to
+// itself, for example through a parent pointer.
+func mapFieldNames(mapper goja.FieldNameMapper, v reflect.Value) (interface{}, error) {
+	m := fieldNameMapping{mapper: mapper, visiting: make(map[visitKey]struct{})}
+	return m.copy(v)
+}
+
+// visitKey identifies a pointer, map, or slice being copied, in the manner of
+// encoding/json's cycle detection.
+type visitKey struct {
+	ptr uintptr
+	typ reflect.Type
+	len int
+}
+
+// fieldNameMapping holds the state of one mapFieldNames call. visiting holds
+// the references on the path from the root to the value being copied, so a
+// value shared by siblings is copied twice but a cycle is reported.
+type fieldNameMapping struct {
+	mapper   goja.FieldNameMapper
+	visiting map[visitKey]struct{}
+}
+
+// enter marks v as being copied and returns the function that unmarks it.
+func (m fieldNameMapping) enter(v reflect.Value) (leave func(), err error) {
+	key := visitKey{ptr: v.Pointer(), typ: v.Type()}
+	if v.Kind() == reflect.Slice {
+		key.len = v.Len()
+	}
+	if _, ok := m.visiting[key]; ok {
+		return nil, fmt.Errorf("cycle detected through %s", v.Type())
+	}
+	m.visiting[key] = struct{}{}
+	return func() { delete(m.visiting, key) }, nil
+}
+
+func (m fieldNameMapping) copy(v reflect.Value) (interface{}, error) {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil, nil
+		}
+		if v.Kind() == reflect.Pointer {
+			leave, err := m.enter(v)
+			if err != nil {
+				return nil, err
+			}
+			defer leave()
+		}
+		v = v.Elem()
+	}
+	if !v.IsValid() {
+		return nil, nil
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		if selfMarshaling(v.Type()) {
+			return v.Interface(), nil
+		}
+		out := make(map[string]interface{}, v.NumField())
+		if err := m.copyStructFields(v, out); err != nil {
+			return nil, err
+		}
+		return out, nil
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String || v.IsNil() {
+			return v.Interface(), nil
+		}
+		leave, err := m.enter(v)
+		if err != nil {
+			return nil, err
+		}
+		defer leave()
+		out := make(map[string]interface{}, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			value, err := m.copy(iter.Value())
+			if err != nil {
+				return nil, err
+			}
+			out[iter.Key().String()] = value
+		}
+		return out, nil
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && (v.IsNil() || v.Type().Elem().Kind() == reflect.Uint8) {
+			return v.Interface(), nil
+		}
+		if v.Kind() == reflect.Slice {
+			leave, err := m.enter(v)
+			if err != nil {
+				return nil, err
+			}
+			defer leave()
+		}
+		out := make([]interface{}, v.Len())
+		for i := range out {
+			value, err := m.copy(v.Index(i))
+			if err != nil {
+				return nil, err
+			}
+			out[i] = value
+		}
+		return out, nil
+	default:
+		return v.Interface(), nil
+	}
+}
+
+func (m fieldNameMapping) copyStructFields(v reflect.Value, out map[string]interface{}) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Anonymous && field.Type.Kind() == reflect.Struct && !selfMarshaling(field.Type) {
+			if err := m.copyStructFields(v.Field(i), out); err != nil {
+				return err
+			}
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		name := m.mapper.FieldName(t, field)
+		if name == "" {
+			continue
+		}
+		value, err := m.copy(v.Field(i))
+		if err != nil {
+			return err
+		}
+		out[name] = value
 	}
 	return nil
 }
+
+var selfMarshalerTypes = [2]reflect.Type{
+	reflect.TypeOf((*json.Marshaler)(nil)).Elem(),
+	reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem(),
+}
+
+// selfMarshaling reports whether t, or a pointer to it, controls its own
+// encoding.
+func selfMarshaling(t reflect.Type) bool {
+	for _, iface := range selfMarshalerTypes {
+		if t.Implements(iface) || reflect.PointerTo(t).Implements(iface) {
+			return true
+		}
+	}
+	return false
+}
+
+// validateProps round-trips props through JSON so Go types (ints, structs,
+// typed slices) are validated the same way renderApp will observe them.
+func validateProps(schema *jsonschema.Schema, props map[string]interface{}) error {
+	if props == nil {
+		props = map[string]interface{}{}
+	}
+	encoded, err := json.Marshal(props)
+	if err != nil {
+		return err
+	}
+	return validatePropsJSON(schema, encoded)
+}
+
+// validatePropsJSON validates JSON-encoded props against schema.
+func validatePropsJSON(schema *jsonschema.Schema, encoded []byte) error {
+	instance, err := jsonschema.UnmarshalJSON(bytes.NewReader(encoded))
+	if err != nil {
+		return err
+	}
+	return schema.Validate(instance)
+}
+
+// logBundleWarnings reports the findings of the bundler's post-build checks.
+func logBundleWarnings(logger Logger, bundles *bundler.ReactBundles) {
+	for _, warning := range bundles.Warnings {
+		logger.Warn("bundle check failed", "warning", warning)
+	}
+}
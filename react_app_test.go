@@ -0,0 +1,1349 @@
+package jsrunner
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dop251/goja"
+)
+
+const testClientEntry = `console.log("client boot");`
+
+func TestReactAppRewriteStack(t *testing.T) {
+	ssrEntry := `type Props = { name: string };
+
+function explode(props: Props): string {
+	throw new Error("bad props " + props.name);
+}
+
+export function renderApp(props: Props) {
+	return explode(props);
+}
+
+(globalThis as any).renderApp = renderApp;
+`
+
+	app, err := NewReactApp(ReactAppOptions{
+		SSREntry:    ssrEntry,
+		ClientEntry: testClientEntry,
+		SourceMap:   true,
+	})
+	if err != nil {
+		t.Fatalf("NewReactApp() failed: %v", err)
+	}
+	if len(app.SSRSourceMap()) == 0 {
+		t.Fatal("expected SSR source map to be attached")
+	}
+
+	_, err = app.Render(map[string]interface{}{"name": "x"})
+	if err == nil {
+		t.Fatal("expected Render to fail")
+	}
+
+	stack := app.RewriteStack(err)
+	if !strings.Contains(stack, "app-ssr.tsx:4:") {
+		t.Errorf("expected rewritten stack to reference app-ssr.tsx:4, got %s", stack)
+	}
+	if strings.Contains(stack, "app-ssr.js:") {
+		t.Errorf("expected bundle positions to be rewritten, got %s", stack)
+	}
+}
+
+func TestReactAppBundleMeta(t *testing.T) {
+	app, err := NewReactApp(ReactAppOptions{
+		SSREntry:    `(globalThis as any).renderApp = (props: any) => "<p>" + props.name + "</p>";`,
+		ClientEntry: testClientEntry,
+		Metafile:    true,
+	})
+	if err != nil {
+		t.Fatalf("NewReactApp() failed: %v", err)
+	}
+
+	meta := app.BundleMeta()
+	if meta == nil {
+		t.Fatal("expected bundle metadata")
+	}
+	if meta.SSRBytes == 0 || meta.ClientBytes != len(app.ClientBundle()) {
+		t.Errorf("unexpected sizes: ssr=%d client=%d (bundle %d)", meta.SSRBytes, meta.ClientBytes, len(app.ClientBundle()))
+	}
+	if len(meta.Dependencies) != 0 {
+		t.Errorf("expected no remote dependencies, got %v", meta.Dependencies)
+	}
+}
+
+func TestReactAppWarmup(t *testing.T) {
+	app, err := NewReactApp(ReactAppOptions{
+		SSREntry:    `(globalThis as any).renderApp = (props: any) => "<h1>" + props.user.name + "</h1>";`,
+		ClientEntry: testClientEntry,
+	})
+	if err != nil {
+		t.Fatalf("NewReactApp() failed: %v", err)
+	}
+
+	if err := app.Warmup(map[string]interface{}{"user": map[string]interface{}{"name": "warm"}}); err != nil {
+		t.Fatalf("Warmup() failed: %v", err)
+	}
+	if err := app.Warmup(map[string]interface{}{}); err == nil {
+		t.Error("expected Warmup to fail when props break renderApp")
+	}
+
+	markup, err := app.Render(map[string]interface{}{"user": map[string]interface{}{"name": "Ada"}})
+	if err != nil {
+		t.Fatalf("Render() failed: %v", err)
+	}
+	if markup != "<h1>Ada</h1>" {
+		t.Errorf("Expected '<h1>Ada</h1>', got '%s'", markup)
+	}
+}
+
+type logEvent struct {
+	level string
+	msg   string
+	kv    []interface{}
+}
+
+type captureLogger struct {
+	mu     sync.Mutex
+	events []logEvent
+}
+
+func (c *captureLogger) record(level, msg string, kv []interface{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.events = append(c.events, logEvent{level: level, msg: msg, kv: kv})
+}
+
+func (c *captureLogger) Debug(msg string, kv ...interface{}) { c.record("debug", msg, kv) }
+func (c *captureLogger) Info(msg string, kv ...interface{})  { c.record("info", msg, kv) }
+func (c *captureLogger) Warn(msg string, kv ...interface{})  { c.record("warn", msg, kv) }
+func (c *captureLogger) Error(msg string, kv ...interface{}) { c.record("error", msg, kv) }
+
+func (c *captureLogger) find(msg string) (logEvent, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, ev := range c.events {
+		if ev.msg == msg {
+			return ev, true
+		}
+	}
+	return logEvent{}, false
+}
+
+var _ Logger = slog.Default()
+
+func TestReactAppLogsBundleBuilt(t *testing.T) {
+	logger := &captureLogger{}
+	_, err := NewReactApp(ReactAppOptions{
+		RunnerOptions: []Option{WithLogger(logger)},
+		SSREntry:      `(globalThis as any).renderApp = () => "<p></p>";`,
+		ClientEntry:   testClientEntry,
+	})
+	if err != nil {
+		t.Fatalf("NewReactApp() failed: %v", err)
+	}
+
+	ev, ok := logger.find("bundle built")
+	if !ok {
+		t.Fatal("expected a 'bundle built' event")
+	}
+	if ev.level != "info" {
+		t.Errorf("Expected info level, got %s", ev.level)
+	}
+	if len(ev.kv) == 0 || ev.kv[0] != "duration" {
+		t.Errorf("Expected structured fields starting with duration, got %v", ev.kv)
+	}
+}
+
+func TestReactAppPropsSchema(t *testing.T) {
+	app, err := NewReactApp(ReactAppOptions{
+		SSREntry:    `(globalThis as any).renderApp = (props: any) => "<p>" + props.user + "</p>";`,
+		ClientEntry: testClientEntry,
+		PropsSchema: []byte(`{
+			"type": "object",
+			"properties": {"user": {"type": "string"}},
+			"required": ["user"]
+		}`),
+	})
+	if err != nil {
+		t.Fatalf("NewReactApp() failed: %v", err)
+	}
+
+	html, err := app.Render(map[string]interface{}{"user": "ada"})
+	if err != nil {
+		t.Fatalf("Render() failed: %v", err)
+	}
+	if html != "<p>ada</p>" {
+		t.Errorf("unexpected markup: %s", html)
+	}
+
+	_, err = app.Render(map[string]interface{}{"name": "ada"})
+	if err == nil {
+		t.Fatal("expected Render to reject props missing user")
+	}
+	if !strings.Contains(err.Error(), "invalid props") || !strings.Contains(err.Error(), "user") {
+		t.Errorf("expected descriptive validation error, got %v", err)
+	}
+
+	if _, err := app.Render(map[string]interface{}{"user": 42}); err == nil {
+		t.Error("expected Render to reject non-string user")
+	}
+
+	_, err = NewReactApp(ReactAppOptions{
+		SSREntry:    `(globalThis as any).renderApp = () => "";`,
+		ClientEntry: testClientEntry,
+		PropsSchema: []byte(`{"type": 5}`),
+	})
+	if err == nil {
+		t.Error("expected NewReactApp to reject an invalid schema")
+	}
+}
+
+func TestNormalizeMarkup(t *testing.T) {
+	a := `<div class="card" id="main" data-x="1">
+		<p>Hello,<!-- --> <b>world</b></p>
+	</div>`
+	b := `<div data-x="1" id="main" class="card"><p>Hello, <b>world</b></p></div>   `
+
+	if got, want := NormalizeMarkup(a), NormalizeMarkup(b); got != want {
+		t.Errorf("expected equivalent markup to normalize identically:\n%s\n%s", got, want)
+	}
+	if got := NormalizeMarkup(b); got != `<div class="card" data-x="1" id="main"><p>Hello, <b>world</b></p></div>` {
+		t.Errorf("unexpected normalized markup: %s", got)
+	}
+
+	if NormalizeMarkup(`<p class="a">x</p>`) == NormalizeMarkup(`<p class="b">x</p>`) {
+		t.Error("expected different attribute values to stay distinct")
+	}
+	if got := NormalizeMarkup("<pre>a  b\n c</pre>"); got != "<pre>a  b\n c</pre>" {
+		t.Errorf("expected pre content to be preserved, got %q", got)
+	}
+}
+
+func TestReactAppClassicJSX(t *testing.T) {
+	ssrEntry := `/** @jsx h */
+function h(tag: string, props: any, ...children: any[]): string {
+	return "<" + tag + ">" + children.join("") + "</" + tag + ">";
+}
+
+(globalThis as any).renderApp = (props: any) => <p>Hello {props.name}</p>;
+`
+
+	app, err := NewReactApp(ReactAppOptions{
+		SSREntry:    ssrEntry,
+		ClientEntry: testClientEntry,
+		JSXMode:     JSXClassic,
+	})
+	if err != nil {
+		t.Fatalf("NewReactApp() failed: %v", err)
+	}
+
+	html, err := app.Render(map[string]interface{}{"name": "ada"})
+	if err != nil {
+		t.Fatalf("Render() failed: %v", err)
+	}
+	if html != "<p>Hello ada</p>" {
+		t.Errorf("unexpected markup: %s", html)
+	}
+
+	_, err = NewReactApp(ReactAppOptions{
+		SSREntry:    ssrEntry,
+		ClientEntry: testClientEntry,
+		JSXMode:     "bogus",
+	})
+	if err == nil {
+		t.Error("expected unknown JSX mode to be rejected")
+	}
+}
+
+func TestReactAppDevMode(t *testing.T) {
+	ssrEntry := `function formatGreeting(name: string): string {
+	if (!name) {
+		throw new Error("name is required");
+	}
+	return "<p>Hello " + name + "</p>";
+}
+
+(globalThis as any).renderApp = (props: any) => formatGreeting(props.name);
+`
+
+	prod, err := NewReactApp(ReactAppOptions{SSREntry: ssrEntry, ClientEntry: testClientEntry, Metafile: true})
+	if err != nil {
+		t.Fatalf("NewReactApp() failed: %v", err)
+	}
+	dev, err := NewReactApp(ReactAppOptions{SSREntry: ssrEntry, ClientEntry: testClientEntry, Metafile: true, DevMode: true})
+	if err != nil {
+		t.Fatalf("NewReactApp(DevMode) failed: %v", err)
+	}
+
+	if dev.BundleMeta().SSRBytes <= prod.BundleMeta().SSRBytes {
+		t.Errorf("expected dev bundle to be larger than production (%d <= %d)", dev.BundleMeta().SSRBytes, prod.BundleMeta().SSRBytes)
+	}
+
+	html, err := dev.Render(map[string]interface{}{"name": "ada"})
+	if err != nil {
+		t.Fatalf("Render() failed: %v", err)
+	}
+	if html != "<p>Hello ada</p>" {
+		t.Errorf("unexpected markup: %s", html)
+	}
+
+	_, err = dev.Render(map[string]interface{}{})
+	if err == nil {
+		t.Fatal("expected Render to fail without a name")
+	}
+	stack := errorStack(err)
+	if !strings.Contains(stack, "formatGreeting") {
+		t.Errorf("expected original function name in stack, got %s", stack)
+	}
+	if !strings.Contains(stack, "app-ssr.tsx:3:") {
+		t.Errorf("expected inline source map to report original positions, got %s", stack)
+	}
+}
+
+func TestReactAppRenderStruct(t *testing.T) {
+	type item struct {
+		Label string `json:"label"`
+	}
+	type pageProps struct {
+		Title    string `json:"title"`
+		Items    []item `json:"items"`
+		Internal string `json:"-"`
+		Draft    bool   `json:"draft,omitempty"`
+	}
+
+	app, err := NewReactApp(ReactAppOptions{
+		SSREntry: `(globalThis as any).renderApp = (props: any) =>
+	"<h1>" + props.title + "</h1>" + props.items.map((i: any) => "<li>" + i.label + "</li>").join("") +
+	"<p>" + Object.keys(props).join(",") + "</p>";`,
+		ClientEntry: testClientEntry,
+		PropsSchema: []byte(`{"type": "object", "required": ["title"], "properties": {"title": {"type": "string", "minLength": 1}}}`),
+	})
+	if err != nil {
+		t.Fatalf("NewReactApp() failed: %v", err)
+	}
+
+	html, err := app.RenderStruct(pageProps{Title: "Home", Items: []item{{Label: "one"}, {Label: "two"}}, Internal: "secret"})
+	if err != nil {
+		t.Fatalf("RenderStruct() failed: %v", err)
+	}
+	if want := "<h1>Home</h1><li>one</li><li>two</li><p>title,items</p>"; html != want {
+		t.Errorf("RenderStruct() = %s, want %s", html, want)
+	}
+
+	if _, err := app.RenderStruct(pageProps{}); err == nil || !strings.Contains(err.Error(), "invalid props") {
+		t.Errorf("expected schema validation error, got %v", err)
+	}
+}
+
+func TestReactAppRenderAppNotFunction(t *testing.T) {
+	_, err := NewReactApp(ReactAppOptions{
+		SSREntry:    `(globalThis as any).renderApp = 5;`,
+		ClientEntry: testClientEntry,
+	})
+	if !errors.Is(err, ErrRenderAppNotFunction) {
+		t.Fatalf("expected ErrRenderAppNotFunction, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "defines it as a number") {
+		t.Errorf("expected error to describe the actual type, got %v", err)
+	}
+}
+
+func TestReactAppRenderNilProps(t *testing.T) {
+	// Destructuring supplies a default for a missing name but throws a
+	// TypeError if the props object itself is null.
+	app, err := NewReactApp(ReactAppOptions{
+		SSREntry:    `(globalThis as any).renderApp = ({ name = "guest" }: any) => "<p>Hello " + name + "</p>";`,
+		ClientEntry: testClientEntry,
+	})
+	if err != nil {
+		t.Fatalf("NewReactApp() failed: %v", err)
+	}
+
+	html, err := app.Render(nil)
+	if err != nil {
+		t.Fatalf("Render(nil) failed: %v", err)
+	}
+	if html != "<p>Hello guest</p>" {
+		t.Errorf("Render(nil) = %q, want %q", html, "<p>Hello guest</p>")
+	}
+
+	html, err = app.RenderStruct(nil)
+	if err != nil {
+		t.Fatalf("RenderStruct(nil) failed: %v", err)
+	}
+	if html != "<p>Hello guest</p>" {
+		t.Errorf("RenderStruct(nil) = %q, want %q", html, "<p>Hello guest</p>")
+	}
+
+	pages, errs := app.RenderBatch([]map[string]interface{}{nil, {"name": "Ada"}})
+	for i, err := range errs {
+		if err != nil {
+			t.Fatalf("RenderBatch()[%d] failed: %v", i, err)
+		}
+	}
+	if pages[0] != "<p>Hello guest</p>" || pages[1] != "<p>Hello Ada</p>" {
+		t.Errorf("RenderBatch() = %q", pages)
+	}
+}
+
+func TestReactAppCustomGlobalNames(t *testing.T) {
+	app, err := NewReactApp(ReactAppOptions{
+		// The entry owns SERVER_PROPS and renderApp, so the app must use
+		// other names to avoid clobbering them.
+		SSREntry: `(globalThis as any).SERVER_PROPS = "entry-owned";
+(globalThis as any).renderApp = "not the render function";
+(globalThis as any).renderPage = (props: any) => "<p>" + props.name + "</p>";`,
+		ClientEntry:        testClientEntry,
+		PropsGlobalName:    "__PAGE_PROPS__",
+		RenderFunctionName: "renderPage",
+	})
+	if err != nil {
+		t.Fatalf("NewReactApp() failed: %v", err)
+	}
+
+	html, err := app.Render(map[string]interface{}{"name": "Ada"})
+	if err != nil {
+		t.Fatalf("Render() failed: %v", err)
+	}
+	if html != "<p>Ada</p>" {
+		t.Errorf("Render() = %q, want %q", html, "<p>Ada</p>")
+	}
+
+	html, err = app.RenderStruct(struct {
+		Name string `json:"name"`
+	}{Name: "Grace"})
+	if err != nil {
+		t.Fatalf("RenderStruct() failed: %v", err)
+	}
+	if html != "<p>Grace</p>" {
+		t.Errorf("RenderStruct() = %q, want %q", html, "<p>Grace</p>")
+	}
+
+	owned, err := app.Runner().Eval("SERVER_PROPS")
+	if err != nil {
+		t.Fatalf("Eval() failed: %v", err)
+	}
+	if got := ExportString(owned); got != "entry-owned" {
+		t.Errorf("SERVER_PROPS = %q, want the entry's value to be left alone", got)
+	}
+
+	_, err = NewReactApp(ReactAppOptions{
+		SSREntry:           `(globalThis as any).renderApp = (props: any) => "";`,
+		ClientEntry:        testClientEntry,
+		RenderFunctionName: "renderPage",
+	})
+	if err == nil || !strings.Contains(err.Error(), "renderPage not defined") {
+		t.Errorf("expected an error for the missing render function, got %v", err)
+	}
+
+	_, err = NewReactApp(ReactAppOptions{
+		SSREntry:        `(globalThis as any).renderApp = (props: any) => "";`,
+		ClientEntry:     testClientEntry,
+		PropsGlobalName: "page.props",
+	})
+	if err == nil || !strings.Contains(err.Error(), "not a valid identifier") {
+		t.Errorf("expected an error for an invalid props global name, got %v", err)
+	}
+
+	_, err = NewReactApp(ReactAppOptions{
+		SSREntry:           `(globalThis as any).renderApp = (props: any) => "";`,
+		ClientEntry:        testClientEntry,
+		RenderFunctionName: "this",
+	})
+	if err == nil || !strings.Contains(err.Error(), "not a valid identifier") {
+		t.Errorf("expected an error for a reserved render function name, got %v", err)
+	}
+}
+
+func TestReactAppRenderWithRequestContext(t *testing.T) {
+	app, err := NewReactApp(ReactAppOptions{
+		SSREntry: `(globalThis as any).renderApp = (props: any) => {
+	const req = (globalThis as any).__REQUEST__;
+	if (!req) {
+		return "<p>" + props.name + " anonymous</p>";
+	}
+	return "<p>" + props.name + " " + req.method + " " + req.url + " " + req.cookies.theme + " " + req.headers["accept-language"] + "</p>";
+};`,
+		ClientEntry: testClientEntry,
+	})
+	if err != nil {
+		t.Fatalf("NewReactApp() failed: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/products?page=2", nil)
+	req.Header.Set("Accept-Language", "nl-NL")
+	req.AddCookie(&http.Cookie{Name: "theme", Value: "dark"})
+
+	html, err := app.RenderWith(RenderOptions{RequestContext: NewRequestContext(req)}, map[string]interface{}{"name": "Ada"})
+	if err != nil {
+		t.Fatalf("RenderWith() failed: %v", err)
+	}
+	if want := "<p>Ada GET /products?page=2 dark nl-NL</p>"; html != want {
+		t.Errorf("RenderWith() = %s, want %s", html, want)
+	}
+
+	html, err = app.Render(map[string]interface{}{"name": "Ada"})
+	if err != nil {
+		t.Fatalf("Render() failed: %v", err)
+	}
+	if want := "<p>Ada anonymous</p>"; html != want {
+		t.Errorf("expected request context to be cleared, got %s", html)
+	}
+
+	// RenderStruct must not see the previous request either.
+	if _, err := app.RenderWith(RenderOptions{RequestContext: NewRequestContext(req)}, map[string]interface{}{"name": "Ada"}); err != nil {
+		t.Fatalf("RenderWith() failed: %v", err)
+	}
+	html, err = app.RenderStruct(struct {
+		Name string `json:"name"`
+	}{Name: "Grace"})
+	if err != nil {
+		t.Fatalf("RenderStruct() failed: %v", err)
+	}
+	if want := "<p>Grace anonymous</p>"; html != want {
+		t.Errorf("expected RenderStruct to clear the request context, got %s", html)
+	}
+}
+
+func TestReactAppBuildStats(t *testing.T) {
+	app, err := NewReactApp(ReactAppOptions{
+		Polyfills:   []string{`var polyfilled = true;`},
+		SSREntry:    `(globalThis as any).renderApp = (props: any) => "<p>" + props.name + "</p>";`,
+		ClientEntry: testClientEntry,
+	})
+	if err != nil {
+		t.Fatalf("NewReactApp() failed: %v", err)
+	}
+
+	bundle, load := app.BuildStats()
+	if bundle <= 0 {
+		t.Errorf("expected a positive bundle duration, got %v", bundle)
+	}
+	if load <= 0 {
+		t.Errorf("expected a positive load duration, got %v", load)
+	}
+}
+
+func TestReactAppPolyfillDir(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"01-first.js":  `var firstPolyfill = "one";`,
+		"02-second.js": `var secondPolyfill = firstPolyfill + "+two";`,
+		"notes.txt":    `this is not javascript`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+
+	app, err := NewReactApp(ReactAppOptions{
+		PolyfillDir: dir,
+		SSREntry:    `(globalThis as any).renderApp = () => (globalThis as any).secondPolyfill;`,
+		ClientEntry: testClientEntry,
+	})
+	if err != nil {
+		t.Fatalf("NewReactApp() failed: %v", err)
+	}
+
+	html, err := app.Render(nil)
+	if err != nil {
+		t.Fatalf("Render() failed: %v", err)
+	}
+	if html != "one+two" {
+		t.Errorf("expected both polyfills to load in order, got %q", html)
+	}
+}
+
+func TestReactAppPolyfillDirMissing(t *testing.T) {
+	_, err := NewReactApp(ReactAppOptions{
+		PolyfillDir: filepath.Join(t.TempDir(), "missing"),
+		SSREntry:    `(globalThis as any).renderApp = () => "";`,
+		ClientEntry: testClientEntry,
+	})
+	if err == nil || !strings.Contains(err.Error(), "read polyfill dir") {
+		t.Fatalf("expected a polyfill dir error, got %v", err)
+	}
+}
+
+func TestReactAppUpdateClientEntry(t *testing.T) {
+	app, err := NewReactApp(ReactAppOptions{
+		SSREntry:    `(globalThis as any).renderApp = (props: any) => "<p>" + props.name + "</p>";`,
+		ClientEntry: testClientEntry,
+	})
+	if err != nil {
+		t.Fatalf("NewReactApp() failed: %v", err)
+	}
+
+	ssrBefore := app.bundles.Load().SSR
+	clientBefore := app.ClientBundle()
+
+	if err := app.UpdateClientEntry(`console.log("client boot v2");`); err != nil {
+		t.Fatalf("UpdateClientEntry() failed: %v", err)
+	}
+	if app.ClientBundle() == clientBefore {
+		t.Error("expected the client bundle to change")
+	}
+	if !strings.Contains(app.ClientBundle(), "client boot v2") {
+		t.Errorf("client bundle does not contain the new entry: %s", app.ClientBundle())
+	}
+	if app.bundles.Load().SSR != ssrBefore {
+		t.Error("expected the SSR bundle to be left untouched")
+	}
+
+	if err := app.UpdateClientEntry(`const = ;`); err == nil {
+		t.Fatal("expected an invalid client entry to fail")
+	}
+	if !strings.Contains(app.ClientBundle(), "client boot v2") {
+		t.Error("expected a failed rebuild to keep the previous client bundle")
+	}
+}
+
+func TestReactAppUpdateSSREntry(t *testing.T) {
+	app, err := NewReactApp(ReactAppOptions{
+		SSREntry:    `(globalThis as any).renderApp = (props: any) => "<p>" + props.name + "</p>";`,
+		ClientEntry: testClientEntry,
+	})
+	if err != nil {
+		t.Fatalf("NewReactApp() failed: %v", err)
+	}
+	clientBefore := app.ClientBundle()
+
+	if err := app.UpdateSSREntry(`(globalThis as any).renderApp = (props: any) => "<h1>" + props.name + "</h1>";`); err != nil {
+		t.Fatalf("UpdateSSREntry() failed: %v", err)
+	}
+	html, err := app.Render(map[string]interface{}{"name": "Ada"})
+	if err != nil {
+		t.Fatalf("Render() failed: %v", err)
+	}
+	if html != "<h1>Ada</h1>" {
+		t.Errorf("expected the rebuilt renderApp, got %q", html)
+	}
+	if app.ClientBundle() != clientBefore {
+		t.Error("expected the client bundle to be left untouched")
+	}
+
+	err = app.UpdateSSREntry(`(globalThis as any).renderApp = "nope";`)
+	if !errors.Is(err, ErrRenderAppNotFunction) {
+		t.Fatalf("expected ErrRenderAppNotFunction, got %v", err)
+	}
+	err = app.UpdateSSREntry(`(globalThis as any).leaked = 1; (globalThis as any).renderApp = () => "half"; throw new Error("boom");`)
+	var loadErr *RuntimeLoadError
+	if !errors.As(err, &loadErr) {
+		t.Fatalf("expected a RuntimeLoadError, got %v", err)
+	}
+
+	// Both rejected updates must leave the previous renderApp in place.
+	html, err = app.Render(map[string]interface{}{"name": "Ada"})
+	if err != nil {
+		t.Fatalf("Render() after a rejected update failed: %v", err)
+	}
+	if html != "<h1>Ada</h1>" {
+		t.Errorf("expected the previous renderApp after a rejected update, got %q", html)
+	}
+	leaked, err := app.Runner().Eval("typeof leaked")
+	if err != nil {
+		t.Fatalf("Eval() failed: %v", err)
+	}
+	if got := ExportString(leaked); got != "undefined" {
+		t.Errorf("expected globals of the rejected bundle to be rolled back, got typeof %s", got)
+	}
+}
+
+func TestReactAppUpdateSSREntryHoldsRunnerLock(t *testing.T) {
+	app, err := NewReactApp(ReactAppOptions{
+		RunnerOptions: []Option{WithSynchronized()},
+		SSREntry:      `(globalThis as any).renderApp = (props: any) => "<p>" + props.name + "</p>";`,
+		ClientEntry:   testClientEntry,
+	})
+	if err != nil {
+		t.Fatalf("NewReactApp() failed: %v", err)
+	}
+
+	// The rejected bundle's renderApp getter pauses each time it is read,
+	// while UpdateSSREntry checks it. A concurrent Eval started then must
+	// wait for the rollback instead of seeing the rejected bundle.
+	entered := make(chan struct{})
+	var once sync.Once
+	app.Runner().SetGlobal("pause", func() {
+		once.Do(func() { close(entered) })
+		time.Sleep(20 * time.Millisecond)
+	})
+	observed := make(chan string, 1)
+	go func() {
+		<-entered
+		result, err := app.Runner().Eval(`typeof renderApp === "function" ? renderApp({name: "Ada"}) : typeof renderApp`)
+		if err != nil {
+			observed <- err.Error()
+			return
+		}
+		observed <- ExportString(result)
+	}()
+
+	err = app.UpdateSSREntry(`Object.defineProperty(globalThis, "renderApp", {
+	configurable: true,
+	get() { (globalThis as any).pause(); return "half"; },
+});`)
+	if !errors.Is(err, ErrRenderAppNotFunction) {
+		t.Fatalf("expected ErrRenderAppNotFunction, got %v", err)
+	}
+	select {
+	case got := <-observed:
+		if got != "<p>Ada</p>" {
+			t.Errorf("concurrent Eval saw %q, want the previous renderApp", got)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("concurrent Eval did not finish")
+	}
+}
+
+func TestReactAppRenderCached(t *testing.T) {
+	app, err := NewReactApp(ReactAppOptions{
+		SSREntry: `(globalThis as any).renders = 0;
+(globalThis as any).renderApp = (props: any) => {
+	(globalThis as any).renders++;
+	return "<p>" + props.name + "</p>";
+};`,
+		ClientEntry:     testClientEntry,
+		RenderCacheSize: 1,
+	})
+	if err != nil {
+		t.Fatalf("NewReactApp() failed: %v", err)
+	}
+	renders := func() int64 {
+		t.Helper()
+		value, err := app.Runner().Eval("renders")
+		if err != nil {
+			t.Fatalf("Eval() failed: %v", err)
+		}
+		return ExportInt(value)
+	}
+
+	now := time.Now()
+	app.renderCache.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		html, err := app.RenderCached("home", map[string]interface{}{"name": "Ada"}, time.Minute)
+		if err != nil {
+			t.Fatalf("RenderCached() failed: %v", err)
+		}
+		if html != "<p>Ada</p>" {
+			t.Errorf("unexpected markup %q", html)
+		}
+	}
+	if got := renders(); got != 1 {
+		t.Errorf("expected the second call to hit the cache, renderApp ran %d times", got)
+	}
+
+	now = now.Add(2 * time.Minute)
+	if _, err := app.RenderCached("home", map[string]interface{}{"name": "Ada"}, time.Minute); err != nil {
+		t.Fatalf("RenderCached() failed: %v", err)
+	}
+	if got := renders(); got != 2 {
+		t.Errorf("expected an expired entry to re-render, renderApp ran %d times", got)
+	}
+
+	// With room for one entry, caching "about" evicts "home".
+	if _, err := app.RenderCached("about", map[string]interface{}{"name": "Bob"}, time.Minute); err != nil {
+		t.Fatalf("RenderCached() failed: %v", err)
+	}
+	if _, err := app.RenderCached("home", map[string]interface{}{"name": "Ada"}, time.Minute); err != nil {
+		t.Fatalf("RenderCached() failed: %v", err)
+	}
+	if got := renders(); got != 4 {
+		t.Errorf("expected the size bound to evict the oldest entry, renderApp ran %d times", got)
+	}
+}
+
+func TestReactAppRenderCachedAfterUpdate(t *testing.T) {
+	app, err := NewReactApp(ReactAppOptions{
+		SSREntry:    `(globalThis as any).renderApp = (props: any) => "<p>" + props.name + "</p>";`,
+		ClientEntry: testClientEntry,
+	})
+	if err != nil {
+		t.Fatalf("NewReactApp() failed: %v", err)
+	}
+	props := map[string]interface{}{"name": "Ada"}
+
+	if html, err := app.RenderCached("home", props, time.Hour); err != nil || html != "<p>Ada</p>" {
+		t.Fatalf("RenderCached() = %q, %v", html, err)
+	}
+	if err := app.UpdateSSREntry(`(globalThis as any).renderApp = (props: any) => "<h1>" + props.name + "</h1>";`); err != nil {
+		t.Fatalf("UpdateSSREntry() failed: %v", err)
+	}
+	html, err := app.RenderCached("home", props, time.Hour)
+	if err != nil {
+		t.Fatalf("RenderCached() failed: %v", err)
+	}
+	if html != "<h1>Ada</h1>" {
+		t.Errorf("RenderCached() after UpdateSSREntry = %q, want markup from the new bundle", html)
+	}
+
+	// A rejected update keeps the bundle, so the cached markup stays valid.
+	if err := app.UpdateSSREntry(`(globalThis as any).renderApp = 1;`); err == nil {
+		t.Fatal("expected UpdateSSREntry to fail")
+	}
+	if got := app.renderCache.keys(); len(got) != 1 {
+		t.Errorf("expected a rejected update to keep the cache, got keys %v", got)
+	}
+}
+
+func TestReactAppRenderBatch(t *testing.T) {
+	app, err := NewReactApp(ReactAppOptions{
+		SSREntry: `(globalThis as any).renderApp = (props: any) => {
+	if (!props.slug) throw new Error("missing slug");
+	return "<article>" + props.slug + "</article>";
+};`,
+		ClientEntry: testClientEntry,
+	})
+	if err != nil {
+		t.Fatalf("NewReactApp() failed: %v", err)
+	}
+
+	pages, errs := app.RenderBatch([]map[string]interface{}{
+		{"slug": "first"},
+		{"slug": "second"},
+		{},
+		{"slug": "third"},
+	})
+	want := []string{"<article>first</article>", "<article>second</article>", "", "<article>third</article>"}
+	for i := range want {
+		if pages[i] != want[i] {
+			t.Errorf("page %d = %q, want %q", i, pages[i], want[i])
+		}
+		if (errs[i] != nil) != (i == 2) {
+			t.Errorf("page %d error = %v", i, errs[i])
+		}
+	}
+}
+
+func TestReactAppShareBundles(t *testing.T) {
+	ClearSharedBundleCache()
+	t.Cleanup(ClearSharedBundleCache)
+
+	logger := &captureLogger{}
+	opts := ReactAppOptions{
+		RunnerOptions: []Option{WithLogger(logger)},
+		SSREntry:      `(globalThis as any).renderApp = (props: any) => "<p>" + props.tenant + "</p>";`,
+		ClientEntry:   testClientEntry,
+		ShareBundles:  true,
+	}
+
+	var apps []*ReactApp
+	for _, tenant := range []string{"acme", "globex"} {
+		app, err := NewReactApp(opts)
+		if err != nil {
+			t.Fatalf("NewReactApp() failed: %v", err)
+		}
+		html, err := app.Render(map[string]interface{}{"tenant": tenant})
+		if err != nil {
+			t.Fatalf("Render() failed: %v", err)
+		}
+		if html != "<p>"+tenant+"</p>" {
+			t.Errorf("Expected <p>%s</p>, got %q", tenant, html)
+		}
+		apps = append(apps, app)
+	}
+
+	builds := func() int {
+		n := 0
+		for _, ev := range logger.events {
+			if ev.msg == "bundle built" {
+				n++
+			}
+		}
+		return n
+	}
+	if got := builds(); got != 1 {
+		t.Errorf("Expected esbuild to run once, got %d builds", got)
+	}
+	if _, ok := logger.find("bundle cache hit"); !ok {
+		t.Error("expected a 'bundle cache hit' event")
+	}
+	if apps[0].bundles.Load() != apps[1].bundles.Load() {
+		t.Error("Expected both apps to share the same bundles")
+	}
+
+	opts.Define = map[string]string{"__TENANT__": `"other"`}
+	if _, err := NewReactApp(opts); err != nil {
+		t.Fatalf("NewReactApp() failed: %v", err)
+	}
+	if got := builds(); got != 2 {
+		t.Errorf("Expected different options to trigger a new build, got %d builds", got)
+	}
+}
+
+func TestReactAppShareBundlesRebuildsChangedFiles(t *testing.T) {
+	ClearSharedBundleCache()
+	t.Cleanup(ClearSharedBundleCache)
+
+	dir := t.TempDir()
+	greeting := filepath.Join(dir, "greeting.ts")
+	if err := os.WriteFile(greeting, []byte(`export const greeting = "hello";`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	logger := &captureLogger{}
+	opts := ReactAppOptions{
+		RunnerOptions: []Option{WithLogger(logger)},
+		SSREntry: `import { greeting } from "./greeting";
+(globalThis as any).renderApp = () => greeting;`,
+		ClientEntry:  testClientEntry,
+		ResolveDir:   dir,
+		ShareBundles: true,
+	}
+	render := func() string {
+		t.Helper()
+		app, err := NewReactApp(opts)
+		if err != nil {
+			t.Fatalf("NewReactApp() failed: %v", err)
+		}
+		html, err := app.Render(nil)
+		if err != nil {
+			t.Fatalf("Render() failed: %v", err)
+		}
+		return html
+	}
+	builds := func() int {
+		n := 0
+		for _, ev := range logger.events {
+			if ev.msg == "bundle built" {
+				n++
+			}
+		}
+		return n
+	}
+
+	if got := render(); got != "hello" {
+		t.Fatalf("Expected hello, got %q", got)
+	}
+	if err := os.WriteFile(greeting, []byte(`export const greeting = "goodbye";`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(greeting, later, later); err != nil {
+		t.Fatal(err)
+	}
+	if got := render(); got != "goodbye" {
+		t.Errorf("Expected the edited file to be rebuilt, got %q", got)
+	}
+	if got := render(); got != "goodbye" {
+		t.Errorf("Expected the rebuilt bundles to be cached, got %q", got)
+	}
+	if got := builds(); got != 2 {
+		t.Errorf("Expected one rebuild after the edit, got %d builds", got)
+	}
+}
+
+func TestReactAppPrecompiled(t *testing.T) {
+	built, err := NewReactApp(ReactAppOptions{
+		SSREntry:    `(globalThis as any).renderApp = (props: any) => "<h1>" + props.title + "</h1>";`,
+		ClientEntry: testClientEntry,
+	})
+	if err != nil {
+		t.Fatalf("NewReactApp() failed: %v", err)
+	}
+	program, err := Compile("app-ssr.js", built.SSRBundle())
+	if err != nil {
+		t.Fatalf("Compile() failed: %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		app, err := NewReactApp(ReactAppOptions{
+			PrecompiledSSR:    program,
+			PrecompiledClient: built.ClientBundle(),
+		})
+		if err != nil {
+			t.Fatalf("NewReactApp() with precompiled bundles failed: %v", err)
+		}
+		html, err := app.Render(map[string]interface{}{"title": "Precompiled"})
+		if err != nil {
+			t.Fatalf("Render() failed: %v", err)
+		}
+		if html != "<h1>Precompiled</h1>" {
+			t.Errorf("Expected precompiled render output, got %q", html)
+		}
+		if app.ClientBundle() != built.ClientBundle() || app.ClientBundleHash() != built.ClientBundleHash() {
+			t.Error("Expected the precompiled client bundle and its hash to be served")
+		}
+		if bundle, _ := app.BuildStats(); bundle != 0 {
+			t.Errorf("Expected no bundling time, got %v", bundle)
+		}
+		if err := app.UpdateClientEntry(testClientEntry); err == nil {
+			t.Error("Expected UpdateClientEntry to fail on a precompiled app")
+		}
+	}
+
+	if _, err := NewReactApp(ReactAppOptions{PrecompiledClient: built.ClientBundle()}); err == nil {
+		t.Error("Expected PrecompiledClient without PrecompiledSSR to be rejected")
+	}
+}
+
+type mappedCardProps struct {
+	UserName string       `js:"userName"`
+	Tags     []string     `js:"tags"`
+	Internal string       `js:"-"`
+	Owner    *mappedOwner `js:"owner"`
+}
+
+type mappedOwner struct {
+	DisplayName string `js:"displayName"`
+}
+
+func TestReactAppPropsFieldNameMapper(t *testing.T) {
+	app, err := NewReactApp(ReactAppOptions{
+		SSREntry: `(globalThis as any).renderApp = (props: any) =>
+	props.card.userName + "|" + props.card.tags.join(",") + "|" + props.card.owner.displayName + "|" + ("Internal" in props.card);`,
+		ClientEntry:          testClientEntry,
+		PropsFieldNameMapper: goja.TagFieldNameMapper("js", true),
+	})
+	if err != nil {
+		t.Fatalf("NewReactApp() failed: %v", err)
+	}
+
+	card := mappedCardProps{
+		UserName: "ada",
+		Tags:     []string{"math", "code"},
+		Internal: "secret",
+		Owner:    &mappedOwner{DisplayName: "Ada L."},
+	}
+	want := "ada|math,code|Ada L.|false"
+
+	html, err := app.Render(map[string]interface{}{"card": card})
+	if err != nil {
+		t.Fatalf("Render() failed: %v", err)
+	}
+	if html != want {
+		t.Errorf("Render() = %q, want %q", html, want)
+	}
+
+	html, err = app.RenderStruct(struct {
+		Card mappedCardProps `js:"card"`
+	}{card})
+	if err != nil {
+		t.Fatalf("RenderStruct() failed: %v", err)
+	}
+	if html != want {
+		t.Errorf("RenderStruct() = %q, want %q", html, want)
+	}
+
+	// The mapper must not leak into the rest of the runtime.
+	app.Runner().SetGlobal("plain", card)
+	result, err := app.Runner().Eval("plain.UserName")
+	if err != nil {
+		t.Fatalf("Eval() failed: %v", err)
+	}
+	if got := ExportString(result); got != "ada" {
+		t.Errorf("expected default field names outside render props, got %q", got)
+	}
+}
+
+type mappedTreeNode struct {
+	Name     string            `js:"name"`
+	Parent   *mappedTreeNode   `js:"parent"`
+	Children []*mappedTreeNode `js:"children"`
+}
+
+func TestReactAppPropsFieldNameMapperCycle(t *testing.T) {
+	app, err := NewReactApp(ReactAppOptions{
+		SSREntry:             `(globalThis as any).renderApp = (props: any) => props.node.name + ":" + props.node.children.length;`,
+		ClientEntry:          testClientEntry,
+		PropsFieldNameMapper: goja.TagFieldNameMapper("js", true),
+	})
+	if err != nil {
+		t.Fatalf("NewReactApp() failed: %v", err)
+	}
+
+	// A value shared by siblings is not a cycle.
+	leaf := &mappedTreeNode{Name: "leaf"}
+	html, err := app.Render(map[string]interface{}{"node": &mappedTreeNode{Name: "root", Children: []*mappedTreeNode{leaf, leaf}}})
+	if err != nil {
+		t.Fatalf("Render() with shared children failed: %v", err)
+	}
+	if html != "root:2" {
+		t.Errorf("Render() = %q, want %q", html, "root:2")
+	}
+
+	root := &mappedTreeNode{Name: "root"}
+	root.Children = []*mappedTreeNode{{Name: "child", Parent: root}}
+
+	if _, err := app.Render(map[string]interface{}{"node": root}); err == nil || !strings.Contains(err.Error(), "cycle") {
+		t.Errorf("Render() error = %v, want a cycle error", err)
+	}
+	if _, err := app.RenderStruct(struct {
+		Node *mappedTreeNode `js:"node"`
+	}{root}); err == nil || !strings.Contains(err.Error(), "cycle") {
+		t.Errorf("RenderStruct() error = %v, want a cycle error", err)
+	}
+
+	markup, errs := app.RenderBatch([]map[string]interface{}{{"node": root}, {"node": leaf}})
+	if errs[0] == nil || !strings.Contains(errs[0].Error(), "cycle") {
+		t.Errorf("RenderBatch() error[0] = %v, want a cycle error", errs[0])
+	}
+	if errs[1] != nil || markup[1] != "leaf:0" {
+		t.Errorf("RenderBatch()[1] = %q, %v, want %q", markup[1], errs[1], "leaf:0")
+	}
+}
+
+func TestReactAppRenderDocumentScriptNonce(t *testing.T) {
+	app, err := NewReactApp(ReactAppOptions{
+		SSREntry:    `(globalThis as any).renderApp = (props: any) => "<p>" + props.name + "</p>";`,
+		ClientEntry: testClientEntry,
+	})
+	if err != nil {
+		t.Fatalf("NewReactApp() failed: %v", err)
+	}
+
+	page, err := app.RenderDocument(DocumentOptions{
+		Title:       "Home & Away",
+		ScriptNonce: "r4nd0m",
+	}, map[string]interface{}{"name": "Ada"})
+	if err != nil {
+		t.Fatalf("RenderDocument() failed: %v", err)
+	}
+
+	for _, want := range []string{
+		`<title>Home &amp; Away</title>`,
+		`<div id="root"><p>Ada</p></div>`,
+		`<script nonce="r4nd0m">window.__INITIAL_PROPS__ = {"name":"Ada"};</script>`,
+		`<script nonce="r4nd0m" src="/static/client.` + app.ClientBundleHash() + `.js"></script>`,
+	} {
+		if !strings.Contains(page, want) {
+			t.Errorf("document is missing %s:\n%s", want, page)
+		}
+	}
+
+	page, err = app.RenderDocument(DocumentOptions{}, nil)
+	if err != nil {
+		t.Fatalf("RenderDocument() failed: %v", err)
+	}
+	if strings.Contains(page, "nonce=") {
+		t.Errorf("expected no nonce attribute without ScriptNonce:\n%s", page)
+	}
+}
+
+func TestReactAppRenderParts(t *testing.T) {
+	app, err := NewReactApp(ReactAppOptions{
+		SSREntry: `(globalThis as any).renderApp = (props: any) => ({
+	html: "<p>" + props.name + "</p>",
+	head: '<meta name="description" content="' + props.name + '\'s page" />',
+	bodyAttributes: { class: "dark", "data-page": "home", hidden: null },
+});`,
+		ClientEntry: testClientEntry,
+	})
+	if err != nil {
+		t.Fatalf("NewReactApp() failed: %v", err)
+	}
+	props := map[string]interface{}{"name": "Ada"}
+
+	parts, err := app.RenderParts(props)
+	if err != nil {
+		t.Fatalf("RenderParts() failed: %v", err)
+	}
+	want := Parts{
+		HTML:           "<p>Ada</p>",
+		Head:           `<meta name="description" content="Ada's page" />`,
+		BodyAttributes: map[string]string{"class": "dark", "data-page": "home"},
+	}
+	if !reflect.DeepEqual(parts, want) {
+		t.Errorf("RenderParts() = %+v, want %+v", parts, want)
+	}
+
+	html, err := app.Render(props)
+	if err != nil {
+		t.Fatalf("Render() failed: %v", err)
+	}
+	if html != "<p>Ada</p>" {
+		t.Errorf("Render() = %q, want only the html part", html)
+	}
+
+	page, err := app.RenderDocument(DocumentOptions{Title: "Home"}, props)
+	if err != nil {
+		t.Fatalf("RenderDocument() failed: %v", err)
+	}
+	head, body, ok := strings.Cut(page, "</head>")
+	if !ok {
+		t.Fatalf("document has no </head>:\n%s", page)
+	}
+	if !strings.Contains(head, `<title>Home</title>`+"\n"+want.Head) {
+		t.Errorf("expected the meta tag after the title in <head>:\n%s", page)
+	}
+	if !strings.Contains(body, `<body class="dark" data-page="home">`) {
+		t.Errorf("expected body attributes on <body>:\n%s", page)
+	}
+	if !strings.Contains(body, `<div id="root"><p>Ada</p></div>`) {
+		t.Errorf("expected the html part in the root element:\n%s", page)
+	}
+
+	// A plain string result is still the markup, with no head content.
+	plain, err := NewReactApp(ReactAppOptions{
+		SSREntry:    `(globalThis as any).renderApp = () => "<p>plain</p>";`,
+		ClientEntry: testClientEntry,
+	})
+	if err != nil {
+		t.Fatalf("NewReactApp() failed: %v", err)
+	}
+	parts, err = plain.RenderParts(nil)
+	if err != nil {
+		t.Fatalf("RenderParts() failed: %v", err)
+	}
+	if !reflect.DeepEqual(parts, Parts{HTML: "<p>plain</p>"}) {
+		t.Errorf("RenderParts() = %+v, want only HTML", parts)
+	}
+}
+
+func TestReactAppRenderDocumentBodyAttributeNames(t *testing.T) {
+	logger := &captureLogger{}
+	app, err := NewReactApp(ReactAppOptions{
+		RunnerOptions: []Option{WithLogger(logger)},
+		SSREntry: `(globalThis as any).renderApp = (props: any) => ({
+	html: "<p></p>",
+	bodyAttributes: { class: "dark", "data-theme": "x", [props.key]: "1" },
+});`,
+		ClientEntry: testClientEntry,
+	})
+	if err != nil {
+		t.Fatalf("NewReactApp() failed: %v", err)
+	}
+
+	hostile := `x><script>alert(1)</script><b a`
+	page, err := app.RenderDocument(DocumentOptions{}, map[string]interface{}{"key": hostile})
+	if err != nil {
+		t.Fatalf("RenderDocument() failed: %v", err)
+	}
+	if strings.Contains(page, "<script>alert") {
+		t.Errorf("hostile attribute name was written to the page:\n%s", page)
+	}
+	if !strings.Contains(page, `<body class="dark" data-theme="x">`) {
+		t.Errorf("expected the valid body attributes to be kept:\n%s", page)
+	}
+	if _, ok := logger.find("skipping invalid body attribute name"); !ok {
+		t.Error("expected a warning about the skipped attribute name")
+	}
+}
+
+func TestReactAppClientBundleHash(t *testing.T) {
+	newApp := func(clientEntry string) *ReactApp {
+		t.Helper()
+		app, err := NewReactApp(ReactAppOptions{
+			SSREntry:    `(globalThis as any).renderApp = () => "<p></p>";`,
+			ClientEntry: clientEntry,
+		})
+		if err != nil {
+			t.Fatalf("NewReactApp() failed: %v", err)
+		}
+		return app
+	}
+
+	first := newApp(testClientEntry)
+	second := newApp(testClientEntry)
+	hash := first.ClientBundleHash()
+	if hash == "" || hash != second.ClientBundleHash() {
+		t.Fatalf("expected identical bundles to share a hash, got %q and %q", hash, second.ClientBundleHash())
+	}
+
+	page, err := first.RenderDocument(DocumentOptions{}, nil)
+	if err != nil {
+		t.Fatalf("RenderDocument() failed: %v", err)
+	}
+	if want := `<script src="/static/client.` + hash + `.js"></script>`; !strings.Contains(page, want) {
+		t.Errorf("document is missing %s:\n%s", want, page)
+	}
+
+	if err := first.UpdateClientEntry(`console.log("client v2");`); err != nil {
+		t.Fatalf("UpdateClientEntry() failed: %v", err)
+	}
+	if first.ClientBundleHash() == hash {
+		t.Error("expected the hash to change with the bundle")
+	}
+}
+
+func TestReactAppRenderDocumentEscapesProps(t *testing.T) {
+	app, err := NewReactApp(ReactAppOptions{
+		SSREntry:    `(globalThis as any).renderApp = () => "<p>ok</p>";`,
+		ClientEntry: testClientEntry,
+	})
+	if err != nil {
+		t.Fatalf("NewReactApp() failed: %v", err)
+	}
+
+	hostile := "</script><script>alert(1)</script><!-- & \u2028\u2029"
+	page, err := app.RenderDocument(DocumentOptions{}, map[string]interface{}{"bio": hostile})
+	if err != nil {
+		t.Fatalf("RenderDocument() failed: %v", err)
+	}
+
+	if strings.Contains(page, "alert(1)</script>") || strings.Contains(page, "<script>alert") {
+		t.Fatalf("props broke out of the script tag:\n%s", page)
+	}
+	if got := strings.Count(page, "</script>"); got != 2 {
+		t.Errorf("expected exactly 2 closing script tags, got %d:\n%s", got, page)
+	}
+	for _, raw := range []string{"<!--", "\u2028", "\u2029"} {
+		if strings.Contains(page, raw) {
+			t.Errorf("expected %q to be escaped:\n%s", raw, page)
+		}
+	}
+
+	// The escaped JSON must still decode to the original value.
+	start := strings.Index(page, "window.__INITIAL_PROPS__ = ")
+	end := strings.Index(page[start:], ";</script>")
+	if start < 0 || end < 0 {
+		t.Fatalf("props script not found:\n%s", page)
+	}
+	runner := New()
+	result, err := runner.Eval("(" + page[start+len("window.__INITIAL_PROPS__ = "):start+end] + ").bio")
+	if err != nil {
+		t.Fatalf("Eval() failed: %v", err)
+	}
+	if got := ExportString(result); got != hostile {
+		t.Errorf("expected the escaped props to round-trip, got %q", got)
+	}
+}
+
+func TestReactAppErrorTypes(t *testing.T) {
+	_, err := NewReactApp(ReactAppOptions{
+		SSREntry:    `const broken: = ;`,
+		ClientEntry: testClientEntry,
+	})
+	var bundleErr *BundleError
+	if !errors.As(err, &bundleErr) {
+		t.Fatalf("expected a BundleError for a TS syntax error, got %T: %v", err, err)
+	}
+	var loadErr *RuntimeLoadError
+	if errors.As(err, &loadErr) {
+		t.Errorf("did not expect a RuntimeLoadError for a TS syntax error")
+	}
+
+	_, err = NewReactApp(ReactAppOptions{
+		SSREntry:    `throw new Error("boom at module scope");`,
+		ClientEntry: testClientEntry,
+	})
+	if !errors.As(err, &loadErr) {
+		t.Fatalf("expected a RuntimeLoadError for a throwing SSR script, got %T: %v", err, err)
+	}
+	if loadErr.Script != "app-ssr.js" || !strings.Contains(loadErr.Err.Error(), "boom at module scope") {
+		t.Errorf("unexpected RuntimeLoadError: script=%q err=%v", loadErr.Script, loadErr.Err)
+	}
+	if errors.As(err, &bundleErr) {
+		t.Errorf("did not expect a BundleError for a throwing SSR script")
+	}
+
+	_, err = NewReactApp(ReactAppOptions{
+		Polyfills:   []string{`null.boom;`},
+		SSREntry:    `(globalThis as any).renderApp = () => "";`,
+		ClientEntry: testClientEntry,
+	})
+	if !errors.As(err, &loadErr) || loadErr.Script != "polyfill[0]" {
+		t.Fatalf("expected a RuntimeLoadError for polyfill[0], got %v", err)
+	}
+}
@@ -0,0 +1,232 @@
+package jsrunner
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/dop251/goja"
+)
+
+// DocumentOptions controls the HTML page produced by ReactApp.RenderDocument.
+type DocumentOptions struct {
+	// Title is the page title. It is HTML-escaped.
+	Title string
+
+	// Head is raw HTML appended to <head>, such as stylesheets or meta tags.
+	// It is inserted verbatim and must be trusted.
+	Head string
+
+	// ClientBundleURL is where the browser loads ClientBundle from. Defaults
+	// to "/static/client.<hash>.js", with the hash from ClientBundleHash.
+	ClientBundleURL string
+
+	// RootID is the id of the element the markup is rendered into and the
+	// client entry hydrates. Defaults to "root".
+	RootID string
+
+	// ScriptNonce is placed as a nonce attribute on the props script and the
+	// client bundle script so they run under a strict Content-Security-Policy.
+	// Generate a fresh random value per response and send the same value in
+	// the header, e.g. "Content-Security-Policy: script-src 'nonce-<value>'".
+	ScriptNonce string
+
+	// RequestContext is exposed to the SSR entry as __REQUEST__, as in
+	// RenderWith.
+	RequestContext *RequestContext
+}
+
+const defaultRootID = "root"
+
+// Parts is the output of one render split into the pieces of a page, as
+// returned by RenderParts.
+//
+// renderApp may return a plain string, which becomes HTML with no head
+// content, or an object with these fields, all optional:
+//
+//	{
+//	  html: "<main>...</main>",                        // markup for the root element
+//	  head: "<title>Home</title><meta name=...>",     // raw HTML for <head>
+//	  bodyAttributes: { class: "dark", "data-page": "home" }
+//	}
+//
+// Render and the other string-returning methods use only html, so an SSR
+// entry can switch to the object form without breaking them.
+type Parts struct {
+	// HTML is the markup rendered inside the root element.
+	HTML string
+
+	// Head is raw HTML for <head>, such as <title> and <meta> tags collected
+	// while rendering. It is inserted verbatim.
+	Head string
+
+	// BodyAttributes are attributes for the <body> element, keyed by name.
+	// RenderDocument escapes the values and skips names that are not valid
+	// attribute names.
+	BodyAttributes map[string]string
+}
+
+// RenderParts is like Render but returns every part of renderApp's result
+// instead of only the markup, so head tags produced while rendering (by a
+// title or meta helper, for instance) can be placed in the page's <head>.
+// RenderDocument uses it to build the page.
+//
+// Example:
+//
+//	// SSR entry:
+//	// (globalThis as any).renderApp = (props: any) => ({
+//	//     html: renderToString(<App {...props} />),
+//	//     head: `<title>${props.title}</title>`,
+//	// });
+//
+//	parts, err := app.RenderParts(props)
+//	fmt.Fprintf(w, "<head>%s</head><body><div id=\"root\">%s</div></body>", parts.Head, parts.HTML)
+func (ra *ReactApp) RenderParts(props map[string]interface{}) (Parts, error) {
+	mapped, err := ra.mapProps(props)
+	if err != nil {
+		return Parts{}, err
+	}
+	return ra.render(RenderOptions{}, mapped)
+}
+
+// RenderDocument renders props and wraps the markup in a complete HTML page:
+// the markup inside the root element, the props serialized to
+// window.__INITIAL_PROPS__ for hydration (escaped so prop values cannot close
+// the script tag), and a script tag loading the client
+// bundle. The client entry should read its props from
+// window.__INITIAL_PROPS__ and hydrate the element with id RootID. When
+// renderApp returns {html, head, bodyAttributes} (see Parts), head is placed
+// in <head> after Title and Head, and bodyAttributes on the <body> element.
+//
+// Example:
+//
+//	nonce := newNonce()
+//	w.Header().Set("Content-Security-Policy", "script-src 'nonce-"+nonce+"'")
+//	page, err := app.RenderDocument(jsrunner.DocumentOptions{
+//	    Title:       "Home",
+//	    ScriptNonce: nonce,
+//	}, props)
+func (ra *ReactApp) RenderDocument(opts DocumentOptions, props map[string]interface{}) (string, error) {
+	props, err := ra.mapProps(props)
+	if err != nil {
+		return "", err
+	}
+
+	parts, err := ra.render(RenderOptions{RequestContext: opts.RequestContext}, props)
+	if err != nil {
+		return "", err
+	}
+
+	encodedProps, err := scriptSafeJSON(props)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode props: %w", err)
+	}
+
+	bundleURL := opts.ClientBundleURL
+	if bundleURL == "" {
+		bundleURL = "/static/client." + ra.ClientBundleHash() + ".js"
+	}
+	rootID := opts.RootID
+	if rootID == "" {
+		rootID = defaultRootID
+	}
+	nonceAttr := ""
+	if opts.ScriptNonce != "" {
+		nonceAttr = ` nonce="` + html.EscapeString(opts.ScriptNonce) + `"`
+	}
+
+	var b strings.Builder
+	b.WriteString("<!doctype html>\n<html>\n<head>\n<meta charset=\"utf-8\" />\n")
+	if opts.Title != "" {
+		b.WriteString("<title>" + html.EscapeString(opts.Title) + "</title>\n")
+	}
+	b.WriteString(opts.Head)
+	b.WriteString(parts.Head)
+	b.WriteString("</head>\n<body" + ra.bodyAttributes(parts.BodyAttributes) + ">\n")
+	b.WriteString(`<div id="` + html.EscapeString(rootID) + `">` + parts.HTML + "</div>\n")
+	b.WriteString("<script" + nonceAttr + ">window.__INITIAL_PROPS__ = " + string(encodedProps) + ";</script>\n")
+	b.WriteString(`<script` + nonceAttr + ` src="` + html.EscapeString(bundleURL) + `"></script>` + "\n")
+	b.WriteString("</body>\n</html>\n")
+	return b.String(), nil
+}
+
+// attributeNamePattern matches the attribute names bodyAttributes writes. It
+// is stricter than HTML requires, but excludes everything that could end the
+// tag or start another attribute.
+var attributeNamePattern = regexp.MustCompile(`^[A-Za-z_:][-A-Za-z0-9_:.]*$`)
+
+// bodyAttributes formats attrs as ` name="value"` pairs, sorted by name so the
+// output is stable. Values are escaped. Names come from renderApp and may
+// carry user input, so names that do not match attributeNamePattern are
+// skipped with a warning.
+func (ra *ReactApp) bodyAttributes(attrs map[string]string) string {
+	names := make([]string, 0, len(attrs))
+	for name := range attrs {
+		if !attributeNamePattern.MatchString(name) {
+			ra.runner.logger.Warn("skipping invalid body attribute name", "name", name)
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	for _, name := range names {
+		b.WriteString(" " + name + `="` + html.EscapeString(attrs[name]) + `"`)
+	}
+	return b.String()
+}
+
+// exportParts converts renderApp's result to Parts. Plain objects are read as
+// {html, head, bodyAttributes}; any other value is the markup itself.
+func exportParts(result goja.Value) Parts {
+	obj, ok := result.(*goja.Object)
+	if !ok || obj.ClassName() != "Object" {
+		return Parts{HTML: ExportString(result)}
+	}
+
+	parts := Parts{
+		HTML: optionalString(obj.Get("html")),
+		Head: optionalString(obj.Get("head")),
+	}
+	if attrs, ok := obj.Get("bodyAttributes").(*goja.Object); ok {
+		parts.BodyAttributes = make(map[string]string)
+		for _, name := range attrs.Keys() {
+			if value := attrs.Get(name); !isNullish(value) {
+				parts.BodyAttributes[name] = value.String()
+			}
+		}
+	}
+	return parts
+}
+
+// optionalString returns v as a string, or "" when it is undefined or null.
+func optionalString(v goja.Value) string {
+	if isNullish(v) {
+		return ""
+	}
+	return v.String()
+}
+
+func isNullish(v goja.Value) bool {
+	return v == nil || goja.IsUndefined(v) || goja.IsNull(v)
+}
+
+// scriptSafeJSON encodes v as JSON that can be embedded in an inline <script>.
+// '<', '>', and '&' are written as \u003c, \u003e, and \u0026 so a value such
+// as "</script>" cannot end the element or open an HTML comment, and U+2028
+// and U+2029 are escaped because older JavaScript engines treat them as line
+// terminators inside string literals. The result parses to the same value.
+func scriptSafeJSON(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(true)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
@@ -0,0 +1,83 @@
+package jsrunner
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+// SetSecretGlobal sets a string global like SetGlobal and registers value as a
+// secret: from then on, every occurrence of it in an error returned by the
+// runner's Eval, Call, LoadScript, Construct, and RunProgram methods (and in
+// the source passed to a WithErrorHandler callback) is replaced with "***".
+// This keeps API keys and tokens out of logs when a script or bound function
+// fails with a message that echoes them.
+//
+// Redaction only applies to the error text; errors.Is and errors.As still
+// reach the original error, whose own message is not redacted. Values
+// returned by scripts are never altered, so scripts can still use the secret.
+//
+// Example:
+//
+//	runner.SetSecretGlobal("apiKey", os.Getenv("API_KEY"))
+//	_, err := runner.Eval(`throw new Error("bad key " + apiKey)`)
+//	log.Println(err) // ... bad key *** ...
+func (r *Runner) SetSecretGlobal(name string, value string) {
+	r.SetGlobal(name, value)
+	r.secrets.add(value)
+}
+
+// secretSet holds the values redacted from runner errors. It has its own
+// lock because errors are redacted after the runner's lock is released.
+type secretSet struct {
+	mu     sync.RWMutex
+	values []string
+}
+
+func (s *secretSet) add(value string) {
+	if value == "" {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, existing := range s.values {
+		if existing == value {
+			return
+		}
+	}
+	s.values = append(s.values, value)
+	// Replace longer secrets first so one containing another is fully masked.
+	sort.SliceStable(s.values, func(i, j int) bool { return len(s.values[i]) > len(s.values[j]) })
+}
+
+// redact replaces every registered secret in text with "***".
+func (s *secretSet) redact(text string) string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, secret := range s.values {
+		text = strings.ReplaceAll(text, secret, "***")
+	}
+	return text
+}
+
+// redactedError presents err with its secrets masked.
+type redactedError struct {
+	msg string
+	err error
+}
+
+func (e *redactedError) Error() string { return e.msg }
+
+func (e *redactedError) Unwrap() error { return e.err }
+
+// redactError masks registered secrets in *err, leaving errors that do not
+// mention any secret untouched.
+func (r *Runner) redactError(err *error) {
+	if *err == nil {
+		return
+	}
+	msg := (*err).Error()
+	if redacted := r.secrets.redact(msg); redacted != msg {
+		*err = &redactedError{msg: redacted, err: *err}
+	}
+}
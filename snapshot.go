@@ -0,0 +1,184 @@
+package jsrunner
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/dop251/goja"
+)
+
+// globalSnapshot records the enumerable globals of a runtime at a point in
+// time. Plain objects, arrays, and dates are stored as deep clones so later
+// mutations of nested data do not leak into the snapshot.
+type globalSnapshot struct {
+	values map[string]goja.Value
+}
+
+// Snapshot captures the current values of the runner's enumerable globals so
+// RestoreSnapshot can later reset them without re-running any scripts. Take it
+// once setup is complete (scripts loaded, globals installed), then restore it
+// between requests or fuzzing iterations to get a clean environment cheaply.
+// A new snapshot replaces the previous one.
+//
+// Values are captured like a structured clone: primitives are copied, and
+// plain objects, arrays, and Date instances are deep-copied (cycles are
+// preserved). Everything else — functions, class instances, Maps, Sets, and
+// globals installed from Go via SetGlobal or WithGlobals — is captured by
+// reference, so restoring rebinds the original value but does not undo
+// changes made to its internals.
+//
+// Limitations:
+//   - Closure state is not captured; a function's private variables keep
+//     whatever values they hold at restore time.
+//   - let/const/class bindings at the top level are not global object
+//     properties and are not restored.
+//   - Changes to built-ins and prototypes (for example Array.prototype) are
+//     not reverted.
+//   - Globals declared with var after the snapshot cannot be deleted; they
+//     are reset to undefined instead.
+//
+// Example:
+//
+//	runner.LoadScript("app.js")
+//	if err := runner.Snapshot(); err != nil {
+//	    log.Fatal(err)
+//	}
+//	for _, input := range inputs {
+//	    runner.Call("handle", input)
+//	    runner.RestoreSnapshot()
+//	}
+func (r *Runner) Snapshot() error {
+	r.syncLock()
+	defer r.syncUnlock()
+
+	global := r.vm.GlobalObject()
+	values := make(map[string]goja.Value)
+	seen := make(map[*goja.Object]*goja.Object)
+
+	if ex := r.vm.Try(func() {
+		for _, name := range global.Keys() {
+			value := global.Get(name)
+			if _, isHost := r.globals[name]; isHost {
+				values[name] = value
+				continue
+			}
+			values[name] = r.cloneValue(value, seen)
+		}
+	}); ex != nil {
+		return fmt.Errorf("failed to snapshot globals: %w", ex)
+	}
+
+	r.snapshot = &globalSnapshot{values: values}
+	return nil
+}
+
+// RestoreSnapshot resets the runner's globals to the state captured by the
+// last Snapshot call. Globals added since then are removed, changed values
+// are reverted, and cloned objects are copied again so the snapshot can be
+// restored any number of times. See Snapshot for what is not restored.
+//
+// It returns an error if no snapshot has been taken.
+//
+// Example:
+//
+//	runner.SetGlobal("counter", 0)
+//	runner.Snapshot()
+//	runner.Eval("counter++")
+//	runner.RestoreSnapshot() // counter is 0 again
+func (r *Runner) RestoreSnapshot() error {
+	r.syncLock()
+	defer r.syncUnlock()
+
+	if r.snapshot == nil {
+		return errors.New("failed to restore snapshot: no snapshot taken")
+	}
+
+	global := r.vm.GlobalObject()
+	seen := make(map[*goja.Object]*goja.Object)
+
+	if ex := r.vm.Try(func() {
+		for _, name := range global.Keys() {
+			if _, ok := r.snapshot.values[name]; ok {
+				continue
+			}
+			if err := global.Delete(name); err != nil {
+				_ = global.Set(name, goja.Undefined())
+			}
+		}
+		for name, value := range r.snapshot.values {
+			if _, isHost := r.globals[name]; !isHost {
+				value = r.cloneValue(value, seen)
+			}
+			// Non-writable globals (for example frozen helpers) cannot have
+			// changed, so a failed assignment is safe to ignore.
+			_ = global.Set(name, value)
+		}
+	}); ex != nil {
+		return fmt.Errorf("failed to restore snapshot: %w", ex)
+	}
+
+	return nil
+}
+
+// cloneValue returns a structured clone of value. Plain objects, arrays, and
+// dates are copied recursively; other values are returned as is. seen maps
+// already-cloned objects to their copies so shared references and cycles are
+// preserved.
+func (r *Runner) cloneValue(value goja.Value, seen map[*goja.Object]*goja.Object) goja.Value {
+	obj, ok := value.(*goja.Object)
+	if !ok {
+		return value
+	}
+	if clone, ok := seen[obj]; ok {
+		return clone
+	}
+
+	switch obj.ClassName() {
+	case "Array":
+		clone := r.vm.NewArray()
+		seen[obj] = clone
+		length := obj.Get("length").ToInteger()
+		for i := int64(0); i < length; i++ {
+			_ = clone.Set(fmt.Sprint(i), r.cloneValue(obj.Get(fmt.Sprint(i)), seen))
+		}
+		return clone
+	case "Date":
+		clone, err := r.vm.New(r.vm.Get("Date"), obj)
+		if err != nil {
+			return obj
+		}
+		seen[obj] = clone
+		return clone
+	case "Object":
+		if !r.isPlainObject(obj) {
+			return obj
+		}
+		clone := r.vm.NewObject()
+		if obj.Prototype() == nil {
+			_ = clone.SetPrototype(nil)
+		}
+		seen[obj] = clone
+		for _, key := range obj.Keys() {
+			_ = clone.Set(key, r.cloneValue(obj.Get(key), seen))
+		}
+		return clone
+	}
+	return obj
+}
+
+// isPlainObject reports whether obj is an ordinary object whose prototype is
+// Object.prototype or null, i.e. an object literal rather than a class
+// instance. Wrapped Go structs and typed maps export to their Go type and are
+// rejected here.
+func (r *Runner) isPlainObject(obj *goja.Object) bool {
+	if obj.ExportType() != reflect.TypeOf(map[string]interface{}(nil)) {
+		return false
+	}
+	proto := obj.Prototype()
+	if proto == nil {
+		return true
+	}
+	objectProto := r.vm.Get("Object").ToObject(r.vm).Get("prototype")
+	return proto.SameAs(objectProto)
+}
@@ -0,0 +1,137 @@
+package jsrunner
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+
+	"github.com/dop251/goja"
+	"github.com/dop251/goja/parser"
+	"github.com/go-sourcemap/sourcemap"
+)
+
+var stackPositionPattern = regexp.MustCompile(`([^\s()]+):(\d+):(\d+)`)
+
+// RewriteStack maps positions in a JavaScript stack trace that reference
+// bundleName back to their original sources using the supplied source map.
+// Positions referencing other files, or positions the map does not cover, are
+// left untouched.
+//
+// Example:
+//
+//	stack := jsErr.String() // "at renderApp (app-ssr.js:1:5120(3))"
+//	readable, _ := jsrunner.RewriteStack(stack, "app-ssr.js", sourceMap)
+//	// "at renderApp (app-ssr.tsx:12:9(3))"
+func RewriteStack(stack, bundleName string, sourceMap []byte) (string, error) {
+	consumer, err := sourcemap.Parse(bundleName, sourceMap)
+	if err != nil {
+		return stack, fmt.Errorf("failed to parse source map: %w", err)
+	}
+
+	rewritten := stackPositionPattern.ReplaceAllStringFunc(stack, func(match string) string {
+		parts := stackPositionPattern.FindStringSubmatch(match)
+		if parts[1] != bundleName {
+			return match
+		}
+		line, _ := strconv.Atoi(parts[2])
+		col, _ := strconv.Atoi(parts[3])
+
+		source, _, origLine, origCol, ok := consumer.Source(line, col)
+		if !ok {
+			return match
+		}
+		return fmt.Sprintf("%s:%d:%d", source, origLine, origCol)
+	})
+
+	return rewritten, nil
+}
+
+// errorStack returns the JavaScript stack trace carried by err, falling back
+// to the plain error message when err does not wrap a goja.Exception.
+func errorStack(err error) string {
+	var jsErr *goja.Exception
+	if errors.As(err, &jsErr) {
+		return jsErr.String()
+	}
+	return err.Error()
+}
+
+// defaultSourceMapScriptName names scripts loaded by LoadScriptWithSourceMap
+// whose map does not declare a "file".
+const defaultSourceMapScriptName = "bundle.js"
+
+// LoadScriptWithSourceMap loads and executes code like LoadScriptString,
+// using sourceMap (the contents of a separate .map file, as shipped with
+// production bundles) to report positions in the original sources. Stack
+// traces of errors thrown by the script, including errors from later Call and
+// Eval invocations of its functions, show the original file names and line
+// and column numbers instead of positions in the minified code.
+//
+// Any sourceMappingURL comment in code is overridden. Function names in stack
+// traces are the ones in code, so keep them with esbuild's KeepNames when
+// minifying. When WithAsyncIteration rewrites the script, the rewrite shifts
+// every position the map describes, so the map is ignored and a warning is
+// logged; stack traces then show positions in the rewritten code.
+//
+// Example:
+//
+//	code, _ := os.ReadFile("dist/app.js")
+//	sourceMap, _ := os.ReadFile("dist/app.js.map")
+//	if err := runner.LoadScriptWithSourceMap(string(code), sourceMap); err != nil {
+//	    log.Fatal(err)
+//	}
+//	_, err := runner.Call("render")
+//	// at render (src/app.tsx:12:9(3))
+//
+// Returns an error if:
+//   - The source map is not valid JSON
+//   - The JavaScript code contains syntax errors
+//   - The JavaScript code throws a runtime error during execution
+func (r *Runner) LoadScriptWithSourceMap(code string, sourceMap []byte) (err error) {
+	defer r.reportError("LoadScriptWithSourceMap", code, &err)
+
+	var header struct {
+		File string `json:"file"`
+	}
+	if err := json.Unmarshal(sourceMap, &header); err != nil {
+		return fmt.Errorf("failed to parse source map: %w", err)
+	}
+	name := header.File
+	if name == "" {
+		name = defaultSourceMapScriptName
+	}
+
+	r.syncLock()
+	defer r.syncUnlock()
+
+	// The map describes code as given. If WithAsyncIteration rewrote it,
+	// every mapped position would be wrong, so load it without the map.
+	source := r.prepareSource(code)
+	mapOption := parser.WithDisableSourceMaps
+	if source == code {
+		// goja only consults a source map named by the last sourceMappingURL
+		// comment, so point one at the map supplied by the caller.
+		source += "\n//# sourceMappingURL=" + name + ".map\n"
+		mapOption = parser.WithSourceMapLoader(func(string) ([]byte, error) {
+			return sourceMap, nil
+		})
+	} else {
+		r.logger.Warn("source map ignored because the script was rewritten", "name", name)
+	}
+	ast, err := goja.Parse(name, source, mapOption)
+	if err != nil {
+		return fmt.Errorf("failed to execute script: %w", err)
+	}
+	program, err := goja.CompileAST(ast, r.strictMode)
+	if err != nil {
+		return fmt.Errorf("failed to execute script: %w", err)
+	}
+	if _, err := r.runProgram(program); err != nil {
+		return fmt.Errorf("failed to execute script: %w", err)
+	}
+
+	r.logger.Debug("script loaded", "name", name)
+	return nil
+}
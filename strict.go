@@ -0,0 +1,25 @@
+package jsrunner
+
+// WithStrictMode compiles every script and expression the runner executes
+// (LoadScript*, LoadScriptsAtomic, Eval, EvalWith, and their variants) as
+// strict-mode code, as if it began with "use strict". Assigning to an
+// undeclared variable then throws a ReferenceError instead of silently
+// creating a global, which keeps legacy scripts from leaking state.
+//
+// Strict mode only rejects undeclared assignments: top-level var and function
+// declarations still register as globals exactly as before. Legacy code that
+// relies on sloppy-mode features (with statements, octal literals, duplicate
+// parameter names) fails to compile. Programs passed to RunProgram keep the
+// mode they were compiled with. The option applies to Runner only;
+// NewEventLoopRunner ignores it and logs a warning.
+//
+// Example:
+//
+//	runner := jsrunner.New(jsrunner.WithStrictMode())
+//	runner.LoadScriptString(`var config = {}; function init() { count = 1; }`)
+//	_, err := runner.Call("init") // ReferenceError: count is not defined
+func WithStrictMode() Option {
+	return func(r *Runner) {
+		r.strictMode = true
+	}
+}
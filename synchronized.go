@@ -0,0 +1,74 @@
+package jsrunner
+
+// WithSynchronized makes a single Runner safe to share across goroutines.
+// SetGlobal, LoadScript*, Eval, Call, CallOn, and Construct acquire the
+// runner's internal mutex, so concurrent callers are serialized instead of
+// racing on the goja runtime.
+//
+// Serialization caps throughput at one script execution at a time. For
+// CPU-bound workloads prefer a pool of independent runners (one per worker);
+// use WithSynchronized when memory matters more than parallelism or when the
+// runner holds state that cannot be duplicated.
+//
+// Go functions invoked from JavaScript must not call back into the same
+// synchronized runner, as the mutex is not reentrant. EventLoopRunner already
+// serializes work on its loop; NewEventLoopRunner ignores the option and logs
+// a warning.
+//
+// Example:
+//
+//	runner := jsrunner.New(jsrunner.WithSynchronized())
+//	runner.LoadScriptString(`function double(x) { return x * 2; }`)
+//	for i := 0; i < 8; i++ {
+//	    go runner.Call("double", i)
+//	}
+func WithSynchronized() Option {
+	return func(r *Runner) {
+		r.synchronized = true
+	}
+}
+
+// Lock acquires the runner's internal mutex. Use it with Unlock to group
+// several operations into one critical section, or to guard direct GetVM
+// access when sharing a runner across goroutines.
+//
+// On a runner created with WithSynchronized, the high-level methods already
+// lock; calling them while holding Lock deadlocks. Use GetVM inside the
+// critical section instead.
+//
+// Example:
+//
+//	runner.Lock()
+//	vm := runner.GetVM()
+//	vm.Set("counter", vm.Get("counter").ToInteger()+1)
+//	runner.Unlock()
+func (r *Runner) Lock() {
+	r.mu.Lock()
+}
+
+// Unlock releases the mutex acquired by Lock.
+func (r *Runner) Unlock() {
+	r.mu.Unlock()
+}
+
+// syncLock acquires the mutex when the runner was created with WithSynchronized.
+func (r *Runner) syncLock() {
+	if r.synchronized {
+		r.mu.Lock()
+	}
+}
+
+// syncUnlock releases the mutex acquired by syncLock. It also clears an
+// interrupt left by a bound function in BoundErrorReturn mode and instruments
+// new functions for WithProfiler, since every operation that runs JavaScript
+// ends with it. The mutex is released even if that work panics, so a failure
+// there cannot leave the runner locked.
+func (r *Runner) syncUnlock() {
+	if r.synchronized {
+		defer r.mu.Unlock()
+	}
+	r.clearBoundError()
+	if r.profiler != nil {
+		r.profiler.instrument(r.globals)
+	}
+}
@@ -0,0 +1,180 @@
+package jsrunner
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/dop251/goja"
+)
+
+// WithTextCodecs installs Go-backed TextEncoder and TextDecoder constructors
+// for UTF-8, the encoding react-dom/server and most streaming code rely on.
+// TextEncoder supports encode (string to Uint8Array) and encodeInto;
+// TextDecoder supports decode of an ArrayBuffer, typed array, or DataView, the
+// fatal option (throw a TypeError on malformed input instead of substituting
+// U+FFFD), and ignoreBOM. Labels other than UTF-8 throw a RangeError, and the
+// stream option of decode is accepted but ignored, so multi-byte characters
+// must not be split across calls.
+//
+// Because they run natively, the codecs are faster than a JavaScript polyfill
+// and need no extra script in ReactAppOptions.Polyfills.
+//
+// Example:
+//
+//	runner := jsrunner.New(jsrunner.WithTextCodecs())
+//	runner.Eval(`new TextDecoder().decode(new TextEncoder().encode("héllo"))`) // "héllo"
+func WithTextCodecs() Option {
+	return func(r *Runner) {
+		r.textCodecs = true
+	}
+}
+
+// textCodecGlobals returns the TextEncoder and TextDecoder constructors.
+func textCodecGlobals() map[string]interface{} {
+	return map[string]interface{}{
+		"TextEncoder": newTextEncoder,
+		"TextDecoder": newTextDecoder,
+	}
+}
+
+// newTextEncoder implements `new TextEncoder()`.
+func newTextEncoder(call goja.ConstructorCall, vm *goja.Runtime) *goja.Object {
+	obj := call.This
+	_ = obj.DefineDataProperty("encoding", vm.ToValue("utf-8"), goja.FLAG_FALSE, goja.FLAG_TRUE, goja.FLAG_TRUE)
+
+	defineMethod(vm, obj, "encode", func(call goja.FunctionCall) goja.Value {
+		input := ""
+		if arg := call.Argument(0); !goja.IsUndefined(arg) {
+			input = arg.String()
+		}
+		arr, err := newUint8Array(vm, []byte(input))
+		if err != nil {
+			panic(vm.NewGoError(err))
+		}
+		return arr
+	})
+	defineMethod(vm, obj, "encodeInto", func(call goja.FunctionCall) goja.Value {
+		dest, ok := byteView(call.Argument(1))
+		if !ok {
+			panic(vm.NewTypeError("TextEncoder.encodeInto: destination must be a Uint8Array"))
+		}
+		read, written := 0, 0
+		var buf [utf8.UTFMax]byte
+		for _, c := range call.Argument(0).String() {
+			n := utf8.EncodeRune(buf[:], c)
+			if written+n > len(dest) {
+				break
+			}
+			copy(dest[written:], buf[:n])
+			written += n
+			read++
+			if c >= 0x10000 {
+				read++ // surrogate pair in UTF-16
+			}
+		}
+		result := vm.NewObject()
+		_ = result.Set("read", read)
+		_ = result.Set("written", written)
+		return result
+	})
+	return nil
+}
+
+// newTextDecoder implements `new TextDecoder(label, options)`.
+func newTextDecoder(call goja.ConstructorCall, vm *goja.Runtime) *goja.Object {
+	if label := call.Argument(0); !goja.IsUndefined(label) {
+		switch strings.ToLower(strings.TrimSpace(label.String())) {
+		case "utf-8", "utf8", "unicode-1-1-utf-8":
+		default:
+			rangeErr, err := vm.New(vm.Get("RangeError"), vm.ToValue(fmt.Sprintf("TextDecoder: unsupported encoding %q", label.String())))
+			if err != nil {
+				panic(err)
+			}
+			panic(rangeErr)
+		}
+	}
+	var fatal, ignoreBOM bool
+	if opts, ok := call.Argument(1).(*goja.Object); ok {
+		if v := opts.Get("fatal"); v != nil {
+			fatal = v.ToBoolean()
+		}
+		if v := opts.Get("ignoreBOM"); v != nil {
+			ignoreBOM = v.ToBoolean()
+		}
+	}
+
+	obj := call.This
+	_ = obj.DefineDataProperty("encoding", vm.ToValue("utf-8"), goja.FLAG_FALSE, goja.FLAG_TRUE, goja.FLAG_TRUE)
+	_ = obj.DefineDataProperty("fatal", vm.ToValue(fatal), goja.FLAG_FALSE, goja.FLAG_TRUE, goja.FLAG_TRUE)
+	_ = obj.DefineDataProperty("ignoreBOM", vm.ToValue(ignoreBOM), goja.FLAG_FALSE, goja.FLAG_TRUE, goja.FLAG_TRUE)
+
+	defineMethod(vm, obj, "decode", func(call goja.FunctionCall) goja.Value {
+		input := call.Argument(0)
+		if goja.IsUndefined(input) {
+			return vm.ToValue("")
+		}
+		data, ok := byteView(input)
+		if !ok {
+			panic(vm.NewTypeError("TextDecoder.decode: input must be an ArrayBuffer or ArrayBufferView"))
+		}
+		if !ignoreBOM {
+			data = trimUTF8BOM(data)
+		}
+		if utf8.Valid(data) {
+			return vm.ToValue(string(data))
+		}
+		if fatal {
+			panic(vm.NewTypeError("TextDecoder.decode: the encoded data was not valid UTF-8"))
+		}
+		return vm.ToValue(replaceInvalidUTF8(data))
+	})
+	return nil
+}
+
+// byteView returns the bytes behind an ArrayBuffer, typed array, or DataView.
+// The slice shares memory with the JavaScript value.
+func byteView(v goja.Value) ([]byte, bool) {
+	obj, ok := v.(*goja.Object)
+	if !ok {
+		return nil, false
+	}
+	if buf, ok := obj.Export().(goja.ArrayBuffer); ok {
+		return buf.Bytes(), true
+	}
+	buffer := obj.Get("buffer")
+	if buffer == nil {
+		return nil, false
+	}
+	buf, ok := buffer.Export().(goja.ArrayBuffer)
+	if !ok {
+		return nil, false
+	}
+	offset := obj.Get("byteOffset").ToInteger()
+	length := obj.Get("byteLength").ToInteger()
+	data := buf.Bytes()
+	if offset < 0 || length < 0 || offset+length > int64(len(data)) {
+		return nil, false
+	}
+	return data[offset : offset+length], true
+}
+
+func trimUTF8BOM(data []byte) []byte {
+	if len(data) >= 3 && data[0] == 0xEF && data[1] == 0xBB && data[2] == 0xBF {
+		return data[3:]
+	}
+	return data
+}
+
+// replaceInvalidUTF8 decodes data, substituting U+FFFD for each byte that does
+// not start a valid sequence.
+func replaceInvalidUTF8(data []byte) string {
+	var sb strings.Builder
+	sb.Grow(len(data))
+	for len(data) > 0 {
+		c, size := utf8.DecodeRune(data)
+		sb.WriteRune(c)
+		data = data[size:]
+	}
+	return sb.String()
+}
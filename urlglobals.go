@@ -0,0 +1,429 @@
+package jsrunner
+
+import (
+	"errors"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/dop251/goja"
+)
+
+// WithURLGlobals installs the WHATWG URL and URLSearchParams constructors,
+// backed by net/url. URL objects expose href, protocol, host, hostname, port,
+// pathname, search, hash, origin, username, password, and a live searchParams
+// object; assigning to a property rewrites the URL, and changes made through
+// searchParams are reflected in href and search. URLSearchParams accepts a
+// query string, an object of key/value pairs, an array of pairs, or another
+// URLSearchParams, and supports append, delete, get, getAll, has, set, sort,
+// forEach, keys, values, entries, and toString.
+//
+// Parsing follows net/url rather than the full WHATWG algorithm, so unusual
+// inputs (for example special-scheme URLs without slashes) may be normalized
+// differently than in a browser. Invalid URLs throw a TypeError.
+//
+// Example:
+//
+//	runner := jsrunner.New(jsrunner.WithURLGlobals())
+//	runner.Eval(`
+//	    const u = new URL("/search?q=go", "https://example.com");
+//	    u.searchParams.set("page", "2");
+//	    u.href // "https://example.com/search?q=go&page=2"
+//	`)
+func WithURLGlobals() Option {
+	return func(r *Runner) {
+		r.urlGlobals = true
+	}
+}
+
+// urlGlobals returns the URL and URLSearchParams constructors.
+func urlGlobals() map[string]interface{} {
+	return map[string]interface{}{
+		"URL":             newURLObject,
+		"URLSearchParams": newSearchParamsObject,
+	}
+}
+
+// searchParams is the ordered list of query pairs behind a URLSearchParams
+// object. onChange, when set, writes the serialized list back to the owning
+// URL.
+type searchParams struct {
+	pairs    [][2]string
+	onChange func()
+}
+
+func parseSearchParams(query string) [][2]string {
+	query = strings.TrimPrefix(query, "?")
+	var pairs [][2]string
+	for _, part := range strings.Split(query, "&") {
+		if part == "" {
+			continue
+		}
+		key, value, _ := strings.Cut(part, "=")
+		pairs = append(pairs, [2]string{unescapeQueryComponent(key), unescapeQueryComponent(value)})
+	}
+	return pairs
+}
+
+func unescapeQueryComponent(s string) string {
+	if unescaped, err := url.QueryUnescape(s); err == nil {
+		return unescaped
+	}
+	return strings.ReplaceAll(s, "+", " ")
+}
+
+func (p *searchParams) String() string {
+	parts := make([]string, len(p.pairs))
+	for i, pair := range p.pairs {
+		parts[i] = url.QueryEscape(pair[0]) + "=" + url.QueryEscape(pair[1])
+	}
+	return strings.Join(parts, "&")
+}
+
+func (p *searchParams) changed() {
+	if p.onChange != nil {
+		p.onChange()
+	}
+}
+
+func (p *searchParams) get(name string) (string, bool) {
+	for _, pair := range p.pairs {
+		if pair[0] == name {
+			return pair[1], true
+		}
+	}
+	return "", false
+}
+
+func (p *searchParams) set(name, value string) {
+	replaced := false
+	kept := p.pairs[:0]
+	for _, pair := range p.pairs {
+		if pair[0] == name {
+			if replaced {
+				continue
+			}
+			pair[1] = value
+			replaced = true
+		}
+		kept = append(kept, pair)
+	}
+	p.pairs = kept
+	if !replaced {
+		p.pairs = append(p.pairs, [2]string{name, value})
+	}
+}
+
+func (p *searchParams) remove(name string) {
+	kept := p.pairs[:0]
+	for _, pair := range p.pairs {
+		if pair[0] != name {
+			kept = append(kept, pair)
+		}
+	}
+	p.pairs = kept
+}
+
+// newSearchParamsObject implements `new URLSearchParams(init)`.
+func newSearchParamsObject(call goja.ConstructorCall, vm *goja.Runtime) *goja.Object {
+	params := &searchParams{}
+	init := call.Argument(0)
+	switch {
+	case goja.IsUndefined(init) || goja.IsNull(init):
+	case isJSObject(init):
+		params.pairs = searchParamsFromObject(vm, init.ToObject(vm))
+	default:
+		params.pairs = parseSearchParams(init.String())
+	}
+	bindSearchParams(vm, call.This, params)
+	return nil
+}
+
+func isJSObject(v goja.Value) bool {
+	_, ok := v.(*goja.Object)
+	return ok
+}
+
+// searchParamsFromObject reads URLSearchParams init pairs from another
+// URLSearchParams, an array of [name, value] pairs, or a record.
+func searchParamsFromObject(vm *goja.Runtime, obj *goja.Object) [][2]string {
+	if _, ok := goja.AssertFunction(obj.Get("getAll")); ok {
+		return parseSearchParams(obj.String())
+	}
+	var pairs [][2]string
+	if obj.ClassName() == "Array" {
+		for _, item := range obj.Export().([]interface{}) {
+			pair := vm.ToValue(item).ToObject(vm)
+			if length := pair.Get("length"); length == nil || length.ToInteger() != 2 {
+				panic(vm.NewTypeError("URLSearchParams: each pair must have exactly two items"))
+			}
+			pairs = append(pairs, [2]string{pair.Get("0").String(), pair.Get("1").String()})
+		}
+		return pairs
+	}
+	for _, key := range obj.Keys() {
+		pairs = append(pairs, [2]string{key, obj.Get(key).String()})
+	}
+	return pairs
+}
+
+// bindSearchParams defines the URLSearchParams methods on obj.
+func bindSearchParams(vm *goja.Runtime, obj *goja.Object, params *searchParams) {
+	entries := func(pick func([2]string) interface{}) goja.Value {
+		items := make([]interface{}, len(params.pairs))
+		for i, pair := range params.pairs {
+			items[i] = pick(pair)
+		}
+		return arrayIterator(vm, items)
+	}
+
+	defineMethod(vm, obj, "append", func(call goja.FunctionCall) goja.Value {
+		params.pairs = append(params.pairs, [2]string{call.Argument(0).String(), call.Argument(1).String()})
+		params.changed()
+		return goja.Undefined()
+	})
+	defineMethod(vm, obj, "delete", func(call goja.FunctionCall) goja.Value {
+		params.remove(call.Argument(0).String())
+		params.changed()
+		return goja.Undefined()
+	})
+	defineMethod(vm, obj, "get", func(call goja.FunctionCall) goja.Value {
+		if value, ok := params.get(call.Argument(0).String()); ok {
+			return vm.ToValue(value)
+		}
+		return goja.Null()
+	})
+	defineMethod(vm, obj, "getAll", func(call goja.FunctionCall) goja.Value {
+		name := call.Argument(0).String()
+		values := []interface{}{}
+		for _, pair := range params.pairs {
+			if pair[0] == name {
+				values = append(values, pair[1])
+			}
+		}
+		return vm.NewArray(values...)
+	})
+	defineMethod(vm, obj, "has", func(call goja.FunctionCall) goja.Value {
+		_, ok := params.get(call.Argument(0).String())
+		return vm.ToValue(ok)
+	})
+	defineMethod(vm, obj, "set", func(call goja.FunctionCall) goja.Value {
+		params.set(call.Argument(0).String(), call.Argument(1).String())
+		params.changed()
+		return goja.Undefined()
+	})
+	defineMethod(vm, obj, "sort", func(call goja.FunctionCall) goja.Value {
+		sort.SliceStable(params.pairs, func(i, j int) bool { return params.pairs[i][0] < params.pairs[j][0] })
+		params.changed()
+		return goja.Undefined()
+	})
+	defineMethod(vm, obj, "forEach", func(call goja.FunctionCall) goja.Value {
+		callback, ok := goja.AssertFunction(call.Argument(0))
+		if !ok {
+			panic(vm.NewTypeError("URLSearchParams.forEach: callback is not a function"))
+		}
+		for _, pair := range append([][2]string(nil), params.pairs...) {
+			if _, err := callback(call.Argument(1), vm.ToValue(pair[1]), vm.ToValue(pair[0]), obj); err != nil {
+				panic(err)
+			}
+		}
+		return goja.Undefined()
+	})
+	defineMethod(vm, obj, "keys", func(goja.FunctionCall) goja.Value {
+		return entries(func(pair [2]string) interface{} { return pair[0] })
+	})
+	defineMethod(vm, obj, "values", func(goja.FunctionCall) goja.Value {
+		return entries(func(pair [2]string) interface{} { return pair[1] })
+	})
+	entriesFn := func(goja.FunctionCall) goja.Value {
+		return entries(func(pair [2]string) interface{} { return vm.NewArray(pair[0], pair[1]) })
+	}
+	defineMethod(vm, obj, "entries", entriesFn)
+	_ = obj.DefineDataPropertySymbol(goja.SymIterator, vm.ToValue(entriesFn), goja.FLAG_TRUE, goja.FLAG_TRUE, goja.FLAG_FALSE)
+	defineMethod(vm, obj, "toString", func(goja.FunctionCall) goja.Value {
+		return vm.ToValue(params.String())
+	})
+	_ = obj.DefineAccessorProperty("size", vm.ToValue(func(goja.FunctionCall) goja.Value {
+		return vm.ToValue(len(params.pairs))
+	}), nil, goja.FLAG_TRUE, goja.FLAG_FALSE)
+}
+
+// arrayIterator returns an iterator over items, as produced by
+// Array.prototype.values.
+func arrayIterator(vm *goja.Runtime, items []interface{}) goja.Value {
+	arr := vm.NewArray(items...)
+	values, _ := goja.AssertFunction(arr.Get("values"))
+	iter, err := values(arr)
+	if err != nil {
+		panic(err)
+	}
+	return iter
+}
+
+// defineMethod adds a non-enumerable method to obj, so instances do not list
+// their methods when enumerated or serialized.
+func defineMethod(vm *goja.Runtime, obj *goja.Object, name string, fn func(goja.FunctionCall) goja.Value) {
+	_ = obj.DefineDataProperty(name, vm.ToValue(fn), goja.FLAG_TRUE, goja.FLAG_TRUE, goja.FLAG_FALSE)
+}
+
+// parseURL resolves input against base (when given) and normalizes the
+// result the way browsers do: schemes and hosts are lowercased and
+// hierarchical URLs always have a path.
+func parseURL(input string, base *string) (*url.URL, error) {
+	var u *url.URL
+	if base != nil {
+		b, err := parseURL(*base, nil)
+		if err != nil {
+			return nil, err
+		}
+		if u, err = b.Parse(input); err != nil {
+			return nil, err
+		}
+	} else {
+		var err error
+		if u, err = url.Parse(input); err != nil {
+			return nil, err
+		}
+		if u.Scheme == "" {
+			return nil, errMissingScheme
+		}
+	}
+	u.Host = strings.ToLower(u.Host)
+	if u.Host != "" && u.Path == "" {
+		u.Path = "/"
+	}
+	return u, nil
+}
+
+var errMissingScheme = errors.New("missing scheme")
+
+// newURLObject implements `new URL(input, base)`.
+func newURLObject(call goja.ConstructorCall, vm *goja.Runtime) *goja.Object {
+	input := call.Argument(0).String()
+	var base *string
+	if b := call.Argument(1); !goja.IsUndefined(b) {
+		s := b.String()
+		base = &s
+	}
+	u, err := parseURL(input, base)
+	if err != nil {
+		panic(vm.NewTypeError("Invalid URL: %s", input))
+	}
+
+	obj := call.This
+	params := &searchParams{pairs: parseSearchParams(u.RawQuery)}
+	params.onChange = func() {
+		u.RawQuery = params.String()
+		u.ForceQuery = false
+	}
+	paramsObj := vm.NewObject()
+	bindSearchParams(vm, paramsObj, params)
+
+	// replace swaps in a reparsed URL while keeping the same searchParams
+	// object attached.
+	replace := func(next *url.URL) {
+		*u = *next
+		params.pairs = parseSearchParams(u.RawQuery)
+	}
+	reparse := func(href string) {
+		next, err := parseURL(href, nil)
+		if err != nil {
+			panic(vm.NewTypeError("Invalid URL: %s", href))
+		}
+		replace(next)
+	}
+
+	accessor := func(name string, get func() string, set func(string)) {
+		getter := vm.ToValue(func(goja.FunctionCall) goja.Value { return vm.ToValue(get()) })
+		var setter goja.Value
+		if set != nil {
+			setter = vm.ToValue(func(call goja.FunctionCall) goja.Value {
+				set(call.Argument(0).String())
+				return goja.Undefined()
+			})
+		}
+		_ = obj.DefineAccessorProperty(name, getter, setter, goja.FLAG_TRUE, goja.FLAG_FALSE)
+	}
+
+	accessor("href", u.String, reparse)
+	accessor("protocol", func() string { return u.Scheme + ":" }, func(v string) {
+		next := *u
+		next.Scheme = strings.ToLower(strings.TrimSuffix(v, ":"))
+		reparse(next.String())
+	})
+	accessor("host", func() string { return u.Host }, func(v string) {
+		u.Host = strings.ToLower(v)
+	})
+	accessor("hostname", u.Hostname, func(v string) {
+		host := strings.ToLower(v)
+		if port := u.Port(); port != "" {
+			host += ":" + port
+		}
+		u.Host = host
+	})
+	accessor("port", u.Port, func(v string) {
+		if v == "" {
+			u.Host = u.Hostname()
+			return
+		}
+		u.Host = u.Hostname() + ":" + v
+	})
+	accessor("pathname", func() string {
+		if u.Opaque != "" {
+			return u.Opaque
+		}
+		return u.EscapedPath()
+	}, func(v string) {
+		if !strings.HasPrefix(v, "/") && u.Host != "" {
+			v = "/" + v
+		}
+		next := *u
+		next.Path, next.RawPath = v, ""
+		reparse(next.String())
+	})
+	accessor("search", func() string {
+		if u.RawQuery == "" {
+			return ""
+		}
+		return "?" + u.RawQuery
+	}, func(v string) {
+		u.RawQuery = strings.TrimPrefix(v, "?")
+		u.ForceQuery = false
+		params.pairs = parseSearchParams(u.RawQuery)
+	})
+	accessor("hash", func() string {
+		if u.Fragment == "" {
+			return ""
+		}
+		return "#" + u.EscapedFragment()
+	}, func(v string) {
+		u.Fragment, u.RawFragment = strings.TrimPrefix(v, "#"), ""
+	})
+	accessor("origin", func() string {
+		if u.Host == "" {
+			return "null"
+		}
+		return u.Scheme + "://" + u.Host
+	}, nil)
+	accessor("username", func() string { return u.User.Username() }, func(v string) {
+		password, ok := u.User.Password()
+		if ok {
+			u.User = url.UserPassword(v, password)
+		} else if v != "" {
+			u.User = url.User(v)
+		} else {
+			u.User = nil
+		}
+	})
+	accessor("password", func() string {
+		password, _ := u.User.Password()
+		return password
+	}, func(v string) {
+		u.User = url.UserPassword(u.User.Username(), v)
+	})
+	_ = obj.DefineDataProperty("searchParams", paramsObj, goja.FLAG_FALSE, goja.FLAG_TRUE, goja.FLAG_FALSE)
+
+	defineMethod(vm, obj, "toString", func(goja.FunctionCall) goja.Value { return vm.ToValue(u.String()) })
+	defineMethod(vm, obj, "toJSON", func(goja.FunctionCall) goja.Value { return vm.ToValue(u.String()) })
+	return nil
+}
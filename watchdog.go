@@ -0,0 +1,139 @@
+package jsrunner
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/dop251/goja"
+)
+
+// ErrLoopStalled is the interrupt value used by WithLoopWatchdog. Errors
+// returned for an interrupted tick match it with errors.Is.
+var ErrLoopStalled = errors.New("event loop tick exceeded the watchdog limit")
+
+// WithLoopWatchdog interrupts the JavaScript running on an EventLoopRunner's
+// loop when a single tick takes longer than d, so a synchronous infinite loop
+// in a timer or promise callback cannot wedge the loop forever. A tick is one
+// unit of work the loop runs to completion: the code passed to RunAsync,
+// RunProgramAsync, AwaitPromise and friends, a callback given to Run,
+// RunOnLoop, SetTimeout, or SetInterval, or a setTimeout, setInterval, or
+// setImmediate callback scheduled by JavaScript, each including the promise
+// reactions it triggers.
+//
+// The interrupted tick is abandoned (finally blocks do not run) and the loop
+// carries on with the next task. When the tick was started from Go, the
+// method that started it returns an error matching ErrLoopStalled; for
+// JavaScript timers, which have no caller, the interruption is reported
+// through the runner's logger as a "loop watchdog fired" warning. Either way
+// LoopStats.WatchdogInterrupts is incremented.
+//
+// Only JavaScript can be interrupted: a Go function that blocks inside a
+// tick is not stopped. The option applies to EventLoopRunner only.
+//
+// Example:
+//
+//	runner := jsrunner.NewEventLoopRunner(jsrunner.WithLoopWatchdog(time.Second))
+//	_, err := runner.RunAsync(`setTimeout(() => { while (true) {} }, 0); "ok"`)
+//	// err is nil: the stuck timer is interrupted after a second and dropped.
+func WithLoopWatchdog(d time.Duration) Option {
+	return func(r *Runner) {
+		r.loopWatchdog = d
+	}
+}
+
+// loopWatchdog tracks the tick currently running on the loop and interrupts
+// it once it exceeds limit.
+type loopWatchdog struct {
+	limit time.Duration
+
+	mu      sync.Mutex
+	depth   int
+	timer   *time.Timer
+	fired   bool
+	tickGen uint64
+
+	// vm is the loop's runtime whose timer globals have been wrapped. It is
+	// only accessed on the loop goroutine.
+	vm *goja.Runtime
+}
+
+// beginTick marks the start of a tick on vm and returns the function that
+// ends it. Nested calls extend the outer tick.
+func (r *EventLoopRunner) beginTick(vm *goja.Runtime) (end func()) {
+	w := r.watchdog
+	if w == nil {
+		return func() {}
+	}
+
+	w.mu.Lock()
+	w.depth++
+	if w.depth == 1 {
+		w.tickGen++
+		gen := w.tickGen
+		w.timer = time.AfterFunc(w.limit, func() {
+			w.mu.Lock()
+			defer w.mu.Unlock()
+			if w.depth > 0 && w.tickGen == gen && !w.fired {
+				w.fired = true
+				vm.Interrupt(ErrLoopStalled)
+			}
+		})
+	}
+	w.mu.Unlock()
+
+	return func() {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		w.depth--
+		if w.depth > 0 {
+			return
+		}
+		w.timer.Stop()
+		if w.fired {
+			// The interrupt may not have been delivered if the tick was
+			// blocked in Go code; clear it so the next tick runs normally.
+			w.fired = false
+			vm.ClearInterrupt()
+			atomic.AddInt64(&r.stats.watchdogInterrupts, 1)
+		}
+	}
+}
+
+// watchTimers wraps the loop's setTimeout, setInterval, and setImmediate so
+// the callbacks they schedule run as watched ticks. It runs once per runtime.
+func (r *EventLoopRunner) watchTimers(vm *goja.Runtime) {
+	w := r.watchdog
+	if w == nil || w.vm == vm {
+		return
+	}
+	w.vm = vm
+
+	for _, name := range []string{"setTimeout", "setInterval", "setImmediate"} {
+		schedule, ok := goja.AssertFunction(vm.Get(name))
+		if !ok {
+			continue
+		}
+		_ = vm.Set(name, func(call goja.FunctionCall) goja.Value {
+			args := append([]goja.Value(nil), call.Arguments...)
+			if fn, ok := goja.AssertFunction(call.Argument(0)); ok {
+				args[0] = vm.ToValue(func(call goja.FunctionCall) goja.Value {
+					end := r.beginTick(vm)
+					defer end()
+					// Like the loop itself, ignore exceptions from timer
+					// callbacks, but report watchdog interruptions.
+					if _, err := fn(call.This, call.Arguments...); errors.Is(err, ErrLoopStalled) {
+						r.logger.Warn("loop watchdog fired", "limit", w.limit, "err", err)
+					}
+					return goja.Undefined()
+				})
+			}
+			value, err := schedule(call.This, args...)
+			if err != nil {
+				panic(err)
+			}
+			return value
+		})
+	}
+}
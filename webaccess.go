@@ -0,0 +1,507 @@
+package jsrunner
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/andybalholm/brotli"
+)
+
+// fetchPolicy holds the request restrictions configured via WebAccessConfig.
+type fetchPolicy struct {
+	allowedHosts         []string
+	blockPrivateNetworks bool
+	maxResponseBytes     int64
+	slots                chan struct{}
+
+	// transports caches the guarded copy of each *http.Transport so
+	// connection pools survive across fetches. It is shared by copies of the
+	// policy.
+	transports *sync.Map
+
+	// resolve looks up host names; nil means net.DefaultResolver.
+	resolve func(ctx context.Context, host string) ([]net.IPAddr, error)
+}
+
+// restricted reports whether the policy limits where requests may go.
+func (p fetchPolicy) restricted() bool {
+	return len(p.allowedHosts) > 0 || p.blockPrivateNetworks
+}
+
+// check validates rawURL against the policy before any request is made. It
+// runs again for every redirect hop.
+func (p fetchPolicy) check(ctx context.Context, rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("invalid fetch url %q: %w", rawURL, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("fetch blocked: unsupported scheme %q", u.Scheme)
+	}
+
+	host := u.Hostname()
+	if len(p.allowedHosts) > 0 && !hostAllowed(host, p.allowedHosts) {
+		return fmt.Errorf("fetch blocked: host %q is not in the allowed hosts list", host)
+	}
+
+	if p.blockPrivateNetworks {
+		resolve := p.resolve
+		if resolve == nil {
+			resolve = net.DefaultResolver.LookupIPAddr
+		}
+		ips, err := resolve(ctx, host)
+		if err != nil {
+			return fmt.Errorf("fetch blocked: cannot resolve host %q: %w", host, err)
+		}
+		for _, ip := range ips {
+			if isPrivateIP(ip.IP) {
+				return fmt.Errorf("fetch blocked: host %q resolves to private address %s", host, ip.IP)
+			}
+		}
+	}
+
+	return nil
+}
+
+// client returns a copy of base that applies the policy to every redirect hop
+// and, when private networks are blocked, to the address each connection
+// actually dials, so a redirect or a DNS answer that changes after check
+// cannot reach an internal service. Dial checks need an *http.Transport (or
+// the default transport); other RoundTrippers only get the redirect checks.
+func (p fetchPolicy) client(base *http.Client) *http.Client {
+	if !p.restricted() {
+		return base
+	}
+
+	restricted := *base
+	checkRedirect := base.CheckRedirect
+	restricted.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if checkRedirect != nil {
+			if err := checkRedirect(req, via); err != nil {
+				return err
+			}
+		} else if len(via) >= 10 {
+			return errors.New("stopped after 10 redirects")
+		}
+		return p.check(req.Context(), req.URL.String())
+	}
+	if p.blockPrivateNetworks {
+		restricted.Transport = p.guardTransport(base.Transport)
+	}
+	return &restricted
+}
+
+// guardTransport returns a copy of rt that refuses to connect to private
+// addresses. rt is returned unchanged when it is not an *http.Transport.
+func (p fetchPolicy) guardTransport(rt http.RoundTripper) http.RoundTripper {
+	if rt == nil {
+		rt = http.DefaultTransport
+	}
+	base, ok := rt.(*http.Transport)
+	if !ok {
+		return rt
+	}
+	if p.transports != nil {
+		if cached, ok := p.transports.Load(base); ok {
+			return cached.(*http.Transport)
+		}
+	}
+
+	t := base.Clone()
+	if dial := t.DialContext; dial != nil {
+		t.DialContext = guardDial(dial)
+	} else {
+		dialer := &net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+			Control: func(network, address string, _ syscall.RawConn) error {
+				return checkDialAddress(address)
+			},
+		}
+		t.DialContext = dialer.DialContext
+	}
+	if t.DialTLSContext != nil {
+		t.DialTLSContext = guardDial(t.DialTLSContext)
+	}
+
+	if p.transports != nil {
+		cached, _ := p.transports.LoadOrStore(base, t)
+		return cached.(*http.Transport)
+	}
+	return t
+}
+
+// guardDial wraps a custom dial function, closing connections whose remote
+// address is private.
+func guardDial(dial func(ctx context.Context, network, addr string) (net.Conn, error)) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := dial(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+		if err := checkDialAddress(conn.RemoteAddr().String()); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		return conn, nil
+	}
+}
+
+// checkDialAddress rejects a host:port address whose IP is private.
+func checkDialAddress(address string) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return fmt.Errorf("fetch blocked: invalid address %q: %w", address, err)
+	}
+	if ip := net.ParseIP(host); ip != nil && isPrivateIP(ip) {
+		return fmt.Errorf("fetch blocked: connection to private address %s", ip)
+	}
+	return nil
+}
+
+// WithHTTPClientFunc lets callers choose the HTTP client for every fetch made by
+// the built-in helpers, based on the requested URL. This enables per-tenant
+// proxies, mTLS client certificates, or dedicated transports for individual
+// upstreams while sharing one runner. When fn returns nil, the client from
+// WebAccessConfig (or the default client) is used. Requests are still subject
+// to the fetch policy and timeout configured via WithWebAccess.
+//
+// Example:
+//
+//	runner := jsrunner.New(
+//	    jsrunner.WithWebAccess(nil),
+//	    jsrunner.WithHTTPClientFunc(func(url string) *http.Client {
+//	        if strings.HasPrefix(url, "https://billing.internal/") {
+//	            return mtlsClient
+//	        }
+//	        return nil
+//	    }),
+//	)
+func WithHTTPClientFunc(fn func(url string) *http.Client) Option {
+	return func(r *Runner) {
+		r.httpClientFunc = fn
+	}
+}
+
+// clientFor returns the client selected by fn for url, or fallback when fn is
+// nil or declines to choose one.
+func clientFor(url string, fn func(string) *http.Client, fallback *http.Client) *http.Client {
+	if fn != nil {
+		if client := fn(url); client != nil {
+			return client
+		}
+	}
+	return fallback
+}
+
+// fetcher performs the HTTP requests behind the fetch helpers. Runner and
+// EventLoopRunner both build one from their configuration, so caching, policy
+// checks, status handling, decoding, and error messages are identical.
+type fetcher struct {
+	client     *http.Client
+	clientFunc func(url string) *http.Client
+	timeout    time.Duration
+	policy     fetchPolicy
+	cache      *fetchCache
+	loopback   loopbackRoutes
+	logger     Logger
+}
+
+// fetch returns the body of url, consulting the cache first. The request is
+// bounded by the configured timeout and canceled when ctx is done.
+func (f fetcher) fetch(ctx context.Context, url string) ([]byte, error) {
+	if f.cache != nil {
+		if data, ok := f.cache.get(url); ok {
+			f.logger.Debug("fetch cache hit", "url", url)
+			return data, nil
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, f.timeout)
+	defer cancel()
+
+	release, err := f.policy.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	resp, err := f.do(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		return nil, fmt.Errorf("fetch request failed with status %d", resp.StatusCode)
+	}
+
+	body, err := decodeBody(resp.Header, resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := f.policy.readBody(body)
+	if err != nil {
+		return nil, err
+	}
+
+	if f.cache != nil && cacheable(resp.Header) {
+		f.cache.put(url, data)
+	}
+
+	return data, nil
+}
+
+// do issues the GET request for url, serving it from a loopback handler when
+// one matches and from the network otherwise.
+func (f fetcher) do(ctx context.Context, url string) (*http.Response, error) {
+	if h := f.loopback.match(url); h != nil {
+		return serveLoopback(ctx, h, url)
+	}
+
+	if err := f.policy.check(ctx, url); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return f.policy.client(clientFor(url, f.clientFunc, f.client)).Do(req)
+}
+
+// fetchBytes fetches url for the runner's helpers. Requests inherit the
+// context of the in-flight CallContext or EvalContext call.
+func (r *Runner) fetchBytes(url string) ([]byte, error) {
+	return r.fetcher().fetch(RunnerContext(r), url)
+}
+
+// fetcher returns the runner's HTTP configuration.
+func (r *Runner) fetcher() fetcher {
+	return fetcher{
+		client:     r.httpClient,
+		clientFunc: r.httpClientFunc,
+		timeout:    r.webAccessTimeout,
+		policy:     r.fetchPolicy,
+		cache:      r.fetchCache,
+		loopback:   r.loopback,
+		logger:     r.logger,
+	}
+}
+
+// fetchBytes fetches url for the event loop's helpers.
+func (r *EventLoopRunner) fetchBytes(url string) ([]byte, error) {
+	f := fetcher{
+		client:     r.httpClient,
+		clientFunc: r.httpClientFunc,
+		timeout:    r.webAccessTimeout,
+		policy:     r.fetchPolicy,
+		cache:      r.fetchCache,
+		loopback:   r.loopback,
+		logger:     r.logger,
+	}
+	return f.fetch(context.Background(), url)
+}
+
+// webAccessDefaults fills in the timeout and client used when WebAccessConfig
+// leaves them unset.
+func webAccessDefaults(timeout time.Duration, client *http.Client) (time.Duration, *http.Client) {
+	if timeout <= 0 {
+		timeout = defaultWebAccessTimeout
+	}
+	if client == nil {
+		client = &http.Client{Timeout: timeout}
+	}
+	return timeout, client
+}
+
+// fetchHelpers controls which fetch globals are installed and their names.
+type fetchHelpers struct {
+	installText  bool
+	installJSON  bool
+	installBytes bool
+	prefix       string
+	jsonParser   func(data []byte) (interface{}, error)
+}
+
+func newFetchHelpers(cfg *WebAccessConfig) fetchHelpers {
+	if cfg == nil || (!cfg.InstallText && !cfg.InstallJSON && !cfg.InstallBytes) {
+		h := fetchHelpers{installText: true, installJSON: true, installBytes: true}
+		if cfg != nil {
+			h.prefix = cfg.Prefix
+			h.jsonParser = cfg.JSONParser
+		}
+		return h
+	}
+	return fetchHelpers{
+		installText:  cfg.InstallText,
+		installJSON:  cfg.InstallJSON,
+		installBytes: cfg.InstallBytes,
+		prefix:       cfg.Prefix,
+		jsonParser:   cfg.JSONParser,
+	}
+}
+
+// parseJSON decodes a fetchJSON response body with the configured parser, or
+// encoding/json when none is set.
+func (h fetchHelpers) parseJSON(data []byte) (interface{}, error) {
+	if h.jsonParser != nil {
+		return h.jsonParser(data)
+	}
+
+	var payload interface{}
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return nil, err
+	}
+	return payload, nil
+}
+
+// name returns the global name for a helper, applying the configured prefix
+// ("fetchJSON" with prefix "go" becomes "goFetchJSON").
+func (h fetchHelpers) name(helper string) string {
+	if h.prefix == "" {
+		return helper
+	}
+	return h.prefix + strings.ToUpper(helper[:1]) + helper[1:]
+}
+
+// names returns the global names of every installed helper.
+func (h fetchHelpers) names() []string {
+	var names []string
+	if h.installText {
+		names = append(names, h.name("fetchText"))
+	}
+	if h.installJSON {
+		names = append(names, h.name("fetchJSON"))
+	}
+	if h.installBytes {
+		names = append(names, h.name("fetchBytes"))
+	}
+	return names
+}
+
+// acquire waits for a free request slot when MaxConcurrentFetches is set. The
+// returned function releases the slot.
+func (p fetchPolicy) acquire(ctx context.Context) (release func(), err error) {
+	if p.slots == nil {
+		return func() {}, nil
+	}
+	select {
+	case p.slots <- struct{}{}:
+		return func() { <-p.slots }, nil
+	case <-ctx.Done():
+		return nil, fmt.Errorf("fetch aborted while waiting for a free request slot: %w", ctx.Err())
+	}
+}
+
+// readBody reads the response body, enforcing the configured size limit. The
+// limit applies to the decompressed size, so compressed responses cannot
+// sidestep it.
+func (p fetchPolicy) readBody(body io.Reader) ([]byte, error) {
+	if p.maxResponseBytes <= 0 {
+		return io.ReadAll(body)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(body, p.maxResponseBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > p.maxResponseBytes {
+		return nil, fmt.Errorf("fetch response exceeds limit of %d bytes", p.maxResponseBytes)
+	}
+	return data, nil
+}
+
+// decodeBody wraps body with decompressors for the Content-Encoding header,
+// so scripts receive plain bytes even when a custom transport leaves the
+// response encoded. gzip, deflate (zlib-wrapped or raw), and br are supported;
+// multiple encodings are undone in reverse order of application.
+func decodeBody(header http.Header, body io.Reader) (io.Reader, error) {
+	encodings := strings.Split(header.Get("Content-Encoding"), ",")
+	for i := len(encodings) - 1; i >= 0; i-- {
+		switch encoding := strings.ToLower(strings.TrimSpace(encodings[i])); encoding {
+		case "", "identity":
+		case "gzip", "x-gzip":
+			zr, err := gzip.NewReader(body)
+			if err != nil {
+				return nil, fmt.Errorf("failed to decode gzip response: %w", err)
+			}
+			body = zr
+		case "deflate":
+			br := bufio.NewReader(body)
+			if isZlibHeader(br) {
+				zr, err := zlib.NewReader(br)
+				if err != nil {
+					return nil, fmt.Errorf("failed to decode deflate response: %w", err)
+				}
+				body = zr
+			} else {
+				body = flate.NewReader(br)
+			}
+		case "br":
+			body = brotli.NewReader(body)
+		default:
+			return nil, fmt.Errorf("unsupported content encoding %q", encoding)
+		}
+	}
+	return body, nil
+}
+
+// isZlibHeader reports whether br starts with a zlib stream header. Some
+// servers send raw DEFLATE data for "Content-Encoding: deflate" instead of
+// the zlib format the spec requires.
+func isZlibHeader(br *bufio.Reader) bool {
+	header, err := br.Peek(2)
+	if err != nil {
+		return false
+	}
+	return header[0]&0x0f == 8 && (uint16(header[0])<<8|uint16(header[1]))%31 == 0
+}
+
+// hostAllowed reports whether host matches one of the patterns. A pattern of
+// the form "*.example.com" matches any subdomain of example.com.
+func hostAllowed(host string, patterns []string) bool {
+	host = strings.ToLower(host)
+	for _, pattern := range patterns {
+		pattern = strings.ToLower(strings.TrimSpace(pattern))
+		if suffix, ok := strings.CutPrefix(pattern, "*."); ok {
+			if strings.HasSuffix(host, "."+suffix) {
+				return true
+			}
+			continue
+		}
+		if host == pattern {
+			return true
+		}
+	}
+	return false
+}
+
+// sharedAddressSpace is 100.64.0.0/10, used for carrier-grade NAT (RFC 6598).
+var sharedAddressSpace = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+func isPrivateIP(ip net.IP) bool {
+	return ip.IsLoopback() ||
+		sharedAddressSpace.Contains(ip) ||
+		ip.IsPrivate() ||
+		ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() ||
+		ip.IsUnspecified()
+}